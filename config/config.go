@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -9,14 +12,28 @@ import (
 type (
 	// Config -.
 	Config struct {
-		App   `yaml:"app"`
-		HTTP  `yaml:"http"`
-		Log   `yaml:"logger"`
-		PG    `yaml:"postgres"`
-		JWT   `yaml:"jwt"`
-		Redis `yaml:"redis"`
-		Gmail `yaml:"gmail"`
-		Gemini `yaml:"gemini"`
+		App         `yaml:"app"`
+		HTTP        `yaml:"http"`
+		Log         `yaml:"logger"`
+		PG          `yaml:"postgres"`
+		JWT         `yaml:"jwt"`
+		Redis       `yaml:"redis"`
+		Gmail       `yaml:"gmail"`
+		Mail        `yaml:"mail"`
+		Gemini      `yaml:"gemini"`
+		Session     `yaml:"session"`
+		Login       `yaml:"login"`
+		OAuth       `yaml:"oauth"`
+		RBAC        `yaml:"rbac"`
+		Audit       `yaml:"audit"`
+		Account     `yaml:"account"`
+		Register    `yaml:"register"`
+		Moderation  `yaml:"moderation"`
+		EmailChange `yaml:"email_change"`
+		Tweet       `yaml:"tweet"`
+		Media       `yaml:"media"`
+		Video       `yaml:"video"`
+		S3          `yaml:"s3"`
 	}
 
 	// App -.
@@ -53,16 +70,183 @@ type (
 	}
 
 	// Gmail -.
+	// SMTP server and credentials of the smtp mail driver, Email is the sender unless Mail.From is set.
 	Gmail struct {
-		Email     string `env-required:"true" yaml:"email" env:"EMAIL"`
-		EmailPass string `env-required:"true" yaml:"email_pass" env:"EMAIL_PASS"`
-		Host      string `env-required:"true" yaml:"host" env:"SMTP_HOST"`
-		Port      string    `env-required:"true" yaml:"port" env:"SMTP_PORT"`
+		Email     string `yaml:"email"      env:"EMAIL"`
+		EmailPass string `yaml:"email_pass" env:"EMAIL_PASS"`
+		Host      string `yaml:"host"       env:"SMTP_HOST"`
+		Port      string `yaml:"port"       env:"SMTP_PORT"`
+	}
+
+	// Mail -.
+	// Driver is smtp, file (.eml files in Dir) or log. Security is starttls, tls or none and Auth is plain,
+	// login, cram-md5 or none. Failed deliveries are retried after RetryBackoff, doubling up to MaxAttempts.
+	Mail struct {
+		Driver        string        `yaml:"driver"         env:"MAIL_DRIVER"         env-default:"smtp"`
+		From          string        `yaml:"from"           env:"MAIL_FROM"`
+		FromName      string        `yaml:"from_name"      env:"MAIL_FROM_NAME"      env-default:"Mini twitter"`
+		Security      string        `yaml:"security"       env:"MAIL_SECURITY"       env-default:"starttls"`
+		Auth          string        `yaml:"auth"           env:"MAIL_AUTH"           env-default:"plain"`
+		Timeout       time.Duration `yaml:"timeout"        env:"MAIL_TIMEOUT"        env-default:"30s"`
+		Dir           string        `yaml:"dir"            env:"MAIL_DIR"            env-default:"tmp/mail"`
+		DefaultLocale string        `yaml:"default_locale" env:"MAIL_DEFAULT_LOCALE" env-default:"en"`
+		QueueInterval time.Duration `yaml:"queue_interval" env:"MAIL_QUEUE_INTERVAL" env-default:"5s"`
+		MaxAttempts   int           `yaml:"max_attempts"   env:"MAIL_MAX_ATTEMPTS"   env-default:"8"`
+		RetryBackoff  time.Duration `yaml:"retry_backoff"  env:"MAIL_RETRY_BACKOFF"  env-default:"30s"`
+	}
+
+	// Media -.
+	// Storage is local (files in Dir) or s3. Max sizes are in bytes, a chunked upload sends at most MaxChunkSize per request.
+	// The image pipeline makes the variants of photos every ProcessInterval, photos of more than MaxImagePixels
+	// aren't decoded and a photo failing ProcessAttempts times is left without variants. Variants are lossy WebP of
+	// WebPQuality (0-100), encoded with the ffmpeg of Video.
+	// Media is served from urls signed with URLSecret, the JWT secret when it's empty, valid for at least URLTTL.
+	// Media no tweet or avatar has used for GCGracePeriod is deleted every GCInterval, only counted with GCDryRun.
+	Media struct {
+		Storage         string        `yaml:"storage"          env:"MEDIA_STORAGE"          env-default:"local"`
+		Dir             string        `yaml:"dir"              env:"MEDIA_DIR"              env-default:"tmp/media"`
+		MaxImageSize    int64         `yaml:"max_image_size"   env:"MEDIA_MAX_IMAGE_SIZE"   env-default:"5242880"`
+		MaxGifSize      int64         `yaml:"max_gif_size"     env:"MEDIA_MAX_GIF_SIZE"     env-default:"15728640"`
+		MaxVideoSize    int64         `yaml:"max_video_size"   env:"MEDIA_MAX_VIDEO_SIZE"   env-default:"536870912"`
+		MaxChunkSize    int64         `yaml:"max_chunk_size"   env:"MEDIA_MAX_CHUNK_SIZE"   env-default:"8388608"`
+		MaxImagePixels  int           `yaml:"max_image_pixels" env:"MEDIA_MAX_IMAGE_PIXELS" env-default:"25000000"`
+		WebPQuality     int           `yaml:"webp_quality"     env:"MEDIA_WEBP_QUALITY"     env-default:"80"`
+		ProcessInterval time.Duration `yaml:"process_interval" env:"MEDIA_PROCESS_INTERVAL" env-default:"5s"`
+		ProcessAttempts int           `yaml:"process_attempts" env:"MEDIA_PROCESS_ATTEMPTS" env-default:"3"`
+		URLSecret       string        `yaml:"url_secret"       env:"MEDIA_URL_SECRET"`
+		URLTTL          time.Duration `yaml:"url_ttl"          env:"MEDIA_URL_TTL"          env-default:"1h"`
+		GCInterval      time.Duration `yaml:"gc_interval"      env:"MEDIA_GC_INTERVAL"      env-default:"1h"`
+		GCGracePeriod   time.Duration `yaml:"gc_grace_period"  env:"MEDIA_GC_GRACE_PERIOD"  env-default:"24h"`
+		GCDryRun        bool          `yaml:"gc_dry_run"       env:"MEDIA_GC_DRY_RUN"       env-default:"false"`
+	}
+
+	// Video -.
+	// Videos are probed and transcoded into an HLS ladder with the FFmpeg and FFprobe binaries every ProcessInterval.
+	// Videos longer than MaxDuration or with a side over MaxDimension are rejected, a transcode running longer
+	// than Timeout is cancelled and tried again.
+	Video struct {
+		FFmpeg          string        `yaml:"ffmpeg"           env:"VIDEO_FFMPEG"           env-default:"ffmpeg"`
+		FFprobe         string        `yaml:"ffprobe"          env:"VIDEO_FFPROBE"          env-default:"ffprobe"`
+		MaxDuration     time.Duration `yaml:"max_duration"     env:"VIDEO_MAX_DURATION"     env-default:"2m20s"`
+		MaxDimension    int           `yaml:"max_dimension"    env:"VIDEO_MAX_DIMENSION"    env-default:"4096"`
+		SegmentDuration time.Duration `yaml:"segment_duration" env:"VIDEO_SEGMENT_DURATION" env-default:"6s"`
+		ProcessInterval time.Duration `yaml:"process_interval" env:"VIDEO_PROCESS_INTERVAL" env-default:"10s"`
+		Timeout         time.Duration `yaml:"timeout"          env:"VIDEO_TIMEOUT"          env-default:"30m"`
+	}
+
+	// S3 -.
+	// Bucket of the s3 media storage, PathStyle is needed by MinIO and most other stand-ins.
+	S3 struct {
+		Endpoint  string        `yaml:"endpoint"   env:"S3_ENDPOINT"`
+		Region    string        `yaml:"region"     env:"S3_REGION"     env-default:"us-east-1"`
+		Bucket    string        `yaml:"bucket"     env:"S3_BUCKET"`
+		AccessKey string        `                  env:"S3_ACCESS_KEY"`
+		SecretKey string        `                  env:"S3_SECRET_KEY"`
+		PathStyle bool          `yaml:"path_style" env:"S3_PATH_STYLE"`
+		Timeout   time.Duration `yaml:"timeout"    env:"S3_TIMEOUT"    env-default:"5m"`
 	}
 
 	Gemini struct {
 		GeminiAPIKey string `env-required:"true" yaml:"api_key" env:"GEMINI_API_KEY"`
 	}
+
+	// Session -.
+	// Local cache lives per replica, invalidations are published on InvalidationChannel for the other replicas.
+	// One published while a replica was disconnected from redis is lost, keep LocalCacheTTL short.
+	Session struct {
+		IdleTimeout         time.Duration `yaml:"idle_timeout"         env:"SESSION_IDLE_TIMEOUT"         env-default:"720h"`
+		AbsoluteTimeout     time.Duration `yaml:"absolute_timeout"     env:"SESSION_ABSOLUTE_TIMEOUT"     env-default:"2160h"`
+		TouchInterval       time.Duration `yaml:"touch_interval"       env:"SESSION_TOUCH_INTERVAL"       env-default:"1m"`
+		CacheTTL            time.Duration `yaml:"cache_ttl"            env:"SESSION_CACHE_TTL"            env-default:"1m"`
+		LocalCacheTTL       time.Duration `yaml:"local_cache_ttl"      env:"SESSION_LOCAL_CACHE_TTL"      env-default:"5s"`
+		LocalCacheSize      int           `yaml:"local_cache_size"     env:"SESSION_LOCAL_CACHE_SIZE"     env-default:"10000"`
+		InvalidationChannel string        `yaml:"invalidation_channel" env:"SESSION_INVALIDATION_CHANNEL" env-default:"session-cache-invalidated"`
+	}
+
+	// Login -.
+	Login struct {
+		Window          time.Duration `yaml:"window"           env:"LOGIN_WINDOW"           env-default:"15m"`
+		MaxPerIP        int           `yaml:"max_per_ip"       env:"LOGIN_MAX_PER_IP"       env-default:"50"`
+		MaxFailures     int           `yaml:"max_failures"     env:"LOGIN_MAX_FAILURES"     env-default:"5"`
+		LockoutDuration time.Duration `yaml:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION" env-default:"15m"`
+		DelayStep       time.Duration `yaml:"delay_step"       env:"LOGIN_DELAY_STEP"       env-default:"500ms"`
+		MaxDelay        time.Duration `yaml:"max_delay"        env:"LOGIN_MAX_DELAY"        env-default:"5s"`
+	}
+
+	// OAuth -.
+	OAuth struct {
+		Providers map[string]OAuthProvider `yaml:"providers"`
+	}
+
+	// OAuthProvider -.
+	// Client secret can be left empty in yaml and set with OAUTH_<NAME>_CLIENT_SECRET.
+	OAuthProvider struct {
+		Issuer       string   `yaml:"issuer"`
+		ClientID     string   `yaml:"client_id"`
+		ClientSecret string   `yaml:"client_secret"`
+		RedirectURL  string   `yaml:"redirect_url"`
+		Scopes       []string `yaml:"scopes"`
+	}
+
+	// RBAC -.
+	// Policy is loaded from postgres, SeedPolicy only fills an empty casbin_rule table.
+	RBAC struct {
+		Model          string        `yaml:"model"           env:"RBAC_MODEL"           env-default:"config/rbac.conf"`
+		SeedPolicy     string        `yaml:"seed_policy"     env:"RBAC_SEED_POLICY"     env-default:"config/policy.csv"`
+		Channel        string        `yaml:"channel"         env:"RBAC_CHANNEL"         env-default:"rbac-policy-updated"`
+		ReloadInterval time.Duration `yaml:"reload_interval" env:"RBAC_RELOAD_INTERVAL" env-default:"5m"`
+	}
+
+	// Audit -.
+	// Entries older than Retention are purged every PurgeInterval, zero retention keeps them forever.
+	Audit struct {
+		Retention     time.Duration `yaml:"retention"      env:"AUDIT_RETENTION"      env-default:"8760h"`
+		PurgeInterval time.Duration `yaml:"purge_interval" env:"AUDIT_PURGE_INTERVAL" env-default:"1h"`
+	}
+
+	// Account -.
+	// Deactivated accounts are deleted DeletionGracePeriod after the user asked for it.
+	Account struct {
+		DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" env:"ACCOUNT_DELETION_GRACE_PERIOD" env-default:"720h"`
+		PurgeInterval       time.Duration `yaml:"purge_interval"        env:"ACCOUNT_PURGE_INTERVAL"        env-default:"1h"`
+		ExportTTL           time.Duration `yaml:"export_ttl"            env:"ACCOUNT_EXPORT_TTL"            env-default:"168h"`
+		ExportInterval      time.Duration `yaml:"export_interval"       env:"ACCOUNT_EXPORT_INTERVAL"       env-default:"30s"`
+	}
+
+	// Register -.
+	// A verification code lives for OtpTTL and is voided after MaxAttempts wrong tries, a new one can be
+	// requested once every ResendInterval. Accounts not verified within UnverifiedTTL are deleted.
+	Register struct {
+		OtpTTL         time.Duration `yaml:"otp_ttl"         env:"REGISTER_OTP_TTL"         env-default:"5m"`
+		MaxAttempts    int           `yaml:"max_attempts"    env:"REGISTER_MAX_ATTEMPTS"    env-default:"5"`
+		ResendInterval time.Duration `yaml:"resend_interval" env:"REGISTER_RESEND_INTERVAL" env-default:"1m"`
+		UnverifiedTTL  time.Duration `yaml:"unverified_ttl"  env:"REGISTER_UNVERIFIED_TTL"  env-default:"168h"`
+		PurgeInterval  time.Duration `yaml:"purge_interval"  env:"REGISTER_PURGE_INTERVAL"  env-default:"1h"`
+	}
+
+	// Moderation -.
+	// Suspensions that are over are lifted every RestoreInterval.
+	Moderation struct {
+		RestoreInterval time.Duration `yaml:"restore_interval" env:"MODERATION_RESTORE_INTERVAL" env-default:"1m"`
+	}
+
+	// Tweet -.
+	// The content of a published tweet can be edited within EditWindow of publishing.
+	// Scheduled tweets that are due are published every PublishInterval, tagging one that failed is retried after
+	// TagRetryDelay. Polls that ended are closed every PollCloseInterval.
+	Tweet struct {
+		EditWindow        time.Duration `yaml:"edit_window"         env:"TWEET_EDIT_WINDOW"         env-default:"1h"`
+		PublishInterval   time.Duration `yaml:"publish_interval"    env:"TWEET_PUBLISH_INTERVAL"    env-default:"30s"`
+		TagRetryDelay     time.Duration `yaml:"tag_retry_delay"     env:"TWEET_TAG_RETRY_DELAY"     env-default:"5m"`
+		PollCloseInterval time.Duration `yaml:"poll_close_interval" env:"TWEET_POLL_CLOSE_INTERVAL" env-default:"1m"`
+	}
+
+	// EmailChange -.
+	// A new address stays claimed by the account for OtpTTL, wrong codes beyond MaxAttempts void the code.
+	EmailChange struct {
+		OtpTTL      time.Duration `yaml:"otp_ttl"      env:"EMAIL_CHANGE_OTP_TTL"      env-default:"15m"`
+		MaxAttempts int           `yaml:"max_attempts" env:"EMAIL_CHANGE_MAX_ATTEMPTS" env-default:"5"`
+	}
 )
 
 // NewConfig returns app config.
@@ -79,5 +263,12 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	for name, provider := range cfg.OAuth.Providers {
+		if provider.ClientSecret == "" {
+			provider.ClientSecret = os.Getenv("OAUTH_" + strings.ToUpper(name) + "_CLIENT_SECRET")
+			cfg.OAuth.Providers[name] = provider
+		}
+	}
+
 	return cfg, nil
 }
@@ -17,6 +17,17 @@ var (
 	ErrorConflict       = "CONFLICT"
 	ErrorBadRequest     = "BAD_REQUEST"
 	ErrorDuplicateKey   = "DUPLICATE_KEY"
+
+	ErrorInvalidCredentials = "INVALID_CREDENTIALS"
+	ErrorTooManyRequests    = "TOO_MANY_REQUESTS"
+	ErrorAccountLocked      = "ACCOUNT_LOCKED"
+	ErrorAccountDeactivated = "ACCOUNT_DEACTIVATED"
+	ErrorEmailNotVerified   = "EMAIL_NOT_VERIFIED"
+	ErrorAccountSuspended   = "ACCOUNT_SUSPENDED"
+	ErrorAccountBanned      = "ACCOUNT_BANNED"
+
+	ErrorUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorPayloadTooLarge      = "PAYLOAD_TOO_LARGE"
 )
 
 var (
@@ -2,20 +2,28 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
 
 	rediscache "github.com/golanguzb70/redis-cache"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	v1 "github.com/golanguzb70/udevslabs-twitter/internal/controller/http/v1"
 	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/httpserver"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/imaging"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/job"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/ratelimit"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/rbac"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/video"
 )
 
 // Run creates objects via constructors.
@@ -29,9 +37,6 @@ func Run(cfg *config.Config) {
 	}
 	defer pg.Close()
 
-	// Use case
-	useCase := usecase.New(pg, cfg, l)
-
 	// redis
 	redis, err := rediscache.New(&rediscache.Config{
 		RedisHost: cfg.Redis.RedisHost,
@@ -41,9 +46,186 @@ func Run(cfg *config.Config) {
 		l.Fatal(fmt.Errorf("app - Run - rediscache.New: %w", err))
 	}
 
+	// redis client for commands rediscache doesn't cover
+	redisClient := goredis.NewClient(&goredis.Options{
+		Addr: fmt.Sprintf("%s:%d", cfg.Redis.RedisHost, cfg.Redis.RedisPort),
+	})
+	defer redisClient.Close()
+
+	// media storage
+	store, err := newStorage(cfg)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - newStorage: %w", err))
+	}
+
+	// Use case
+	useCase := usecase.New(pg, cfg, l, redis, redisClient, store)
+
+	// RBAC policies live in postgres, replicas are told to reload over redis pub/sub
+	err = useCase.CasbinRuleRepo.Seed(context.Background(), cfg.RBAC.SeedPolicy)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - CasbinRuleRepo.Seed: %w", err))
+	}
+
+	enforcer, err := rbac.New(cfg.RBAC.Model, useCase.CasbinRuleRepo)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - rbac.New: %w", err))
+	}
+
+	watcher, err := rbac.NewWatcher(context.Background(), redisClient, cfg.RBAC.Channel)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - rbac.NewWatcher: %w", err))
+	}
+
+	err = enforcer.Watch(watcher, cfg.RBAC.ReloadInterval, func(err error) {
+		l.Error(fmt.Errorf("app - Run - rbac reload: %w", err))
+	})
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - enforcer.Watch: %w", err))
+	}
+	defer enforcer.Close()
+
+	// audit log retention
+	auditPurge := job.Every(cfg.Audit.PurgeInterval, func(ctx context.Context) error {
+		if cfg.Audit.Retention <= 0 {
+			return nil
+		}
+
+		rows, err := useCase.AuditLogRepo.DeleteBefore(ctx, time.Now().UTC().Add(-cfg.Audit.Retention))
+		if err != nil {
+			return err
+		}
+
+		if rows.RowsEffected > 0 {
+			l.Info(fmt.Sprintf("app - Run - audit log purge: %d entries", rows.RowsEffected))
+		}
+
+		return nil
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - audit log purge: %w", err))
+	})
+	defer auditPurge.Stop()
+
+	// accounts past their deletion grace period
+	accountPurge := job.Every(cfg.Account.PurgeInterval, func(ctx context.Context) error {
+		n, err := useCase.PurgeDeactivatedAccounts(ctx)
+		if n > 0 {
+			l.Info(fmt.Sprintf("app - Run - account purge: %d accounts", n))
+		}
+
+		return err
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - account purge: %w", err))
+	})
+	defer accountPurge.Stop()
+
+	// accounts that never verified their email
+	unverifiedPurge := job.Every(cfg.Register.PurgeInterval, func(ctx context.Context) error {
+		n, err := useCase.PurgeUnverifiedAccounts(ctx, cfg.Register.UnverifiedTTL)
+		if n > 0 {
+			l.Info(fmt.Sprintf("app - Run - unverified account purge: %d accounts", n))
+		}
+
+		return err
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - unverified account purge: %w", err))
+	})
+	defer unverifiedPurge.Stop()
+
+	// suspensions that are over
+	suspensionRestore := job.Every(cfg.Moderation.RestoreInterval, func(ctx context.Context) error {
+		n, err := useCase.RestoreExpiredSuspensions(ctx)
+		if n > 0 {
+			l.Info(fmt.Sprintf("app - Run - suspension restore: %d users", n))
+		}
+
+		return err
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - suspension restore: %w", err))
+	})
+	defer suspensionRestore.Stop()
+
+	// scheduled tweets that are due
+	tweetPublish := job.Every(cfg.Tweet.PublishInterval, func(ctx context.Context) error {
+		n, err := useCase.PublishScheduledTweets(ctx, cfg.Tweet.TagRetryDelay)
+		if n > 0 {
+			l.Info(fmt.Sprintf("app - Run - scheduled tweets: %d published", n))
+		}
+
+		return err
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - scheduled tweets: %w", err))
+	})
+	defer tweetPublish.Stop()
+
+	// polls that ended, their results are frozen
+	pollClose := job.Every(cfg.Tweet.PollCloseInterval, func(ctx context.Context) error {
+		n, err := useCase.ClosePolls(ctx)
+		if n > 0 {
+			l.Info(fmt.Sprintf("app - Run - poll close: %d polls", n))
+		}
+
+		return err
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - poll close: %w", err))
+	})
+	defer pollClose.Stop()
+
+	// data export archives
+	dataExport := job.Every(cfg.Account.ExportInterval, func(ctx context.Context) error {
+		return useCase.ProcessDataExports(ctx, cfg.Account.ExportTTL)
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - data export: %w", err))
+	})
+	defer dataExport.Stop()
+
+	// variants of uploaded photos
+	webp := imaging.NewWebPEncoder(cfg.Video.FFmpeg, cfg.Media.WebPQuality)
+	mediaVariants := job.Every(cfg.Media.ProcessInterval, func(ctx context.Context) error {
+		return useCase.ProcessMediaVariants(ctx, webp, cfg.Media)
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - media variants: %w", err))
+	})
+	defer mediaVariants.Stop()
+
+	// poster frames and HLS renditions of uploaded videos, apart from photos so a long transcode doesn't hold them up
+	transcoder := video.New(cfg.Video.FFmpeg, cfg.Video.FFprobe)
+	mediaVideos := job.Every(cfg.Video.ProcessInterval, func(ctx context.Context) error {
+		return useCase.ProcessVideos(ctx, transcoder, webp, cfg.Media, cfg.Video)
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - media videos: %w", err))
+	})
+	defer mediaVideos.Stop()
+
+	// media no tweet or avatar uses anymore
+	mediaGC := job.Every(cfg.Media.GCInterval, func(ctx context.Context) error {
+		result, err := useCase.CollectMediaGarbage(ctx, cfg.Media.GCGracePeriod, cfg.Media.GCDryRun)
+		if result.Deleted > 0 {
+			l.Info(fmt.Sprintf("app - Run - media gc: %d media, %d bytes (dry run: %t)", result.Deleted, result.Bytes, cfg.Media.GCDryRun))
+		}
+
+		return err
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - media gc: %w", err))
+	})
+	defer mediaGC.Stop()
+
+	// mail delivery
+	mail, err := newMailer(cfg, l)
+	if err != nil {
+		l.Fatal(fmt.Errorf("app - Run - newMailer: %w", err))
+	}
+
+	mailOutbox := job.Every(cfg.Mail.QueueInterval, func(ctx context.Context) error {
+		return useCase.ProcessMailOutbox(ctx, mail, cfg.Mail.MaxAttempts, cfg.Mail.RetryBackoff)
+	}, func(err error) {
+		l.Error(fmt.Errorf("app - Run - mail outbox: %w", err))
+	})
+	defer mailOutbox.Stop()
+
 	// HTTP Server
 	handler := gin.New()
-	v1.NewRouter(handler, l, cfg, useCase, redis)
+	v1.NewRouter(handler, l, cfg, useCase, redis, ratelimit.New(redisClient, "ratelimit-"), enforcer)
 
 	httpServer := httpserver.New(handler, httpserver.Port(cfg.HTTP.Port))
 
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"fmt"
+	"net/mail"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/mailer"
+)
+
+// newMailer creates the mailer of the configured driver.
+func newMailer(cfg *config.Config, l *logger.Logger) (mailer.Mailer, error) {
+	from := mail.Address{Name: cfg.Mail.FromName, Address: cfg.Mail.From}
+	if from.Address == "" {
+		from.Address = cfg.Gmail.Email
+	}
+
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return mailer.NewSMTP(mailer.SMTPConfig{
+			Host:     cfg.Gmail.Host,
+			Port:     cfg.Gmail.Port,
+			Username: cfg.Gmail.Email,
+			Password: cfg.Gmail.EmailPass,
+			Security: cfg.Mail.Security,
+			Auth:     cfg.Mail.Auth,
+			Timeout:  cfg.Mail.Timeout,
+		}, from)
+	case "file":
+		return mailer.NewFile(cfg.Mail.Dir, from)
+	case "log":
+		return mailer.NewLog(l), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+}
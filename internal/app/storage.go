@@ -0,0 +1,28 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/storage"
+)
+
+// newStorage creates the media storage of the configured kind.
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Media.Storage {
+	case "local":
+		return storage.NewLocal(cfg.Media.Dir)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PathStyle: cfg.S3.PathStyle,
+			Timeout:   cfg.S3.Timeout,
+		})
+	}
+
+	return nil, fmt.Errorf("unknown media storage %q", cfg.Media.Storage)
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// DeleteMyAccount godoc
+// @Router /me/delete [post]
+// @Summary Delete the current account
+// @Description Deactivates the account and revokes its sessions, the account is deleted permanently after the grace period unless the deletion is cancelled
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.AccountDeletion
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteMyAccount(ctx *gin.Context) {
+	principal := h.principal(ctx)
+
+	h.auditTarget(ctx, "user", principal.UserID)
+
+	// a leaked bot token must not be enough to delete the account
+	if principal.TokenID != "" {
+		h.ReturnError(ctx, config.ErrorForbidden, "Account can only be deleted from a signed in session", http.StatusForbidden)
+		return
+	}
+
+	// timestamp columns hold UTC, the purge compares them with the UTC time
+	now := time.Now().UTC()
+	scheduledAt := now.Add(h.Config.Account.DeletionGracePeriod)
+
+	rows, err := h.UseCase.UserRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "id", Type: "eq", Value: principal.UserID},
+			{Column: "status", Type: "eq", Value: "active"},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "status", Value: "deactivated"},
+			{Column: "delete_scheduled_at", Value: scheduledAt},
+			{Column: "updated_at", Value: now},
+		},
+	})
+	if h.HandleDbError(ctx, err, "Error deactivating user") {
+		return
+	}
+
+	if rows.RowsEffected == 0 {
+		h.ReturnError(ctx, config.ErrorConflict, "Only active accounts can be deleted", http.StatusConflict)
+		return
+	}
+
+	if !h.revokeSessions(ctx, principal.UserID) {
+		return
+	}
+
+	response := entity.AccountDeletion{
+		DeleteScheduledAt: scheduledAt.Format(time.RFC3339),
+	}
+
+	h.auditChange(ctx, principal.UserID,
+		map[string]interface{}{"status": "active"},
+		map[string]interface{}{"status": "deactivated", "delete_scheduled_at": response.DeleteScheduledAt},
+	)
+
+	ctx.JSON(200, response)
+}
+
+// CancelAccountDeletion godoc
+// @Router /auth/cancel-deletion [post]
+// @Summary Cancel the deletion of an account
+// @Description Reactivates an account scheduled for deletion and signs in, the sessions of a deactivated account are revoked so the credentials are required
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body entity.LoginRequest true "Credentials"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
+func (h *Handler) CancelAccountDeletion(ctx *gin.Context) {
+	var (
+		body entity.LoginRequest
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	user, ok := h.authenticate(ctx, body)
+	if !ok {
+		return
+	}
+
+	if user.Status != "deactivated" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Account is not scheduled for deletion", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.UseCase.UserRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "id", Type: "eq", Value: user.ID},
+			{Column: "status", Type: "eq", Value: "deactivated"},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "status", Value: "active"},
+			{Column: "delete_scheduled_at", Value: nil},
+			{Column: "updated_at", Value: time.Now().UTC()},
+		},
+	})
+	if h.HandleDbError(ctx, err, "Error reactivating user") {
+		return
+	}
+
+	if rows.RowsEffected == 0 {
+		h.ReturnError(ctx, config.ErrorConflict, "Account is not scheduled for deletion", http.StatusConflict)
+		return
+	}
+
+	before := user
+	user.Status = "active"
+	user.DeleteScheduledAt = ""
+
+	h.auditChange(ctx, user.ID, before, user)
+
+	if !h.platformAllowed(ctx, user, body.Platform) {
+		return
+	}
+
+	h.startSession(ctx, user, body.Platform)
+}
+
+// ExportMyData godoc
+// @Router /me/export [get]
+// @Summary Export the data of the current account
+// @Description Responds with a ZIP archive (profile, tweets, attachments, followers, following, sessions) once it is built.
+// @Description Until then the export is queued for a background job and 202 with its status is returned, poll again later.
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  application/zip
+// @Success 200 {file} file
+// @Success 202 {object} entity.DataExport
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) ExportMyData(ctx *gin.Context) {
+	userID := h.principal(ctx).UserID
+
+	h.auditTarget(ctx, "user", userID)
+
+	exports, err := h.UseCase.DataExportRepo.GetList(ctx, entity.GetListFilter{
+		Limit:   1,
+		Filters: []entity.Filter{{Column: "user_id", Type: "eq", Value: userID}},
+		OrderBy: []entity.OrderBy{{Column: "created_at", Order: "desc"}},
+	})
+	if h.HandleDbError(ctx, err, "Error getting data export") {
+		return
+	}
+
+	if len(exports.Items) > 0 {
+		export := exports.Items[0]
+
+		switch export.Status {
+		case "pending", "processing":
+			ctx.JSON(http.StatusAccepted, export)
+			return
+		case "ready":
+			expiresAt, err := time.Parse(time.RFC3339, export.ExpiresAt)
+			if err == nil && time.Now().UTC().Before(expiresAt) {
+				h.sendDataExport(ctx, export)
+				return
+			}
+		}
+	}
+
+	// no export yet, or the last one failed or expired
+	export, err := h.UseCase.DataExportRepo.Create(ctx, entity.DataExport{UserID: userID})
+	if h.HandleDbError(ctx, err, "Error creating data export") {
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, export)
+}
+
+func (h *Handler) sendDataExport(ctx *gin.Context, export entity.DataExport) {
+	archive, err := h.UseCase.DataExportRepo.GetArchive(ctx, entity.Id{ID: export.ID})
+	if h.HandleDbError(ctx, err, "Error getting data export archive") {
+		return
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, export.CreatedAt)
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, createdAt.Format("20060102-150405")))
+	ctx.Data(http.StatusOK, "application/zip", archive)
+}
+
+// revokeSessions deactivates every active session of a user, their tokens stop working right away.
+func (h *Handler) revokeSessions(ctx *gin.Context, userID string) bool {
+	_, err := h.UseCase.SessionRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "user_id", Type: "eq", Value: userID},
+			{Column: "is_active", Type: "eq", Value: "true"},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "is_active", Value: false},
+			{Column: "updated_at", Value: "now()"},
+		},
+	})
+
+	return !h.HandleDbError(ctx, err, "Error revoking sessions")
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/apitoken"
+)
+
+// apiTokenResources are the route groups a token can be scoped to,
+// a scope is <resource>:read for GET requests and <resource>:write for the rest.
+var apiTokenResources = []string{"user", "session", "tag", "follower", "tweet", "media", "me"}
+
+// CreateMyToken godoc
+// @Router /me/tokens [post]
+// @Summary Create a personal access token
+// @Description Create a personal access token, the token itself is returned only once
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param body body entity.ApiTokenCreateRequest true "Token"
+// @Success 201 {object} entity.ApiToken
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 403 {object} entity.ErrorResponse
+func (h *Handler) CreateMyToken(ctx *gin.Context) {
+	var (
+		body entity.ApiTokenCreateRequest
+	)
+
+	// a token with me:write could otherwise mint tokens with scopes it doesn't have
+	if h.principal(ctx).TokenID != "" {
+		h.ReturnError(ctx, config.ErrorForbidden, "Tokens can only be created from a signed in session", http.StatusForbidden)
+		return
+	}
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	if body.Name == "" || len(body.Scopes) == 0 || body.ExpiresInDays < 0 {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Name and at least one scope are required", 400)
+		return
+	}
+
+	for _, scope := range body.Scopes {
+		if !validScope(scope) {
+			h.ReturnError(ctx, config.ErrorBadRequest, fmt.Sprintf("Unknown scope %q", scope), 400)
+			return
+		}
+	}
+
+	token, lookup, secretHash, err := apitoken.Generate()
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+		return
+	}
+
+	req := entity.ApiToken{
+		UserID:    h.principal(ctx).UserID,
+		Name:      body.Name,
+		Lookup:    lookup,
+		TokenHash: secretHash,
+		Scopes:    body.Scopes,
+	}
+	if body.ExpiresInDays > 0 {
+		req.ExpiresAt = time.Now().UTC().AddDate(0, 0, body.ExpiresInDays).Format(time.RFC3339)
+	}
+
+	created, err := h.UseCase.ApiTokenRepo.Create(ctx, req)
+	if h.HandleDbError(ctx, err, "Error creating api token") {
+		return
+	}
+
+	h.auditChange(ctx, created.ID, nil, created)
+
+	created.Token = token
+
+	ctx.JSON(201, created)
+}
+
+// GetMyTokens godoc
+// @Router /me/tokens [get]
+// @Summary Get personal access tokens of the current user
+// @Description Get personal access tokens of the current user
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Success 200 {object} entity.ApiTokenList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMyTokens(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+	req.Filters = append(req.Filters,
+		entity.Filter{
+			Column: "user_id",
+			Type:   "eq",
+			Value:  h.principal(ctx).UserID,
+		},
+	)
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "created_at",
+		Order:  "desc",
+	})
+
+	tokens, err := h.UseCase.ApiTokenRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting api tokens") {
+		return
+	}
+
+	ctx.JSON(200, tokens)
+}
+
+// DeleteMyToken godoc
+// @Router /me/tokens/{id} [delete]
+// @Summary Revoke a personal access token
+// @Description Revoke a personal access token
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Token ID"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) DeleteMyToken(ctx *gin.Context) {
+	token, err := h.UseCase.ApiTokenRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting api token") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, token.UserID) {
+		return
+	}
+
+	err = h.UseCase.ApiTokenRepo.Delete(ctx, entity.Id{ID: token.ID})
+	if h.HandleDbError(ctx, err, "Error deleting api token") {
+		return
+	}
+
+	h.auditChange(ctx, token.ID, token, nil)
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Token revoked successfully",
+	})
+}
+
+// authenticateAPIToken validates a personal access token and its scope for the current route.
+// On success it returns the owner as principal so casbin rules apply to bots like to their owners.
+func (h *Handler) authenticateAPIToken(c *gin.Context, raw string) (entity.Principal, bool) {
+	lookup, secret, ok := apitoken.Parse(raw)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token is invalid"})
+		return entity.Principal{}, false
+	}
+
+	token, err := h.UseCase.ApiTokenRepo.GetSingle(c, entity.Id{Slug: lookup})
+	if err != nil || !apitoken.Verify(secret, token.TokenHash) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token is invalid"})
+		return entity.Principal{}, false
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt); err == nil && time.Now().UTC().After(expiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token is expired"})
+		return entity.Principal{}, false
+	}
+
+	if scope := requiredScope(c.FullPath(), c.Request.Method); scope != "" && !slices.Contains(token.Scopes, scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Token has no %s scope", scope)})
+		return entity.Principal{}, false
+	}
+
+	user, err := h.UseCase.UserRepo.GetSingle(c, entity.UserSingleRequest{ID: token.UserID})
+	if err != nil || user.Status != "active" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token owner is not active"})
+		return entity.Principal{}, false
+	}
+
+	h.touchApiToken(c, token.ID)
+
+	return entity.Principal{
+		UserID:   user.ID,
+		Role:     user.UserRole,
+		Type:     user.UserType,
+		Platform: "api",
+		TokenID:  token.ID,
+	}, true
+}
+
+// touchApiToken updates last_used_at at most once per touch interval.
+func (h *Handler) touchApiToken(ctx *gin.Context, tokenID string) {
+	key := fmt.Sprintf("api-token-touch-%s", tokenID)
+
+	if _, err := h.Redis.Get(ctx, key); err == nil {
+		return
+	}
+
+	_, err := h.UseCase.ApiTokenRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{{Column: "id", Type: "eq", Value: tokenID}},
+		Items:  []entity.UpdateFieldItem{{Column: "last_used_at", Value: time.Now().UTC()}},
+	})
+	if err != nil {
+		// the next request tries again instead of leaving last_used_at stale for a whole interval
+		h.Logger.Error(err, "Error touching api token")
+		return
+	}
+
+	err = h.Redis.Set(ctx, key, "1", int(h.Config.Session.TouchInterval.Seconds()))
+	if err != nil {
+		h.Logger.Error(err, "Error setting api token touch key")
+	}
+}
+
+// requiredScope maps a route to the scope a token needs for it, routes outside /v1 need none.
+func requiredScope(path, method string) string {
+	if !strings.HasPrefix(path, "/v1/") {
+		return ""
+	}
+
+	resource := strings.SplitN(strings.TrimPrefix(path, "/v1/"), "/", 2)[0]
+
+	if method == http.MethodGet || method == http.MethodHead {
+		return resource + ":read"
+	}
+
+	return resource + ":write"
+}
+
+func validScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok || (action != "read" && action != "write") {
+		return false
+	}
+
+	return slices.Contains(apiTokenResources, resource)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+func TestCreateMyTokenRequiresSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal entity.Principal
+		want      int
+	}{
+		{"session", entity.Principal{UserID: "u1", Role: "user", SessionID: "s1"}, 201},
+		{"api token", entity.Principal{UserID: "u1", Role: "user", TokenID: "t1"}, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := &fakeApiTokenRepo{}
+			h := &Handler{
+				Logger:  logger.New("error"),
+				Config:  &config.Config{},
+				UseCase: &usecase.UseCase{ApiTokenRepo: tokens},
+			}
+
+			ctx, recorder := newTestContext("POST", "/v1/me/tokens",
+				`{"name": "bot", "scopes": ["tweet:write", "me:write"]}`, tt.principal)
+
+			h.CreateMyToken(ctx)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if created := len(tokens.created) == 1; created != (tt.want == 201) {
+				t.Errorf("token created = %v", created)
+			}
+		})
+	}
+}
+
+func TestTouchApiTokenRetriesAfterFailedUpdate(t *testing.T) {
+	tokens := &fakeApiTokenRepo{touchErr: errors.New("connection refused")}
+	redis := &fakeRedis{values: map[string]string{}}
+
+	cfg := &config.Config{}
+	cfg.Session.TouchInterval = time.Minute
+
+	h := &Handler{
+		Logger:  logger.New("error"),
+		Config:  cfg,
+		UseCase: &usecase.UseCase{ApiTokenRepo: tokens},
+		Redis:   redis,
+	}
+
+	ctx, _ := newTestContext("GET", "/v1/tweet/list", "", entity.Principal{})
+
+	h.touchApiToken(ctx, "t1")
+	if len(redis.values) != 0 {
+		t.Fatalf("touch throttled after a failed update: %v", redis.values)
+	}
+
+	tokens.touchErr = nil
+	h.touchApiToken(ctx, "t1")
+	h.touchApiToken(ctx, "t1")
+	if tokens.touches != 1 {
+		t.Errorf("touches = %d, want 1", tokens.touches)
+	}
+}
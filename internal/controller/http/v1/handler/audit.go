@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/google/uuid"
+)
+
+const auditKey = "audit"
+
+// auditRecord is what a handler knows about the audited request beyond the route.
+type auditRecord struct {
+	actor      entity.Principal
+	targetType string
+	targetID   string
+	before     interface{}
+	after      interface{}
+	reason     string
+}
+
+// Audit writes an audit_log entry for every request to an audited operation, denied ones included.
+// It has to run before AuthMiddleware and Authorize so the entry is written when either of them aborts.
+func (h *Handler) Audit(operations map[string]usecase.Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := operations[c.Request.Method+" "+c.FullPath()]
+
+		targetType, ok := usecase.AuditedOperations[op]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		record := &auditRecord{}
+		c.Set(auditKey, record)
+
+		c.Next()
+
+		if record.targetType != "" {
+			targetType = record.targetType
+		}
+		if record.targetID == "" {
+			record.targetID = c.Param("id")
+		}
+		if record.actor.UserID == "" {
+			record.actor = h.principal(c)
+		}
+
+		entry := entity.AuditLog{
+			Action:     string(op),
+			Method:     c.Request.Method,
+			Path:       c.FullPath(),
+			TargetType: targetType,
+			TargetID:   record.targetID,
+			Status:     c.Writer.Status(),
+			Reason:     record.reason,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+
+		var err error
+		entry.Before, entry.After, err = usecase.AuditDiff(record.before, record.after)
+		if err != nil {
+			h.Logger.Error(err, "Error diffing audited change")
+		}
+
+		// the entry must be written even if the client hung up
+		err = h.UseCase.Audit(context.WithoutCancel(c.Request.Context()), record.actor, entry)
+		if err != nil {
+			h.Logger.Error(err, "Error writing audit log")
+		}
+	}
+}
+
+func auditRecordOf(ctx *gin.Context) *auditRecord {
+	value, ok := ctx.Get(auditKey)
+	if !ok {
+		// not an audited route, the record is dropped
+		return &auditRecord{}
+	}
+
+	record, _ := value.(*auditRecord)
+	return record
+}
+
+// auditChange records the state of the target before and after the request, nil for a side that doesn't exist.
+func (h *Handler) auditChange(ctx *gin.Context, targetID string, before, after interface{}) {
+	record := auditRecordOf(ctx)
+	record.targetID = targetID
+	record.before = before
+	record.after = after
+}
+
+// auditTarget records the target of the request when it is not the :id of the route.
+func (h *Handler) auditTarget(ctx *gin.Context, targetType, targetID string) {
+	record := auditRecordOf(ctx)
+	record.targetType = targetType
+	record.targetID = targetID
+}
+
+// auditActor records the actor of a request that authenticates the caller, e.g. a login.
+func (h *Handler) auditActor(ctx *gin.Context, actor entity.Principal) {
+	auditRecordOf(ctx).actor = actor
+}
+
+// auditReason records why the request failed, e.g. the reason of a failed login.
+func (h *Handler) auditReason(ctx *gin.Context, reason string) {
+	auditRecordOf(ctx).reason = reason
+}
+
+// auditEvent writes an entry besides the one of the request, e.g. the lockout a failed login caused.
+func (h *Handler) auditEvent(ctx *gin.Context, entry entity.AuditLog) {
+	entry.Method = ctx.Request.Method
+	entry.Path = ctx.FullPath()
+	entry.IPAddress = ctx.ClientIP()
+	entry.UserAgent = ctx.Request.UserAgent()
+
+	err := h.UseCase.Audit(context.WithoutCancel(ctx.Request.Context()), h.principal(ctx), entry)
+	if err != nil {
+		h.Logger.Error(err, "Error writing audit log")
+	}
+}
+
+// GetAuditLog godoc
+// @Router /admin/audit [get]
+// @Summary Get audit log
+// @Description Get audit log entries, newest first. from and to are RFC3339 timestamps
+// @Security BearerAuth
+// @Tags audit
+// @Accept  json
+// @Produce  json
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Param actor_id query string false "actor_id"
+// @Param target_type query string false "target_type"
+// @Param target_id query string false "target_id"
+// @Param action query string false "action"
+// @Param from query string false "from"
+// @Param to query string false "to"
+// @Success 200 {object} entity.AuditLogList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetAuditLog(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+
+	if actorID := ctx.Query("actor_id"); actorID != "" {
+		if _, err := uuid.Parse(actorID); err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "actor_id must be a user id", 400)
+			return
+		}
+	}
+
+	for _, column := range []string{"actor_id", "target_type", "target_id", "action"} {
+		if value := ctx.Query(column); value != "" {
+			req.Filters = append(req.Filters, entity.Filter{
+				Column: column,
+				Type:   "eq",
+				Value:  value,
+			})
+		}
+	}
+
+	for param, filterType := range map[string]string{"from": "gte", "to": "lte"} {
+		value := ctx.Query(param)
+		if value == "" {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, param+" must be an RFC3339 timestamp", 400)
+			return
+		}
+
+		req.Filters = append(req.Filters, entity.Filter{
+			Column: "created_at",
+			Type:   filterType,
+			Value:  date.UTC().Format("2006-01-02 15:04:05.999999"),
+		})
+	}
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "created_at",
+		Order:  "desc",
+	})
+
+	entries, err := h.UseCase.AuditLogRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting audit log") {
+		return
+	}
+
+	ctx.JSON(200, entries)
+}
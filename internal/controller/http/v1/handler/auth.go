@@ -1,16 +1,21 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/etc"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/hash"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/jwt"
+	"github.com/jackc/pgx/v4"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 // Login godoc
@@ -23,6 +28,7 @@ import (
 // @Param body body entity.LoginRequest true "User"
 // @Success 200 {object} entity.SuccessResponse
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
 func (h *Handler) Login(ctx *gin.Context) {
 	var (
 		body entity.LoginRequest
@@ -34,36 +40,121 @@ func (h *Handler) Login(ctx *gin.Context) {
 		return
 	}
 
+	user, ok := h.authenticate(ctx, body)
+	if !ok {
+		return
+	}
+
+	if !h.platformAllowed(ctx, user, body.Platform) {
+		return
+	}
+
+	h.startSession(ctx, user, body.Platform)
+}
+
+// authenticate checks the credentials of a login request behind the login guard (rate limit, lockout, delay).
+func (h *Handler) authenticate(ctx *gin.Context, body entity.LoginRequest) (entity.User, bool) {
+	if body.Username == "" && body.Email == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Username or email is required", 400)
+		return entity.User{}, false
+	}
+
+	if !h.loginIPAllowed(ctx) {
+		return entity.User{}, false
+	}
+
 	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{
 		UserName: body.Username,
 		Email:    body.Email,
 	})
-	if h.HandleDbError(ctx, err, "Error getting user") {
-		return
+	if err != nil && err != pgx.ErrNoRows {
+		h.HandleDbError(ctx, err, "Error getting user")
+		return entity.User{}, false
+	}
+
+	identifier := body.Email
+	if identifier == "" {
+		identifier = body.Username
+	}
+
+	h.auditLoginTarget(ctx, identifier, user)
+
+	subject := loginSubject(body, user)
+	if h.loginLocked(ctx, subject) {
+		return entity.User{}, false
 	}
 
-	if user.UserType == "user" && body.Platform == "admin" {
+	h.loginDelay(ctx, subject)
+
+	// unknown user and wrong password must be indistinguishable
+	if user.ID == "" {
+		hash.CheckPasswordHash(body.Password, dummyPasswordHash)
+		h.loginFailed(ctx, subject, identifier, user, "user_not_found")
+		h.ReturnError(ctx, config.ErrorInvalidCredentials, "Incorrect username, email or password", http.StatusBadRequest)
+		return entity.User{}, false
+	}
+
+	if !hash.CheckPasswordHash(body.Password, user.Password) {
+		h.loginFailed(ctx, subject, identifier, user, "wrong_password")
+		h.ReturnError(ctx, config.ErrorInvalidCredentials, "Incorrect username, email or password", http.StatusBadRequest)
+		return entity.User{}, false
+	}
+
+	h.loginSucceeded(ctx, subject, identifier, user)
+
+	return user, true
+}
+
+// platformAllowed keeps users out of the admin web and admins inside of it.
+func (h *Handler) platformAllowed(ctx *gin.Context, user entity.User, platform string) bool {
+	if user.UserType == "user" && platform == "admin" {
 		h.ReturnError(ctx, config.ErrorForbidden, "User can't login to admin web", http.StatusBadRequest)
-		return
-	} else if user.UserType == "admin" && body.Platform != "admin" {
+		return false
+	} else if user.UserType == "admin" && platform != "admin" {
 		h.ReturnError(ctx, config.ErrorForbidden, "Admin can only login to admin web", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// startSession creates a session for the user and responds with an access token.
+// Every way of signing in ends here so sessions look the same regardless of how the user authenticated.
+func (h *Handler) startSession(ctx *gin.Context, user entity.User, platform string) {
+	if user.Status == "inverify" {
+		h.auditReason(ctx, "unverified")
+		h.ReturnError(ctx, config.ErrorEmailNotVerified,
+			"Verify your email address to sign in, a new code can be requested with resend-verification", http.StatusForbidden)
 		return
 	}
 
-	if !hash.CheckPasswordHash(body.Password, user.Password) {
-		h.ReturnError(ctx, config.ErrorInvalidPass, "Incorrect password", http.StatusBadRequest)
+	if user.Status == "suspended" {
+		h.auditReason(ctx, "suspended")
+		h.ReturnError(ctx, config.ErrorAccountSuspended, "Account is suspended until "+user.SuspendedUntil, http.StatusForbidden)
+		return
+	}
+
+	if user.Status == "blocked" {
+		h.auditReason(ctx, "banned")
+		h.ReturnError(ctx, config.ErrorAccountBanned, "Account is banned", http.StatusForbidden)
+		return
+	}
+
+	if user.Status == "deactivated" {
+		h.auditReason(ctx, "deactivated")
+		h.ReturnError(ctx, config.ErrorAccountDeactivated,
+			"Account is scheduled for deletion at "+user.DeleteScheduledAt+", cancel the deletion to sign in again", http.StatusForbidden)
 		return
 	}
 
-	// create session
 	newSession := entity.Session{
 		UserID:       user.ID,
 		IPAddress:    ctx.ClientIP(),
-		ExpiresAt:    time.Now().Add(time.Hour * 999999).Format(time.RFC3339),
+		ExpiresAt:    time.Now().UTC().Add(h.Config.Session.AbsoluteTimeout).Format(time.RFC3339),
 		UserAgent:    ctx.Request.UserAgent(),
 		IsActive:     true,
-		LastActiveAt: time.Now().Format(time.RFC3339),
-		Platform:     body.Platform,
+		LastActiveAt: time.Now().UTC().Format(time.RFC3339),
+		Platform:     platform,
 	}
 
 	session, err := h.UseCase.SessionRepo.Create(ctx, newSession)
@@ -76,16 +167,21 @@ func (h *Handler) Login(ctx *gin.Context) {
 		"sub":        user.ID,
 		"user_role":  user.UserRole,
 		"user_type":  user.UserType,
-		"platform":   body.Platform,
+		"platform":   platform,
 		"session_id": session.ID,
 	}
 
+	h.auditActor(ctx, entity.Principal{UserID: user.ID, Role: user.UserRole})
+	h.auditTarget(ctx, "user", user.ID)
+
 	user.AccessToken, err = jwt.GenerateJWT(jwtFields, h.Config.JWT.Secret)
 	if err != nil {
 		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
 		return
 	}
 
+	user.Password = ""
+
 	ctx.JSON(200, gin.H{
 		"user":    user,
 		"session": session,
@@ -103,7 +199,7 @@ func (h *Handler) Login(ctx *gin.Context) {
 // @Success 200 {object} entity.SuccessResponse
 // @Failure 400 {object} entity.ErrorResponse
 func (h *Handler) Logout(ctx *gin.Context) {
-	sessionID := ctx.GetHeader("session_id")
+	sessionID := h.principal(ctx).SessionID
 	if sessionID == "" {
 		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid session ID", 400)
 		return
@@ -116,6 +212,8 @@ func (h *Handler) Logout(ctx *gin.Context) {
 		return
 	}
 
+	h.auditTarget(ctx, "session", sessionID)
+
 	ctx.JSON(200, entity.SuccessResponse{
 		Message: "Successfully logged out",
 	})
@@ -124,13 +222,14 @@ func (h *Handler) Logout(ctx *gin.Context) {
 // Register godoc
 // @Router /auth/register [post]
 // @Summary Register
-// @Description Register
+// @Description Register, a verification code is sent to the email. Registering again with the email and password of an unverified account sends a new code.
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param body body entity.RegisterRequest true "User"
-// @Success 200 {object} entity.User
+// @Success 201 {object} entity.SuccessResponse
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
 func (h *Handler) Register(ctx *gin.Context) {
 	var (
 		body entity.RegisterRequest
@@ -142,12 +241,39 @@ func (h *Handler) Register(ctx *gin.Context) {
 		return
 	}
 
-	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{
-		UserName: body.Username,
-		Email:    body.Email,
-	})
+	if body.Email == "" || body.Username == "" || body.Password == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Email, username and password are required", 400)
+		return
+	}
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{Email: body.Email})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.HandleDbError(ctx, err, "Error getting user")
+		return
+	}
+
+	// a registration that didn't get its code through is retried by registering again,
+	// the password has to match so nobody else can take over the pending account
+	if user.ID != "" {
+		if user.Status != "inverify" || !hash.CheckPasswordHash(body.Password, user.Password) {
+			h.ReturnError(ctx, config.ErrorConflict, "User already exists", http.StatusConflict)
+			return
+		}
+
+		h.auditTarget(ctx, "user", user.ID)
+		if h.verificationResendAllowed(ctx, user.Email) {
+			h.sendVerification(ctx, user)
+		}
+		return
+	}
+
+	_, err = h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{UserName: body.Username})
 	if err == nil {
-		h.ReturnError(ctx, config.ErrorConflict, "User already exists", 400)
+		h.ReturnError(ctx, config.ErrorConflict, "Username is already taken", http.StatusConflict)
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		h.HandleDbError(ctx, err, "Error getting user")
 		return
 	}
 
@@ -167,46 +293,124 @@ func (h *Handler) Register(ctx *gin.Context) {
 		Password: body.Password,
 		Gender:   body.Gender,
 	})
+	// a concurrent registration took the email or username
+	if isUniqueViolation(err) {
+		h.ReturnError(ctx, config.ErrorConflict, "User already exists", http.StatusConflict)
+		return
+	}
 	if h.HandleDbError(ctx, err, "Error creating user") {
 		return
 	}
 
-	// send verification code to user
-	otp := etc.GenerateOTP(6)
-	err = h.Redis.Set(ctx, fmt.Sprintf("otp-%s", user.Email), otp, 5*60)
-	if err != nil {
-		h.ReturnError(ctx, config.ErrorInternalServer, "Error setting OTP", 500)
+	h.auditChange(ctx, user.ID, nil, user)
+
+	h.sendVerification(ctx, user)
+}
+
+// ResendVerification godoc
+// @Router /auth/resend-verification [post]
+// @Summary Resend the verification code
+// @Description Sends a new verification code to the email of an unverified account. The response doesn't tell whether such an account exists.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body entity.ResendVerification true "Email"
+// @Success 201 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
+func (h *Handler) ResendVerification(ctx *gin.Context) {
+	var (
+		body entity.ResendVerification
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || body.Email == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
 		return
 	}
 
-	// send otp code to user's email
-	emailBody, err := etc.GenerateOtpEmailBody(otp)
-	if err != nil {
-		h.ReturnError(ctx, config.ErrorInternalServer, "Error sending OTP", 500)
+	// counted before the lookup, so unknown addresses are limited the same way
+	if !h.verificationResendAllowed(ctx, body.Email) {
 		return
 	}
 
-	err = etc.SendEmail(h.Config.Gmail.Host, h.Config.Gmail.Port, h.Config.Gmail.Email, h.Config.Gmail.EmailPass, body.Email, emailBody)
-	if err != nil {
-		h.ReturnError(ctx, config.ErrorInternalServer, "Error sending OTP", 500)
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{Email: body.Email})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.HandleDbError(ctx, err, "Error getting user")
 		return
 	}
 
+	if user.ID == "" || user.Status != "inverify" {
+		ctx.JSON(201, entity.SuccessResponse{
+			Message: verificationSentMessage,
+		})
+		return
+	}
+
+	h.auditTarget(ctx, "user", user.ID)
+	h.sendVerification(ctx, user)
+}
+
+const verificationSentMessage = "Verification code is sent, please verify your email address"
+
+// verificationResendAllowed lets a new code be sent to an address once every Register.ResendInterval.
+func (h *Handler) verificationResendAllowed(ctx *gin.Context, email string) bool {
+	hits, err := h.Limiter.Hit(ctx, "verify-resend-"+strings.ToLower(email), h.Config.Register.ResendInterval)
+	if err != nil {
+		h.Logger.Error(err, "Error counting verification resends")
+		return true
+	}
+
+	if hits > 1 {
+		h.auditReason(ctx, "rate_limited")
+		h.ReturnError(ctx, config.ErrorTooManyRequests, "A code was sent recently, try again later", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+// sendVerification stores a new verification code of the user and mails it, a previous code stops working.
+// Failures are only logged, the account stays unverified and the user asks for a new code.
+func (h *Handler) sendVerification(ctx *gin.Context, user entity.User) {
+	key := verificationKey(user.Email)
+	otp := etc.GenerateOTP(6)
+
+	err := h.Redis.Set(ctx, key, otp, int(h.Config.Register.OtpTTL.Seconds()))
+	if err == nil {
+		err = h.Limiter.Reset(ctx, key)
+	}
+	if err == nil {
+		// the code is delivered in the background, a failing mail server doesn't fail the registration
+		err = h.sendMail(ctx, user.Email, usecase.MailVerifyEmail, map[string]interface{}{
+			"Code":    otp,
+			"Minutes": int(h.Config.Register.OtpTTL.Minutes()),
+		})
+	}
+	if err != nil {
+		h.Logger.Error(err, "Error sending verification code")
+	}
+
 	ctx.JSON(201, entity.SuccessResponse{
-		Message: "User registered successfully, please verify your email address",
+		Message: verificationSentMessage,
 	})
 }
 
-// Register godoc
+func verificationKey(email string) string {
+	return fmt.Sprintf("otp-%s", email)
+}
+
+// VerifyEmail godoc
 // @Router /auth/verify-email [post]
-// @Summary Register
-// @Description Register
+// @Summary Verify email
+// @Description Activates an account with the code sent to its email and signs in
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param body body entity.VerifyEmail true "User"
 // @Success 200 {object} entity.User
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
 func (h *Handler) VerifyEmail(ctx *gin.Context) {
 	var (
 		body entity.VerifyEmail
@@ -218,9 +422,30 @@ func (h *Handler) VerifyEmail(ctx *gin.Context) {
 		return
 	}
 
-	key := fmt.Sprintf("otp-%s", body.Email)
+	key := verificationKey(body.Email)
+
+	attempts, err := h.Limiter.Hit(ctx, key, h.Config.Register.OtpTTL)
+	if err != nil {
+		// without the count the code could be guessed without limit
+		h.Logger.Error(err, "Error counting verification attempts")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+		return
+	}
+	if attempts > int64(h.Config.Register.MaxAttempts) {
+		err = h.Redis.Del(ctx, key)
+		if err != nil {
+			h.Logger.Error(err, "Error voiding verification code")
+		}
+
+		h.ReturnError(ctx, config.ErrorTooManyRequests, "Too many wrong codes, request a new one", http.StatusTooManyRequests)
+		return
+	}
 
 	otp, err := h.Redis.Get(ctx, key)
+	if errors.Is(err, goredis.Nil) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Code is expired, request a new one", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		h.ReturnError(ctx, config.ErrorInternalServer, "Ooops, something went wrong", http.StatusInternalServerError)
 		return
@@ -238,46 +463,26 @@ func (h *Handler) VerifyEmail(ctx *gin.Context) {
 		return
 	}
 
-	user.Status = "active"
-
-	_, err = h.UseCase.UserRepo.Update(ctx, user)
-	if h.HandleDbError(ctx, err, "update user") {
+	if user.Status != "inverify" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Email is already verified", http.StatusBadRequest)
 		return
 	}
 
-	// create session
-	newSession := entity.Session{
-		UserID:       user.ID,
-		IPAddress:    ctx.ClientIP(),
-		ExpiresAt:    time.Now().Add(time.Hour * 999999).Format(time.RFC3339),
-		UserAgent:    ctx.Request.UserAgent(),
-		IsActive:     true,
-		LastActiveAt: time.Now().Format(time.RFC3339),
-		Platform:     body.Platform,
-	}
+	before := user
+	user.Status = "active"
 
-	session, err := h.UseCase.SessionRepo.Create(ctx, newSession)
-	if h.HandleDbError(ctx, err, "Error while creating new session") {
+	_, err = h.UseCase.UserRepo.Update(ctx, user)
+	if h.HandleDbError(ctx, err, "update user") {
 		return
 	}
 
-	// generate jwt token
-	jwtFields := map[string]interface{}{
-		"sub":        user.ID,
-		"user_role":  user.UserRole,
-		"user_type":  user.UserType,
-		"platform":   body.Platform,
-		"session_id": session.ID,
-	}
+	h.auditChange(ctx, user.ID, before, user)
 
-	user.AccessToken, err = jwt.GenerateJWT(jwtFields, h.Config.JWT.Secret)
-	if err != nil {
-		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
-		return
+	for _, err = range []error{h.Redis.Del(ctx, key), h.Limiter.Reset(ctx, key)} {
+		if err != nil {
+			h.Logger.Error(err, "Error removing verification code")
+		}
 	}
 
-	ctx.JSON(200, gin.H{
-		"user":    user,
-		"session": session,
-	})
+	h.startSession(ctx, user, body.Platform)
 }
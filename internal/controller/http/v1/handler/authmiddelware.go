@@ -1,66 +1,56 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/casbin/casbin"
 	"github.com/gin-gonic/gin"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/apitoken"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/jwt"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/rbac"
 )
 
-func (h *Handler) AuthMiddleware(e *casbin.Enforcer) gin.HandlerFunc {
+// AuthMiddleware authenticates the caller and stores it as entity.Principal in the request context.
+// Identity is only ever taken from a verified token, never from request headers.
+func (h *Handler) AuthMiddleware(e *rbac.Enforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var (
-			userRole string
-			act      = c.Request.Method
-			obj      = c.FullPath()
+			principal entity.Principal
+			userRole  = "unauthorized"
+			act       = c.Request.Method
+			obj       = c.FullPath()
 		)
 
-		token := c.GetHeader("Authorization")
-		if token == "" {
-			userRole = "unauthorized"
-		}
-
-		if userRole == "" {
-			token = strings.TrimPrefix(token, "Bearer ")
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
 
-			claims, err := jwt.ParseJWT(token, h.Config.JWT.Secret)
-			if err != nil {
-				userRole = "unauthorized"
-			}
+		switch {
+		case token == "":
+		// personal access tokens of bots, validated against api_token instead of a session
+		case strings.HasPrefix(token, apitoken.Prefix):
+			var ok bool
 
-			v, ok := claims["user_role"].(string)
+			principal, ok = h.authenticateAPIToken(c, token)
 			if !ok {
-				userRole = "unauthorized"
-			} else {
-				userRole = v
+				return
 			}
-
-			for key, value := range claims {
-				c.Request.Header.Set(key, fmt.Sprintf("%v", value))
+		default:
+			claims, err := jwt.ParseJWT(token, h.Config.JWT.Secret)
+			if err == nil {
+				principal = principalFromClaims(claims)
 			}
-		}
 
-		// TO DO: Check if session is valid
-
-		if userRole != "unauthorized" {
-			session, err := h.UseCase.SessionRepo.GetSingle(c, entity.Id{ID: c.GetHeader("session_id")})
-			if err != nil {
-				fmt.Println("error while gettign single session", err)
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session is invalid"})
+			if principal.IsAuthenticated() && !h.validSession(c, principal) {
 				return
 			}
+		}
 
-			if !session.IsActive {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session is not active"})
-				return
-			}
+		if principal.IsAuthenticated() {
+			userRole = principal.Role
+			c.Request = c.Request.WithContext(entity.ContextWithPrincipal(c.Request.Context(), principal))
 		}
 
-		ok, err := e.EnforceSafe(userRole, obj, act)
+		ok, err := e.Enforce(userRole, obj, act)
 		if err != nil {
 			h.Logger.Error(err, "Error enforcing policy")
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
@@ -75,3 +65,52 @@ func (h *Handler) AuthMiddleware(e *casbin.Enforcer) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// validSession checks that the session of the token is active, unexpired and belongs to the token's user.
+func (h *Handler) validSession(c *gin.Context, principal entity.Principal) bool {
+	session, err := h.UseCase.SessionRepo.GetSingle(c, entity.Id{ID: principal.SessionID})
+	if err != nil || session.UserID != principal.UserID {
+		if err != nil {
+			h.Logger.Error(err, "Error getting session of token")
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session is invalid"})
+		return false
+	}
+
+	if !session.IsActive {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session is not active"})
+		return false
+	}
+
+	if h.sessionExpired(session) {
+		h.deactivateSession(c, session.ID)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session is expired"})
+		return false
+	}
+
+	h.touchSession(c, session.ID)
+
+	return true
+}
+
+// principalFromClaims reads the claims startSession puts into a token, tokens without a user or role stay anonymous.
+func principalFromClaims(claims map[string]interface{}) entity.Principal {
+	str := func(key string) string {
+		value, _ := claims[key].(string)
+		return value
+	}
+
+	principal := entity.Principal{
+		UserID:    str("sub"),
+		Role:      str("user_role"),
+		Type:      str("user_type"),
+		Platform:  str("platform"),
+		SessionID: str("session_id"),
+	}
+
+	if principal.UserID == "" || principal.Role == "" {
+		return entity.Principal{}
+	}
+
+	return principal
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+const (
+	operationKey    = "operation"
+	ownerCheckedKey = "owner_checked"
+)
+
+// Authorize enforces the usecase policy of the operation a route is mapped to.
+// Routes without an operation are denied. Owned policies are only checked for an authenticated
+// caller here, the handler completes the check with AuthorizeOwner once it has loaded the resource.
+// The response of an owned operation is held back until the handler returns, a handler that succeeds
+// without checking the owner fails with 500 instead of leaking the resource.
+func (h *Handler) Authorize(operations map[string]usecase.Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op, ok := operations[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			h.Logger.Error(fmt.Sprintf("no operation is declared for %s %s", c.Request.Method, c.FullPath()))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			return
+		}
+
+		principal := h.principal(c)
+		policy := usecase.Policies[op]
+
+		c.Set(operationKey, op)
+
+		if policy.Owned && !principal.IsAuthenticated() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication is required"})
+			return
+		}
+
+		if !policy.Owned && usecase.Authorize(principal, op, "") != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			return
+		}
+
+		if !policy.Owned {
+			c.Next()
+			return
+		}
+
+		writer := &heldResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+
+		if !c.GetBool(ownerCheckedKey) && writer.status < http.StatusBadRequest {
+			h.Logger.Error(fmt.Sprintf("operation %s succeeded without an owner check", op))
+			h.ReturnError(c, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+			c.Abort()
+			return
+		}
+
+		writer.release()
+	}
+}
+
+// heldResponseWriter buffers a response until release writes it out.
+type heldResponseWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *heldResponseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *heldResponseWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *heldResponseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *heldResponseWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *heldResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *heldResponseWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+
+	return w.body.Len()
+}
+
+func (w *heldResponseWriter) Written() bool {
+	return w.written
+}
+
+// Flush is a no-op, nothing reaches the client before release.
+func (w *heldResponseWriter) Flush() {}
+
+func (w *heldResponseWriter) release() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+// AuthorizeOwner checks the policy of the current operation against the owner of the loaded resource.
+func (h *Handler) AuthorizeOwner(ctx *gin.Context, ownerID string) bool {
+	ctx.Set(ownerCheckedKey, true)
+
+	op, _ := ctx.Get(operationKey)
+	operation, _ := op.(usecase.Operation)
+
+	return h.authorize(ctx, operation, ownerID)
+}
+
+// authorize checks an operation besides the one of the route, e.g. a role change within an update.
+func (h *Handler) authorize(ctx *gin.Context, op usecase.Operation, ownerID string) bool {
+	err := usecase.Authorize(h.principal(ctx), op, ownerID)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorForbidden, "You have no access to the resource", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// principal returns the caller authenticated by AuthMiddleware, anonymous callers get an empty principal.
+func (h *Handler) principal(ctx *gin.Context) entity.Principal {
+	return entity.PrincipalFromContext(ctx.Request.Context())
+}
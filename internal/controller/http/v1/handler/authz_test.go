@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+// newAuthorizeEngine serves stub routes behind Authorize, the caller is taken from the principal header.
+func newAuthorizeEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	h := &Handler{Logger: logger.New("error"), Config: &config.Config{}}
+
+	callers := map[string]entity.Principal{
+		"owner": {UserID: "owner-id", Role: "user"},
+		"other": {UserID: "other-id", Role: "user"},
+		"admin": {UserID: "admin-id", Role: "admin"},
+	}
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(func(c *gin.Context) {
+		principal := callers[c.GetHeader("principal")]
+		c.Request = c.Request.WithContext(entity.ContextWithPrincipal(c.Request.Context(), principal))
+	})
+
+	checked := func(c *gin.Context) {
+		if !h.AuthorizeOwner(c, c.Param("owner")) {
+			return
+		}
+		c.JSON(200, gin.H{"secret": "of " + c.Param("owner")})
+	}
+	unchecked := func(c *gin.Context) {
+		c.JSON(200, gin.H{"secret": "of " + c.Param("owner")})
+	}
+
+	group := engine.Group("", h.Authorize(map[string]usecase.Operation{
+		"POST /login":                   usecase.OpAuthLogin,    // anyone
+		"GET /users":                    usecase.OpUserList,     // admin
+		"PUT /tweet/:owner":             usecase.OpTweetUpdate,  // owner or admin
+		"POST /tweet/:owner/publish":    usecase.OpTweetPublish, // owner
+		"DELETE /tweet/:owner":          usecase.OpTweetDelete,  // owner or admin, handler forgets the check
+		"GET /tweet/:owner/not-checked": usecase.OpMediaGet,     // owner or admin, handler fails first
+	}))
+	group.POST("/login", unchecked)
+	group.GET("/users", unchecked)
+	group.PUT("/tweet/:owner", checked)
+	group.POST("/tweet/:owner/publish", checked)
+	group.DELETE("/tweet/:owner", unchecked)
+	group.GET("/tweet/:owner/not-checked", func(c *gin.Context) {
+		h.ReturnError(c, config.ErrorNotFound, "Media not found", http.StatusNotFound)
+	})
+	group.GET("/undeclared", unchecked)
+
+	return engine
+}
+
+func TestAuthorize(t *testing.T) {
+	engine := newAuthorizeEngine()
+
+	tests := []struct {
+		method, path string
+		want         map[string]int // status by caller, "" is anonymous
+	}{
+		{"POST", "/login", map[string]int{"": 200, "owner": 200, "admin": 200}},
+		{"GET", "/users", map[string]int{"": 403, "owner": 403, "admin": 200}},
+		{"PUT", "/tweet/owner-id", map[string]int{"": 401, "owner": 200, "other": 403, "admin": 200}},
+		{"POST", "/tweet/owner-id/publish", map[string]int{"": 401, "owner": 200, "other": 403, "admin": 403}},
+		// an owned operation is never served without the owner being checked
+		{"DELETE", "/tweet/owner-id", map[string]int{"": 401, "owner": 500, "other": 500, "admin": 500}},
+		{"GET", "/tweet/owner-id/not-checked", map[string]int{"": 401, "owner": 404, "other": 404}},
+		{"GET", "/undeclared", map[string]int{"": 403, "owner": 403, "admin": 403}},
+	}
+
+	for _, tt := range tests {
+		for caller, want := range tt.want {
+			t.Run(tt.method+" "+tt.path+" as "+caller, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				req.Header.Set("principal", caller)
+
+				rec := httptest.NewRecorder()
+				engine.ServeHTTP(rec, req)
+
+				if rec.Code != want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, want, rec.Body.String())
+				}
+				if want != 200 && strings.Contains(rec.Body.String(), "secret") {
+					t.Errorf("denied response leaks the resource: %s", rec.Body.String())
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/etc"
+	"github.com/jackc/pgx/v4"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// The code of an email change is keyed by account, not by address like the otp-<email> code of VerifyEmail,
+// and holds the address it was sent to, so a code only ever confirms the address of the account that asked for it.
+func emailChangeKey(userID string) string {
+	return "email-change-" + userID
+}
+
+// sendMail queues an email in the language of the request, the message is queued even if the client hangs up.
+func (h *Handler) sendMail(ctx *gin.Context, to, template string, data map[string]interface{}) error {
+	return h.UseCase.SendMail(context.WithoutCancel(ctx.Request.Context()), to, template, ctx.GetHeader("Accept-Language"), data)
+}
+
+// ChangeMyEmail godoc
+// @Router /me/email [post]
+// @Summary Change the email of the current account
+// @Description Claims the new address for the account and sends a code to it, the address is changed once the code is confirmed. The current address is notified.
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param body body entity.EmailChangeRequest true "New email"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) ChangeMyEmail(ctx *gin.Context) {
+	var (
+		body      entity.EmailChangeRequest
+		principal = h.principal(ctx)
+	)
+
+	h.auditTarget(ctx, "user", principal.UserID)
+
+	// the email is how an account is recovered, a bot token must not be able to move it
+	if principal.TokenID != "" {
+		h.ReturnError(ctx, config.ErrorForbidden, "Email can only be changed from a signed in session", http.StatusForbidden)
+		return
+	}
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	email := strings.TrimSpace(body.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > 50 {
+		h.ReturnError(ctx, config.ErrorInvalidEmail, "Invalid email address", 400)
+		return
+	}
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: principal.UserID})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return
+	}
+
+	if user.Status != "active" {
+		h.ReturnError(ctx, config.ErrorForbidden, "Only active accounts can change their email", http.StatusForbidden)
+		return
+	}
+
+	if email == user.Email {
+		h.ReturnError(ctx, config.ErrorBadRequest, "This is already the email of the account", 400)
+		return
+	}
+
+	_, err = h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{Email: email})
+	if err == nil {
+		h.ReturnError(ctx, config.ErrorConflict, "Email is already taken", http.StatusConflict)
+		return
+	}
+	if err != pgx.ErrNoRows {
+		h.HandleDbError(ctx, err, "Error getting user")
+		return
+	}
+
+	// pending_email_expires_at holds UTC, like every timestamp column
+	now := time.Now().UTC()
+
+	// claims of other accounts that were never confirmed don't hold the address any more
+	_, err = h.UseCase.UserRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "pending_email", Type: "eq", Value: email},
+			{Column: "pending_email_expires_at", Type: "lt", Value: now.Format(time.RFC3339)},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "pending_email", Value: nil},
+			{Column: "pending_email_expires_at", Value: nil},
+		},
+	})
+	if h.HandleDbError(ctx, err, "Error releasing expired email claims") {
+		return
+	}
+
+	// the unique index on pending_email lets a single account claim the address
+	_, err = h.UseCase.UserRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "id", Type: "eq", Value: user.ID},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "pending_email", Value: email},
+			{Column: "pending_email_expires_at", Value: now.Add(h.Config.EmailChange.OtpTTL)},
+			{Column: "updated_at", Value: now},
+		},
+	})
+	if isUniqueViolation(err) {
+		h.ReturnError(ctx, config.ErrorConflict, "Email is already taken", http.StatusConflict)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error saving pending email") {
+		return
+	}
+
+	otp := etc.GenerateOTP(6)
+
+	err = h.Redis.Set(ctx, emailChangeKey(user.ID), otp+":"+email, int(h.Config.EmailChange.OtpTTL.Seconds()))
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error setting OTP", 500)
+		return
+	}
+
+	err = h.Limiter.Reset(ctx, emailChangeKey(user.ID))
+	if err != nil {
+		h.Logger.Error(err, "Error resetting email change attempts")
+	}
+
+	err = h.sendMail(ctx, email, usecase.MailEmailChangeOtp, map[string]interface{}{
+		"Code":    otp,
+		"Minutes": int(h.Config.EmailChange.OtpTTL.Minutes()),
+	})
+	if err != nil {
+		h.Logger.Error(err, "Error sending email change OTP")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error sending OTP", 500)
+		return
+	}
+
+	err = h.sendMail(ctx, user.Email, usecase.MailEmailChangeNotice, map[string]interface{}{
+		"NewEmail": email,
+	})
+	if err != nil {
+		h.Logger.Error(err, "Error sending email change notice")
+	}
+
+	h.auditChange(ctx, user.ID,
+		map[string]interface{}{"pending_email": user.PendingEmail},
+		map[string]interface{}{"pending_email": email},
+	)
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Verification code is sent to the new email address",
+	})
+}
+
+// ConfirmMyEmail godoc
+// @Router /me/email/confirm [post]
+// @Summary Confirm the new email of the current account
+// @Description Confirms the code sent to the new address and makes it the email of the account
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param body body entity.EmailChangeConfirm true "Code"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+// @Failure 429 {object} entity.ErrorResponse
+func (h *Handler) ConfirmMyEmail(ctx *gin.Context) {
+	var (
+		body      entity.EmailChangeConfirm
+		principal = h.principal(ctx)
+	)
+
+	h.auditTarget(ctx, "user", principal.UserID)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: principal.UserID})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return
+	}
+
+	if user.PendingEmail == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "No email change is pending", 400)
+		return
+	}
+
+	key := emailChangeKey(user.ID)
+
+	attempts, err := h.Limiter.Hit(ctx, key, h.Config.EmailChange.OtpTTL)
+	if err != nil {
+		// without the count the code could be guessed without limit
+		h.Logger.Error(err, "Error counting email change attempts")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+		return
+	}
+	if attempts > int64(h.Config.EmailChange.MaxAttempts) {
+		err = h.Redis.Del(ctx, key)
+		if err != nil {
+			h.Logger.Error(err, "Error voiding email change OTP")
+		}
+
+		h.ReturnError(ctx, config.ErrorTooManyRequests, "Too many wrong codes, request a new one", http.StatusTooManyRequests)
+		return
+	}
+
+	value, err := h.Redis.Get(ctx, key)
+	if errors.Is(err, goredis.Nil) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Code is expired, request a new one", 400)
+		return
+	}
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Ooops, something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	otp, email, _ := strings.Cut(value, ":")
+	if otp != body.Otp || email != user.PendingEmail {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Incorrect otp", http.StatusBadRequest)
+		return
+	}
+
+	// users.email is unique, an address taken since the claim fails here instead of being shared
+	rows, err := h.UseCase.UserRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "id", Type: "eq", Value: user.ID},
+			{Column: "pending_email", Type: "eq", Value: email},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "email", Value: email},
+			{Column: "pending_email", Value: nil},
+			{Column: "pending_email_expires_at", Value: nil},
+			{Column: "updated_at", Value: time.Now().UTC()},
+		},
+	})
+	if isUniqueViolation(err) {
+		h.ReturnError(ctx, config.ErrorConflict, "Email is already taken", http.StatusConflict)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error changing email") {
+		return
+	}
+
+	if rows.RowsEffected == 0 {
+		h.ReturnError(ctx, config.ErrorConflict, "Email change was replaced by a newer one", http.StatusConflict)
+		return
+	}
+
+	if err = h.Redis.Del(ctx, key); err != nil {
+		h.Logger.Error(err, "Error deleting email change OTP")
+	}
+	if err = h.Limiter.Reset(ctx, key); err != nil {
+		h.Logger.Error(err, "Error resetting email change attempts")
+	}
+
+	h.auditChange(ctx, user.ID,
+		map[string]interface{}{"email": user.Email},
+		map[string]interface{}{"email": email},
+	)
+
+	user.Email = email
+	user.PendingEmail = ""
+	user.Password = ""
+
+	ctx.JSON(200, user)
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/ratelimit"
+)
+
+// Without a count of the attempts the code could be guessed without limit, so an unreachable limiter fails the request.
+func TestConfirmMyEmailFailsClosed(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]entity.User{
+		"u1": {ID: "u1", Email: "old@example.com", PendingEmail: "new@example.com"},
+	}}
+	redisClient := &fakeRedis{values: map[string]string{
+		emailChangeKey("u1"): "654321:new@example.com",
+	}}
+
+	h := &Handler{
+		Logger:  logger.New("error"),
+		Config:  &config.Config{},
+		UseCase: &usecase.UseCase{UserRepo: users},
+		Redis:   redisClient,
+		Limiter: ratelimit.New(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}), ""),
+	}
+
+	ctx, recorder := newTestContext("POST", "/v1/me/email/confirm", `{"otp":"000000"}`, entity.Principal{UserID: "u1", Role: "user"})
+
+	h.ConfirmMyEmail(ctx)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusInternalServerError, recorder.Body.String())
+	}
+	if users.users["u1"].Email != "old@example.com" {
+		t.Errorf("email = %s, want it unchanged", users.users["u1"].Email)
+	}
+}
@@ -89,3 +89,9 @@ func (h Handler) ReturnError(c *gin.Context, code string, message string, status
 	}
 	c.JSON(statusCode, errorResponse)
 }
+
+// isUniqueViolation reports whether err is a postgres unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -32,8 +31,12 @@ func (h *Handler) FollowUnfollow(ctx *gin.Context) {
 		return
 	}
 
-	if ctx.GetHeader("user_type") == "user" {
-		body.FollowerId = ctx.GetHeader("sub")
+	if body.FollowerId == "" {
+		body.FollowerId = h.principal(ctx).UserID
+	}
+
+	if !h.AuthorizeOwner(ctx, body.FollowerId) {
+		return
 	}
 
 	follower, err := h.UseCase.FollowerRepo.UpsertOrRemove(ctx, body)
@@ -123,12 +126,11 @@ func (h *Handler) GetFollowers(ctx *gin.Context) {
 	search := ctx.DefaultQuery("search", "")
 	following_id := ctx.DefaultQuery("following_id", "")
 
-	if ctx.GetHeader("user_type") == "user" {
-		following_id = ctx.GetHeader("sub")
+	if following_id == "" {
+		following_id = h.principal(ctx).UserID
 	}
 
-	if following_id == "" {
-		h.ReturnError(ctx, config.ErrorBadRequest, "following_id is required", http.StatusBadRequest)
+	if !h.AuthorizeOwner(ctx, following_id) {
 		return
 	}
 
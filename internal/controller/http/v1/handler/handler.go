@@ -5,20 +5,41 @@ import (
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/oidc"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/ratelimit"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/rbac"
 )
 
 type Handler struct {
-	Logger  *logger.Logger
-	Config  *config.Config
-	UseCase *usecase.UseCase
-	Redis   rediscache.RedisCache
+	Logger   *logger.Logger
+	Config   *config.Config
+	UseCase  *usecase.UseCase
+	Redis    rediscache.RedisCache
+	Limiter  *ratelimit.Limiter
+	OAuth    map[string]*oidc.Provider
+	Enforcer *rbac.Enforcer
 }
 
-func NewHandler(l *logger.Logger, c *config.Config, useCase *usecase.UseCase, redis rediscache.RedisCache) *Handler {
+func NewHandler(l *logger.Logger, c *config.Config, useCase *usecase.UseCase, redis rediscache.RedisCache, limiter *ratelimit.Limiter, enforcer *rbac.Enforcer) *Handler {
+	providers := make(map[string]*oidc.Provider, len(c.OAuth.Providers))
+	for name, p := range c.OAuth.Providers {
+		providers[name] = oidc.NewProvider(oidc.Config{
+			Name:         name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+
 	return &Handler{
-		Logger:  l,
-		Config:  c,
-		UseCase: useCase,
-		Redis:   redis,
+		Logger:   l,
+		Config:   c,
+		UseCase:  useCase,
+		Redis:    redis,
+		Limiter:  limiter,
+		OAuth:    providers,
+		Enforcer: enforcer,
 	}
 }
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	rediscache "github.com/golanguzb70/redis-cache"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+// newTestContext returns the context of a request made by principal, with a JSON body unless body is empty.
+func newTestContext(method, path, body string, principal entity.Principal, params ...gin.Param) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Request = ctx.Request.WithContext(entity.ContextWithPrincipal(ctx.Request.Context(), principal))
+	ctx.Params = params
+
+	return ctx, recorder
+}
+
+// The fakes keep their rows in memory and implement only the methods the tested handlers call.
+
+type fakeUserRepo struct {
+	usecase.UserRepoI
+	users map[string]entity.User
+}
+
+func (r *fakeUserRepo) GetSingle(_ context.Context, req entity.UserSingleRequest) (entity.User, error) {
+	for _, user := range r.users {
+		if (req.ID != "" && user.ID == req.ID) || (req.Email != "" && user.Email == req.Email) {
+			return user, nil
+		}
+	}
+
+	return entity.User{}, pgx.ErrNoRows
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, req entity.User) (entity.User, error) {
+	if req.Password == "" {
+		req.Password = r.users[req.ID].Password
+	}
+	r.users[req.ID] = req
+
+	return req, nil
+}
+
+func (r *fakeUserRepo) Delete(_ context.Context, req entity.Id) error {
+	delete(r.users, req.ID)
+
+	return nil
+}
+
+type fakeSessionRepo struct {
+	usecase.SessionRepoI
+	sessions    []entity.Session
+	invalidated []string
+}
+
+func (r *fakeSessionRepo) Invalidate(_ context.Context, ids ...string) {
+	r.invalidated = append(r.invalidated, ids...)
+}
+
+func (r *fakeSessionRepo) UpdateField(_ context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	response := entity.RowsEffected{}
+
+	for i, session := range r.sessions {
+		if session.UserID != req.Filter[0].Value || !session.IsActive {
+			continue
+		}
+
+		r.sessions[i].IsActive = false
+		response.RowsEffected++
+	}
+
+	return response, nil
+}
+
+type fakeIdentityRepo struct {
+	usecase.IdentityRepoI
+	identities []entity.Identity
+}
+
+func (r *fakeIdentityRepo) GetSingle(_ context.Context, req entity.IdentitySingleRequest) (entity.Identity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == req.Provider && identity.Subject == req.Subject {
+			return identity, nil
+		}
+	}
+
+	return entity.Identity{}, pgx.ErrNoRows
+}
+
+func (r *fakeIdentityRepo) Create(_ context.Context, req entity.Identity) (entity.Identity, error) {
+	r.identities = append(r.identities, req)
+
+	return req, nil
+}
+
+type fakeRedis struct {
+	rediscache.RedisCache
+	values map[string]string
+}
+
+func (r *fakeRedis) Get(_ context.Context, key string) (string, error) {
+	value, ok := r.values[key]
+	if !ok {
+		return "", errors.New("redis: nil")
+	}
+
+	return value, nil
+}
+
+func (r *fakeRedis) Set(_ context.Context, key, value string, _ int) error {
+	r.values[key] = value
+
+	return nil
+}
+
+func (r *fakeRedis) Del(_ context.Context, key string) error {
+	delete(r.values, key)
+
+	return nil
+}
+
+type fakeApiTokenRepo struct {
+	usecase.ApiTokenRepoI
+	created  []entity.ApiToken
+	touchErr error
+	touches  int
+}
+
+func (r *fakeApiTokenRepo) Create(_ context.Context, req entity.ApiToken) (entity.ApiToken, error) {
+	r.created = append(r.created, req)
+
+	return req, nil
+}
+
+func (r *fakeApiTokenRepo) UpdateField(_ context.Context, _ entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	if r.touchErr != nil {
+		return entity.RowsEffected{}, r.touchErr
+	}
+
+	r.touches++
+
+	return entity.RowsEffected{RowsEffected: 1}, nil
+}
+
+type fakeUserModerationRepo struct {
+	usecase.UserModerationRepoI
+	users   *fakeUserRepo
+	revoke  []string
+	changes []entity.UserModerationChange
+}
+
+func (r *fakeUserModerationRepo) Moderate(_ context.Context, req entity.UserModerationChange) (entity.UserModeration, []string, error) {
+	user, ok := r.users.users[req.Moderation.UserID]
+	if !ok || user.Status != req.From {
+		return entity.UserModeration{}, nil, pgx.ErrNoRows
+	}
+
+	user.Status = req.Status
+	user.SuspendedUntil = req.Moderation.SuspendedUntil
+	r.users.users[user.ID] = user
+	r.changes = append(r.changes, req)
+
+	return req.Moderation, r.revoke, nil
+}
+
+type fakeTweetRepo struct {
+	usecase.TweetI
+	tweets  map[string]entity.Tweet
+	locked  map[string]entity.Tweet // the tweets as Update finds them, when they changed since GetSingle
+	created []entity.Tweet
+	updated []entity.Tweet
+	err     error
+}
+
+func (r *fakeTweetRepo) Create(_ context.Context, req entity.Tweet) (entity.Tweet, error) {
+	if r.err != nil {
+		return entity.Tweet{}, r.err
+	}
+
+	req.Id = "c9d8e7f6-5a4b-4c3d-9e2f-1a0b9c8d7e6f"
+	r.created = append(r.created, req)
+
+	return req, nil
+}
+
+func (r *fakeTweetRepo) GetSingle(_ context.Context, req entity.Id) (entity.Tweet, error) {
+	tweet, ok := r.tweets[req.ID]
+	if !ok {
+		return entity.Tweet{}, pgx.ErrNoRows
+	}
+
+	return tweet, nil
+}
+
+func (r *fakeTweetRepo) Update(_ context.Context, req entity.Tweet, check func(stored entity.Tweet) error) (entity.Tweet, error) {
+	stored, ok := r.locked[req.Id]
+	if !ok {
+		stored = r.tweets[req.Id]
+	}
+
+	if check != nil {
+		if err := check(stored); err != nil {
+			return entity.Tweet{}, err
+		}
+	}
+
+	r.updated = append(r.updated, req)
+	r.tweets[req.Id] = req
+
+	return req, nil
+}
+
+type fakeAttachmentRepo struct {
+	usecase.TweetAttachentRepoI
+	attachments map[string][]entity.Attachment
+}
+
+func (r *fakeAttachmentRepo) GetList(_ context.Context, req entity.GetListFilter) (entity.AttachmentList, error) {
+	items := r.attachments[req.Filters[0].Value]
+
+	return entity.AttachmentList{Items: items, Count: int64(len(items))}, nil
+}
+
+type fakeTagRepo struct {
+	usecase.TagRepoI
+}
+
+func (r *fakeTagRepo) TagTweetByContent(_ context.Context, req entity.Tweet) (entity.Tweet, error) {
+	return req, nil
+}
+
+type fakePollRepo struct {
+	usecase.PollRepoI
+}
+
+func (r *fakePollRepo) GetByTweets(_ context.Context, _ []string, _ string) (map[string]entity.Poll, error) {
+	return map[string]entity.Poll{}, nil
+}
+
+type fakeReportRepo struct {
+	usecase.ReportRepoI
+	reports  []entity.Report
+	revoke   []string
+	err      error
+	resolved []entity.ReportResolve
+}
+
+func (r *fakeReportRepo) Resolve(_ context.Context, req entity.ReportResolve) ([]entity.Report, []string, error) {
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+
+	r.resolved = append(r.resolved, req)
+
+	return r.reports, r.revoke, nil
+}
+
+type fakeMediaRepo struct {
+	usecase.MediaRepoI
+	media map[string]entity.Media
+}
+
+func (r *fakeMediaRepo) GetSingle(_ context.Context, req entity.Id) (entity.Media, error) {
+	media, ok := r.media[req.ID]
+	if !ok {
+		return entity.Media{}, pgx.ErrNoRows
+	}
+
+	return media, nil
+}
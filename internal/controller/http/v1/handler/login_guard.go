@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+// dummyPasswordHash is checked when the user doesn't exist,
+// so a missing account takes as long to reject as a wrong password.
+const dummyPasswordHash = "$2a$10$Hhw3uQOCYphqJgSkbr1sneVbGQ.cXwPoRYT96u413ltvoT9g35XZK"
+
+// loginSubject is the key failed attempts are counted against: the user id when the
+// account exists, otherwise the submitted email/username so unknown accounts lock the same way.
+func loginSubject(body entity.LoginRequest, user entity.User) string {
+	if user.ID != "" {
+		return "user-" + user.ID
+	}
+
+	if body.Email != "" {
+		return "email-" + strings.ToLower(body.Email)
+	}
+
+	return "username-" + strings.ToLower(body.Username)
+}
+
+// loginIPAllowed counts the attempt against the client ip and rejects it when the ip is over its budget.
+func (h *Handler) loginIPAllowed(ctx *gin.Context) bool {
+	hits, err := h.Limiter.Hit(ctx, "login-ip-"+ctx.ClientIP(), h.Config.Login.Window)
+	if err != nil {
+		h.Logger.Error(err, "Error counting login attempts")
+		return true
+	}
+
+	if hits > int64(h.Config.Login.MaxPerIP) {
+		h.auditReason(ctx, "rate_limited")
+		h.ReturnError(ctx, config.ErrorTooManyRequests, "Too many login attempts, try again later", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+// loginLocked rejects the request when the subject is temporarily locked out.
+func (h *Handler) loginLocked(ctx *gin.Context, subject string) bool {
+	until, err := h.Redis.Get(ctx, "login-lock-"+subject)
+	if err != nil || until == "" {
+		return false
+	}
+
+	h.auditReason(ctx, "locked")
+	h.ReturnError(ctx, config.ErrorAccountLocked, "Too many failed attempts, try again after "+until, http.StatusTooManyRequests)
+	return true
+}
+
+// loginDelay slows down every next attempt of a subject that has recent failures.
+func (h *Handler) loginDelay(ctx *gin.Context, subject string) {
+	failures, err := h.Limiter.Count(ctx, "login-fail-"+subject, h.Config.Login.Window)
+	if err != nil || failures == 0 {
+		return
+	}
+
+	delay := time.Duration(failures) * h.Config.Login.DelayStep
+	if delay > h.Config.Login.MaxDelay {
+		delay = h.Config.Login.MaxDelay
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Request.Context().Done():
+	}
+}
+
+// loginFailed records the failure and locks the subject once it reaches the failure threshold.
+func (h *Handler) loginFailed(ctx *gin.Context, subject, identifier string, user entity.User, reason string) {
+	h.recordLoginAttempt(ctx, identifier, user.ID, false, reason)
+	h.auditReason(ctx, reason)
+
+	failures, err := h.Limiter.Hit(ctx, "login-fail-"+subject, h.Config.Login.Window)
+	if err != nil {
+		h.Logger.Error(err, "Error counting failed logins")
+		return
+	}
+
+	if failures < int64(h.Config.Login.MaxFailures) {
+		return
+	}
+
+	until := time.Now().Add(h.Config.Login.LockoutDuration).Format(time.RFC3339)
+
+	err = h.Redis.Set(ctx, "login-lock-"+subject, until, int(h.Config.Login.LockoutDuration.Seconds()))
+	if err != nil {
+		h.Logger.Error(err, "Error locking account")
+		return
+	}
+
+	err = h.Limiter.Reset(ctx, "login-fail-"+subject)
+	if err != nil {
+		h.Logger.Error(err, "Error resetting failed logins")
+	}
+
+	h.recordLoginAttempt(ctx, identifier, user.ID, false, "locked")
+	h.auditEvent(ctx, entity.AuditLog{
+		Action:     usecase.AuditLoginLockout,
+		TargetType: "user",
+		TargetID:   user.ID,
+		After:      map[string]interface{}{"locked_until": until},
+	})
+
+	if user.Email == "" {
+		return
+	}
+
+	err = h.sendMail(ctx, user.Email, usecase.MailLoginLockout, map[string]interface{}{
+		"Until": until,
+	})
+	if err != nil {
+		h.Logger.Error(err, "Error sending lockout notice")
+	}
+}
+
+// loginSucceeded records the attempt and forgets previous failures of the subject.
+func (h *Handler) loginSucceeded(ctx *gin.Context, subject, identifier string, user entity.User) {
+	h.recordLoginAttempt(ctx, identifier, user.ID, true, "")
+
+	err := h.Limiter.Reset(ctx, "login-fail-"+subject)
+	if err != nil {
+		h.Logger.Error(err, "Error resetting failed logins")
+	}
+}
+
+func (h *Handler) recordLoginAttempt(ctx *gin.Context, identifier, userID string, success bool, reason string) {
+	// audit must survive the client hanging up
+	_, err := h.UseCase.LoginAttemptRepo.Create(context.WithoutCancel(ctx.Request.Context()), entity.LoginAttempt{
+		UserID:     userID,
+		Identifier: identifier,
+		IPAddress:  ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		Success:    success,
+		Reason:     reason,
+	})
+	if err != nil {
+		h.Logger.Error(err, fmt.Sprintf("Error recording login attempt of %s", identifier))
+	}
+}
+
+// auditLoginTarget records the account a login was aimed at, the submitted identifier when there is none.
+func (h *Handler) auditLoginTarget(ctx *gin.Context, identifier string, user entity.User) {
+	if user.ID != "" {
+		h.auditTarget(ctx, "user", user.ID)
+		return
+	}
+
+	h.auditTarget(ctx, "identifier", identifier)
+}
@@ -0,0 +1,679 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// multipartOverhead is the room left for the multipart framing around the file of an upload.
+const multipartOverhead = 1 << 20
+
+// maxHLSFileSize bounds the playlists and segments ServeMedia reads whole.
+const maxHLSFileSize = 64 << 20
+
+// UploadMedia godoc
+// @Router /media [post]
+// @Summary Upload media
+// @Description Uploads a photo or video in one request as the file field of a multipart form, the content type of the field is checked against the content. The id of the returned media is attached to tweets. Large files are better sent with a chunked upload.
+// @Security BearerAuth
+// @Tags media
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "jpeg, png, gif, webp, mp4 or webm"
+// @Success 201 {object} entity.Media
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 413 {object} entity.ErrorResponse
+// @Failure 415 {object} entity.ErrorResponse
+func (h *Handler) UploadMedia(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, h.Config.Media.MaxVideoSize+multipartOverhead)
+
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Expected a multipart/form-data body", 400)
+		return
+	}
+
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "file is required", 400)
+			return
+		}
+
+		if part.FormName() == "file" {
+			break
+		}
+	}
+	defer part.Close()
+
+	contentType, ok := h.mediaContentType(ctx, part.Header.Get("Content-Type"))
+	if !ok {
+		return
+	}
+
+	maxSize := usecase.MediaMaxSize(h.Config.Media, contentType)
+
+	// object storages need the size upfront, which a multipart part tells only once it is read
+	file, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error receiving file", 500)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	size, err := io.Copy(file, io.LimitReader(part, maxSize+1))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || size > maxSize {
+		h.ReturnError(ctx, config.ErrorPayloadTooLarge, fmt.Sprintf("%s files can be at most %d bytes", contentType, maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Error reading file", 400)
+		return
+	}
+
+	if size == 0 {
+		h.ReturnError(ctx, config.ErrorBadRequest, "File is empty", 400)
+		return
+	}
+
+	head := make([]byte, usecase.MediaSniffLength)
+	n, _ := file.ReadAt(head, 0)
+	if !h.mediaContentMatches(ctx, contentType, head[:n]) {
+		return
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error receiving file", 500)
+		return
+	}
+
+	media := entity.Media{
+		ID:             uuid.NewString(),
+		OwnerID:        h.principal(ctx).UserID,
+		Kind:           usecase.MediaTypes[contentType],
+		ContentType:    contentType,
+		Size:           size,
+		Received:       size,
+		Status:         "ready",
+		VariantsStatus: usecase.MediaVariantsStatus(usecase.MediaTypes[contentType]),
+	}
+	media.StorageKey = "media/" + media.ID
+
+	err = h.UseCase.Storage.Put(ctx, media.StorageKey, file, size, contentType)
+	if err != nil {
+		h.Logger.Error(err, "Error storing media")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error storing media", 500)
+		return
+	}
+
+	created, err := h.UseCase.MediaRepo.Create(ctx, media)
+	if err != nil {
+		if deleteErr := h.UseCase.Storage.Delete(ctx, media.StorageKey); deleteErr != nil {
+			h.Logger.Error(deleteErr, "Error deleting orphaned media")
+		}
+	}
+	if h.HandleDbError(ctx, err, "Error creating media") {
+		return
+	}
+
+	ctx.JSON(201, created)
+}
+
+// CreateMediaUpload godoc
+// @Router /media/uploads [post]
+// @Summary Start a chunked media upload
+// @Description Starts a resumable upload of a photo or video of the given size. The chunks are sent in order with PUT /media/uploads/{id}, an interrupted upload resumes from the received offset of GET /media/{id}.
+// @Security BearerAuth
+// @Tags media
+// @Accept  json
+// @Produce  json
+// @Param body body entity.MediaUploadRequest true "Content type and size in bytes"
+// @Success 201 {object} entity.Media
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 413 {object} entity.ErrorResponse
+// @Failure 415 {object} entity.ErrorResponse
+func (h *Handler) CreateMediaUpload(ctx *gin.Context) {
+	var body entity.MediaUploadRequest
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	contentType, ok := h.mediaContentType(ctx, body.ContentType)
+	if !ok {
+		return
+	}
+
+	maxSize := usecase.MediaMaxSize(h.Config.Media, contentType)
+	if body.Size > maxSize {
+		h.ReturnError(ctx, config.ErrorPayloadTooLarge, fmt.Sprintf("%s files can be at most %d bytes", contentType, maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if body.Size <= 0 {
+		h.ReturnError(ctx, config.ErrorBadRequest, "size must be positive", 400)
+		return
+	}
+
+	media, err := h.UseCase.MediaRepo.Create(ctx, entity.Media{
+		OwnerID:     h.principal(ctx).UserID,
+		Kind:        usecase.MediaTypes[contentType],
+		ContentType: contentType,
+		Size:        body.Size,
+		Status:      "uploading",
+	})
+	if h.HandleDbError(ctx, err, "Error creating media upload") {
+		return
+	}
+
+	ctx.JSON(201, media)
+}
+
+// UploadMediaChunk godoc
+// @Router /media/uploads/{id} [put]
+// @Summary Upload a chunk of a chunked media upload
+// @Description Stores the bytes of the Content-Range header, e.g. bytes 0-1048575/5242880. Chunks are sent in order, each starting at the received offset of the upload. The upload is ready once the last chunk is stored.
+// @Security BearerAuth
+// @Tags media
+// @Accept  application/octet-stream
+// @Produce  json
+// @Param id path string true "Media ID"
+// @Param Content-Range header string true "bytes start-end/size"
+// @Success 200 {object} entity.Media
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+// @Failure 413 {object} entity.ErrorResponse
+// @Failure 415 {object} entity.ErrorResponse
+func (h *Handler) UploadMediaChunk(ctx *gin.Context) {
+	media, err := h.UseCase.MediaRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting media") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, media.OwnerID) {
+		return
+	}
+
+	if media.Status != "uploading" {
+		h.ReturnError(ctx, config.ErrorConflict, "Upload is already complete", http.StatusConflict)
+		return
+	}
+
+	start, end, total, ok := parseContentRange(ctx.GetHeader("Content-Range"))
+	if !ok || total != media.Size || end >= total {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Content-Range must be bytes start-end/"+strconv.FormatInt(media.Size, 10), 400)
+		return
+	}
+
+	if start != media.Received {
+		h.ReturnError(ctx, config.ErrorConflict, "Expected the chunk at offset "+strconv.FormatInt(media.Received, 10), http.StatusConflict)
+		return
+	}
+
+	length := end - start + 1
+	if length > h.Config.Media.MaxChunkSize {
+		h.ReturnError(ctx, config.ErrorPayloadTooLarge, fmt.Sprintf("Chunks can be at most %d bytes", h.Config.Media.MaxChunkSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if ctx.Request.ContentLength != length {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Content-Length must match Content-Range", 400)
+		return
+	}
+
+	body := bufio.NewReaderSize(io.LimitReader(ctx.Request.Body, length), usecase.MediaSniffLength)
+
+	if start == 0 {
+		head, _ := body.Peek(usecase.MediaSniffLength)
+		if !h.mediaContentMatches(ctx, media.ContentType, head) {
+			return
+		}
+	}
+
+	err = h.UseCase.Storage.Put(ctx, usecase.MediaChunkKey(media.ID, media.Chunks), body, length, "")
+	if err != nil {
+		h.Logger.Error(err, "Error storing media chunk")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error storing chunk, send it again", 500)
+		return
+	}
+
+	received := entity.Media{ID: media.ID, Chunks: media.Chunks + 1, Size: media.Size, ContentType: media.ContentType, StorageKey: media.StorageKey}
+	items := []entity.UpdateFieldItem{
+		{Column: "received", Value: end + 1},
+		{Column: "chunks", Value: received.Chunks},
+		{Column: "updated_at", Value: time.Now().UTC()},
+	}
+
+	// the last chunk is joined with the others before the upload is marked ready,
+	// a failure leaves the received offset unchanged, so the last chunk is sent again
+	complete := end+1 == total
+	if complete {
+		err = h.UseCase.AssembleMedia(ctx, received)
+		if err != nil {
+			h.Logger.Error(err, "Error assembling media")
+			h.ReturnError(ctx, config.ErrorInternalServer, "Error storing media, send the last chunk again", 500)
+			return
+		}
+
+		items = append(items,
+			entity.UpdateFieldItem{Column: "status", Value: "ready"},
+			entity.UpdateFieldItem{Column: "variants_status", Value: usecase.MediaVariantsStatus(media.Kind)},
+		)
+	}
+
+	// the offset read above must still hold, a chunk sent twice at once is stored once
+	rows, err := h.UseCase.MediaRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{Column: "id", Type: "eq", Value: media.ID},
+			{Column: "status", Type: "eq", Value: "uploading"},
+			{Column: "received", Type: "eq", Value: strconv.FormatInt(start, 10)},
+		},
+		Items: items,
+	})
+	if h.HandleDbError(ctx, err, "Error updating media upload") {
+		return
+	}
+
+	if rows.RowsEffected == 0 {
+		h.ReturnError(ctx, config.ErrorConflict, "Upload was changed meanwhile, check its received offset", http.StatusConflict)
+		return
+	}
+
+	if complete {
+		h.UseCase.DeleteMediaChunks(ctx, received)
+	}
+
+	media, err = h.UseCase.MediaRepo.GetSingle(ctx, entity.Id{ID: media.ID})
+	if h.HandleDbError(ctx, err, "Error getting media") {
+		return
+	}
+
+	ctx.JSON(200, media)
+}
+
+// GetMediaUpload godoc
+// @Router /media/uploads/{id} [get]
+// @Summary Get media
+// @Description Get uploaded media, the received offset tells where an interrupted chunked upload resumes
+// @Security BearerAuth
+// @Tags media
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Media ID"
+// @Success 200 {object} entity.Media
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) GetMediaUpload(ctx *gin.Context) {
+	media, err := h.UseCase.MediaRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting media") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, media.OwnerID) {
+		return
+	}
+
+	h.signVariants(media.ID, media.Variants, usecase.MediaURLExpiry(h.Config.Media.URLTTL, time.Now()))
+
+	ctx.JSON(200, media)
+}
+
+// ServeMedia godoc
+// @Router /media/{id} [get]
+// @Summary Serve media
+// @Description Streams a file of uploaded media from the signed url found in attachments and avatar variants, no token is needed. The url opens only the variant and file it was signed for and works until it expires, only while the media is attached to a tweet the caller can see or is the avatar of a user who isn't suspended or banned, its owner and admins can always use it. A video is served once it's transcoded, only to its owner and admins while it's processing and to no one when it failed. Range and conditional requests are supported. Playlists of the hls variant are served with signed urls of their renditions and segments.
+// @Tags media
+// @Produce  octet-stream
+// @Param id path string true "Media ID"
+// @Param variant query string false "Variant name, the original when empty"
+// @Param file query string false "Playlist or segment of the hls variant, the master playlist when empty"
+// @Param expires query string true "Expiry of the url, a unix time"
+// @Param signature query string true "Signature of the url"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Failure 403 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) ServeMedia(ctx *gin.Context) {
+	mediaID := ctx.Param("id")
+
+	// a url opens only the variant and file it was signed for
+	err := usecase.CheckMediaSignature(h.mediaURLSecret(), mediaID, ctx.Query("variant"), ctx.Query("file"),
+		ctx.Query("expires"), ctx.Query("signature"), time.Now())
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorForbidden, "Media url is invalid or expired", http.StatusForbidden)
+		return
+	}
+	expiresUnix, _ := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	expires := time.Unix(expiresUnix, 0)
+
+	media, err := h.UseCase.MediaRepo.GetSingle(ctx, entity.Id{ID: mediaID})
+	if h.HandleDbError(ctx, err, "Error getting media") {
+		return
+	}
+
+	if media.Status != "ready" {
+		h.ReturnError(ctx, config.ErrorNotFound, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	// media of a deleted user has no owner and only waits to be garbage collected
+	principal := h.principal(ctx)
+	owned := principal.IsAuthenticated() && media.OwnerID == principal.UserID
+	if !owned && !principal.IsAdmin() {
+		visible, err := h.mediaVisible(ctx, principal, media)
+		if h.HandleDbError(ctx, err, "Error checking media visibility") {
+			return
+		}
+
+		if !visible {
+			h.ReturnError(ctx, config.ErrorNotFound, "Media not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// a video is only served once it's transcoded, its owner and admins can get the original while it's processing
+	if media.Kind == "video" && media.VariantsStatus != "ready" {
+		processing := media.VariantsStatus == "pending" || media.VariantsStatus == "processing"
+		if !processing || !owned && !principal.IsAdmin() {
+			h.ReturnError(ctx, config.ErrorNotFound, "Media not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	name := ctx.DefaultQuery("variant", "original")
+	variant, ok := media.Variants[name]
+	if name == "original" {
+		variant, ok = entity.MediaVariant{FilePath: media.StorageKey, ContentType: media.ContentType, Size: media.Size}, true
+	}
+	if !ok {
+		h.ReturnError(ctx, config.ErrorNotFound, "Media variant not found", http.StatusNotFound)
+		return
+	}
+
+	if name != "hls" {
+		content := storage.NewReader(ctx, h.UseCase.Storage, variant.FilePath, variant.Size)
+		defer content.Close()
+
+		h.serveMediaContent(ctx, media, variant.FilePath, variant.ContentType, expires, content)
+		return
+	}
+
+	// playlists are small and rewritten, segments are read whole as players fetch them whole
+	file, ok := usecase.MediaHLSFile(ctx.Query("file"))
+	if !ok {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid file", 400)
+		return
+	}
+
+	key := usecase.MediaHLSKey(media.ID, file)
+	object, err := h.UseCase.Storage.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.ReturnError(ctx, config.ErrorNotFound, "Media file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error(err, "Error getting media file")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error getting media file", 500)
+		return
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(io.LimitReader(object, maxHLSFileSize))
+	if err != nil {
+		h.Logger.Error(err, "Error reading media file")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error reading media file", 500)
+		return
+	}
+
+	contentType := usecase.MediaHLSContentType(file)
+	if path.Ext(file) == ".m3u8" {
+		data = usecase.SignPlaylist(data, h.mediaURLSecret(), media.ID, path.Dir(file), expires)
+	}
+
+	h.serveMediaContent(ctx, media, key, contentType, expires, bytes.NewReader(data))
+}
+
+// mediaContentType validates the declared content type of an upload, it writes the error response itself.
+func (h *Handler) mediaContentType(ctx *gin.Context, declared string) (string, bool) {
+	contentType, err := usecase.MediaContentType(declared)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorUnsupportedMediaType, "Content type must be one of image/jpeg, image/png, image/gif, image/webp, video/mp4 or video/webm", http.StatusUnsupportedMediaType)
+		return "", false
+	}
+
+	return contentType, true
+}
+
+// mediaContentMatches sniffs the first bytes of an upload against its content type, it writes the error response itself.
+func (h *Handler) mediaContentMatches(ctx *gin.Context, contentType string, head []byte) bool {
+	if usecase.CheckMediaContent(contentType, head) != nil {
+		h.ReturnError(ctx, config.ErrorUnsupportedMediaType, "Content is not "+contentType, http.StatusUnsupportedMediaType)
+		return false
+	}
+
+	return true
+}
+
+// attachMedia fills the attachments of a tweet: the ones it already has from current, they can only be moved or
+// get another alt text, and the new ones from the media they reference, only ready media of the tweet's owner can
+// be attached. The limits of the attachments of a tweet are checked too. It writes the error response itself.
+func (h *Handler) attachMedia(ctx *gin.Context, ownerID string, current, attachments []entity.Attachment) bool {
+	for i, attachment := range attachments {
+		if attachment.Id != "" {
+			j := slices.IndexFunc(current, func(a entity.Attachment) bool { return a.Id == attachment.Id })
+			if j < 0 {
+				h.ReturnError(ctx, config.ErrorBadRequest, "Attachment "+attachment.Id+" is not an attachment of the tweet", 400)
+				return false
+			}
+
+			attachments[i].MediaId = current[j].MediaId
+			attachments[i].FilePath = current[j].FilePath
+			attachments[i].ContentType = current[j].ContentType
+			continue
+		}
+
+		if attachment.MediaId == "" {
+			h.ReturnError(ctx, config.ErrorBadRequest, "media_id of an attachment is required, upload the file with POST /media first", 400)
+			return false
+		}
+
+		if _, err := uuid.Parse(attachment.MediaId); err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Invalid media_id", 400)
+			return false
+		}
+
+		media, err := h.UseCase.MediaRepo.GetSingle(ctx, entity.Id{ID: attachment.MediaId})
+		if err == nil && media.OwnerID != ownerID {
+			h.ReturnError(ctx, config.ErrorNotFound, "Media not found", http.StatusNotFound)
+			return false
+		}
+		if h.HandleDbError(ctx, err, "Error getting media") {
+			return false
+		}
+
+		if media.Status != "ready" {
+			h.ReturnError(ctx, config.ErrorConflict, "Media "+media.ID+" is still uploading", http.StatusConflict)
+			return false
+		}
+
+		// a video still processing can be attached, the attachment shows as processing until it's ready
+		if media.VariantsStatus == "failed" || media.VariantsStatus == "rejected" {
+			h.ReturnError(ctx, config.ErrorBadRequest, "Media "+media.ID+" can't be processed: "+media.VariantsError, 400)
+			return false
+		}
+
+		attachments[i].FilePath = media.StorageKey
+		attachments[i].ContentType = media.Kind
+	}
+
+	if err := usecase.CheckAttachments(attachments); err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid attachments: "+err.Error(), 400)
+		return false
+	}
+
+	return true
+}
+
+// avatarMedia checks that mediaID is a ready photo of the user, it writes the error response itself.
+func (h *Handler) avatarMedia(ctx *gin.Context, userID, mediaID string) bool {
+	if _, err := uuid.Parse(mediaID); err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "avatar_id must be the id of an uploaded photo", 400)
+		return false
+	}
+
+	media, err := h.UseCase.MediaRepo.GetSingle(ctx, entity.Id{ID: mediaID})
+	if err == nil && media.OwnerID != userID {
+		h.ReturnError(ctx, config.ErrorNotFound, "Media not found", http.StatusNotFound)
+		return false
+	}
+	if h.HandleDbError(ctx, err, "Error getting media") {
+		return false
+	}
+
+	if media.Kind != "photo" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "avatar_id must be the id of an uploaded photo", 400)
+		return false
+	}
+
+	if media.Status != "ready" {
+		h.ReturnError(ctx, config.ErrorConflict, "Media "+media.ID+" is still uploading", http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// parseContentRange parses a Content-Range header of the form bytes start-end/size.
+func parseContentRange(header string) (start, end, size int64, ok bool) {
+	_, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &size)
+	if err != nil || start < 0 || end < start || size <= 0 || header != fmt.Sprintf("bytes %d-%d/%d", start, end, size) {
+		return 0, 0, 0, false
+	}
+
+	return start, end, size, true
+}
+
+// mediaVisible tells whether principal can see media it doesn't own: the avatar of a user who isn't suspended or
+// banned, or media attached to a tweet the principal can see.
+func (h *Handler) mediaVisible(ctx *gin.Context, principal entity.Principal, media entity.Media) (bool, error) {
+	if media.OwnerID == "" {
+		return false, nil
+	}
+
+	owner, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: media.OwnerID})
+	if err != nil {
+		return false, err
+	}
+
+	if owner.AvatarId == media.ID && !moderatedStatus(owner.Status) {
+		return true, nil
+	}
+
+	attachments, err := h.UseCase.TweetAttachmentsRepo.GetList(ctx, entity.GetListFilter{
+		Filters: []entity.Filter{{Column: "media_id", Type: "eq", Value: media.ID}},
+		Page:    1,
+		Limit:   10,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, attachment := range attachments.Items {
+		tweet, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: attachment.TweetId})
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		// media is attached to tweets of its owner only
+		tweet.Owner = owner
+		if tweetVisible(principal, tweet) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// serveMediaContent serves a file of media with range and conditional requests, it's cached privately as long
+// as the url it's served from works.
+func (h *Handler) serveMediaContent(ctx *gin.Context, media entity.Media, key, contentType string, expires time.Time, content io.ReadSeeker) {
+	etag := sha256.Sum256([]byte(key + "\n" + media.UpdatedAt))
+	modified, _ := time.Parse(time.RFC3339, media.UpdatedAt)
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("ETag", `"`+hex.EncodeToString(etag[:16])+`"`)
+	header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", max(0, int(time.Until(expires).Seconds()))))
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", modified, content)
+}
+
+// mediaURLSecret is the secret media urls are signed with.
+func (h *Handler) mediaURLSecret() string {
+	if h.Config.Media.URLSecret != "" {
+		return h.Config.Media.URLSecret
+	}
+
+	return h.Config.JWT.Secret
+}
+
+// signAttachments sets the signed urls of the media of attachments and of its variants.
+func (h *Handler) signAttachments(attachments []entity.Attachment) {
+	expires := usecase.MediaURLExpiry(h.Config.Media.URLTTL, time.Now())
+
+	for i, attachment := range attachments {
+		// attachments made before media uploads have no media to serve, nor do videos that failed processing
+		if attachment.MediaId == "" || attachment.Status == "failed" {
+			continue
+		}
+
+		attachments[i].URL = usecase.MediaURL(h.mediaURLSecret(), attachment.MediaId, "", expires)
+		h.signVariants(attachment.MediaId, attachment.Variants, expires)
+	}
+}
+
+// signAvatar sets the signed urls of the avatar variants of a user.
+func (h *Handler) signAvatar(user *entity.User) {
+	if user.AvatarId == "" {
+		return
+	}
+
+	h.signVariants(user.AvatarId, user.AvatarVariants, usecase.MediaURLExpiry(h.Config.Media.URLTTL, time.Now()))
+}
+
+// signVariants sets the signed urls of the variants of a media.
+func (h *Handler) signVariants(mediaID string, variants map[string]entity.MediaVariant, expires time.Time) {
+	for name, variant := range variants {
+		variant.URL = usecase.MediaURL(h.mediaURLSecret(), mediaID, name, expires)
+		variants[name] = variant
+	}
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/storage"
+)
+
+func TestServeMediaVideoOriginal(t *testing.T) {
+	const mediaID = "7f1c2a44-0d7e-4f0e-9f55-3a9d3c1b6e21"
+
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Put(context.Background(), "originals/video", bytes.NewReader([]byte("raw video")), 9, "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := entity.Principal{UserID: "u1", Role: "user"}
+	admin := entity.Principal{UserID: "u3", Role: "admin"}
+	viewer := entity.Principal{UserID: "u2", Role: "user"}
+
+	tests := []struct {
+		status    string
+		principal entity.Principal
+		want      int
+	}{
+		{"ready", viewer, 200},
+		{"processing", owner, 200},
+		{"pending", admin, 200},
+		{"processing", viewer, 404},
+		{"failed", owner, 404},
+		{"rejected", owner, 404},
+		{"failed", admin, 404},
+		{"rejected", viewer, 404},
+	}
+
+	for _, tt := range tests {
+		h := &Handler{
+			Logger: logger.New("error"),
+			Config: &config.Config{},
+			UseCase: &usecase.UseCase{
+				UserRepo: &fakeUserRepo{users: map[string]entity.User{
+					"u1": {ID: "u1", Status: "active", AvatarId: mediaID},
+				}},
+				MediaRepo: &fakeMediaRepo{media: map[string]entity.Media{
+					mediaID: {ID: mediaID, OwnerID: "u1", Kind: "video", ContentType: "video/mp4", Size: 9, Status: "ready",
+						StorageKey: "originals/video", VariantsStatus: tt.status, UpdatedAt: time.Now().Format(time.RFC3339)},
+				}},
+				Storage: store,
+			},
+		}
+
+		signed, err := url.Parse(usecase.MediaURL(h.mediaURLSecret(), mediaID, "", time.Now().Add(time.Hour)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, recorder := newTestContext("GET", signed.String(), "", tt.principal, gin.Param{Key: "id", Value: mediaID})
+		h.ServeMedia(ctx)
+
+		if recorder.Code != tt.want {
+			t.Errorf("%s video for %s = %d, want %d", tt.status, tt.principal.UserID, recorder.Code, tt.want)
+		}
+		if tt.want == 404 && bytes.Contains(recorder.Body.Bytes(), []byte("raw video")) {
+			t.Errorf("%s video for %s served its original", tt.status, tt.principal.UserID)
+		}
+	}
+}
+
+func TestServeMediaSignedVariantOnly(t *testing.T) {
+	const mediaID = "2b8e4d16-9c3a-4f7e-b5d1-6a0c8e2f4b93"
+
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, data := range map[string]string{"originals/photo": "original", "variants/thumbnail": "thumbnail", "variants/large": "large"} {
+		if err = store.Put(context.Background(), key, bytes.NewReader([]byte(data)), int64(len(data)), "image/webp"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{
+		Logger: logger.New("error"),
+		Config: &config.Config{},
+		UseCase: &usecase.UseCase{
+			UserRepo: &fakeUserRepo{users: map[string]entity.User{
+				"u1": {ID: "u1", Status: "active", AvatarId: mediaID},
+			}},
+			MediaRepo: &fakeMediaRepo{media: map[string]entity.Media{
+				mediaID: {ID: mediaID, OwnerID: "u1", Kind: "photo", ContentType: "image/jpeg", Size: 8, Status: "ready",
+					StorageKey: "originals/photo", VariantsStatus: "ready", UpdatedAt: time.Now().Format(time.RFC3339),
+					Variants: map[string]entity.MediaVariant{
+						"thumbnail": {FilePath: "variants/thumbnail", ContentType: "image/webp", Size: 9},
+						"large":     {FilePath: "variants/large", ContentType: "image/webp", Size: 5},
+					}},
+			}},
+			Storage: store,
+		},
+	}
+
+	signed, err := url.Parse(usecase.MediaURL(h.mediaURLSecret(), mediaID, "thumbnail", time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		variant string
+		want    int
+	}{
+		{"signed variant", "thumbnail", 200},
+		{"other variant", "large", 403},
+		{"original", "", 403},
+	}
+
+	for _, tt := range tests {
+		query := signed.Query()
+		query.Del("variant")
+		if tt.variant != "" {
+			query.Set("variant", tt.variant)
+		}
+
+		ctx, recorder := newTestContext("GET", signed.Path+"?"+query.Encode(), "", entity.Principal{UserID: "u2", Role: "user"},
+			gin.Param{Key: "id", Value: mediaID})
+		h.ServeMedia(ctx)
+
+		if recorder.Code != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, recorder.Code, tt.want)
+		}
+	}
+}
+
+func TestAttachmentFilePathNotSent(t *testing.T) {
+	attachment := entity.Attachment{MediaId: "m1", FilePath: "originals/m1", Variants: map[string]entity.MediaVariant{
+		"large": {FilePath: "variants/m1/large.webp"},
+	}}
+
+	data, err := json.Marshal(attachment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("originals/m1")) || bytes.Contains(data, []byte("large.webp")) {
+		t.Errorf("attachment json has file paths: %s", data)
+	}
+
+	// the variants column keeps them
+	stored, err := entity.MarshalMediaVariants(attachment.Variants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants, err := entity.UnmarshalMediaVariants(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variants["large"].FilePath != "variants/m1/large.webp" {
+		t.Errorf("stored variants = %s, lost the file path", stored)
+	}
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/jackc/pgx/v4"
+)
+
+// moderatedStatus tells whether a status is set only through the moderation endpoints.
+func moderatedStatus(status string) bool {
+	return status == "suspended" || status == "blocked"
+}
+
+// moderatedTweetStatus tells whether a tweet status is set only by resolving a report.
+func moderatedTweetStatus(status string) bool {
+	return status == "hidden" || status == "removed"
+}
+
+// tweetVisible tells whether principal can see a tweet, its owner has to be loaded. Published tweets of suspended
+// and banned users stay visible to moderators only, drafts, scheduled and hidden tweets to their owner as well.
+func tweetVisible(principal entity.Principal, tweet entity.Tweet) bool {
+	private := tweet.Status == "draft" || tweet.Status == "scheduled" || tweet.Status == "hidden"
+
+	visible := tweet.Status == "published" && !moderatedStatus(tweet.Owner.Status) ||
+		private && tweet.Owner.ID == principal.UserID
+
+	return visible || principal.IsAdmin()
+}
+
+// SuspendUser godoc
+// @Router /admin/users/{id}/suspend [post]
+// @Summary Suspend a user
+// @Description Suspends a user until suspended_until, the user's sessions are revoked and tweets hidden until the suspension is over. A suspension can be extended by suspending again.
+// @Security BearerAuth
+// @Tags moderation
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param body body entity.ModerationRequest true "Reason and end of the suspension"
+// @Success 200 {object} entity.UserModeration
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) SuspendUser(ctx *gin.Context) {
+	body, ok := h.moderationRequest(ctx, true)
+	if !ok {
+		return
+	}
+
+	until, err := time.Parse(time.RFC3339, body.SuspendedUntil)
+	if err != nil || !until.After(time.Now()) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "suspended_until must be an RFC3339 timestamp in the future", 400)
+		return
+	}
+
+	moderation, ok := h.moderateUser(ctx, ctx.Param("id"), entity.UserModeration{
+		Action:         usecase.ModerationSuspend,
+		Reason:         body.Reason,
+		SuspendedUntil: until.UTC().Format(time.RFC3339),
+	}, []string{"active", "suspended"}, "suspended")
+	if !ok {
+		return
+	}
+
+	ctx.JSON(200, moderation)
+}
+
+// BanUser godoc
+// @Router /admin/users/{id}/ban [post]
+// @Summary Ban a user
+// @Description Bans a user until restored, the user's sessions are revoked and tweets hidden
+// @Security BearerAuth
+// @Tags moderation
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param body body entity.ModerationRequest true "Reason"
+// @Success 200 {object} entity.UserModeration
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) BanUser(ctx *gin.Context) {
+	body, ok := h.moderationRequest(ctx, true)
+	if !ok {
+		return
+	}
+
+	moderation, ok := h.moderateUser(ctx, ctx.Param("id"), entity.UserModeration{
+		Action: usecase.ModerationBan,
+		Reason: body.Reason,
+	}, []string{"active", "suspended"}, "blocked")
+	if !ok {
+		return
+	}
+
+	ctx.JSON(200, moderation)
+}
+
+// RestoreUser godoc
+// @Router /admin/users/{id}/restore [post]
+// @Summary Restore a user
+// @Description Lifts the suspension or ban of a user, the user can sign in again and the tweets are listed again
+// @Security BearerAuth
+// @Tags moderation
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param body body entity.ModerationRequest true "Reason"
+// @Success 200 {object} entity.UserModeration
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) RestoreUser(ctx *gin.Context) {
+	body, ok := h.moderationRequest(ctx, false)
+	if !ok {
+		return
+	}
+
+	moderation, ok := h.moderateUser(ctx, ctx.Param("id"), entity.UserModeration{
+		Action: usecase.ModerationRestore,
+		Reason: body.Reason,
+	}, []string{"suspended", "blocked"}, "active")
+	if !ok {
+		return
+	}
+
+	ctx.JSON(200, moderation)
+}
+
+// GetUserModeration godoc
+// @Router /admin/users/{id}/moderation [get]
+// @Summary Get the moderation history of a user
+// @Description Get the suspensions, bans and restores of a user, newest first
+// @Security BearerAuth
+// @Tags moderation
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Success 200 {object} entity.UserModerationList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetUserModeration(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return
+	}
+
+	req.Filters = append(req.Filters, entity.Filter{
+		Column: "user_id",
+		Type:   "eq",
+		Value:  user.ID,
+	})
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "created_at",
+		Order:  "desc",
+	})
+
+	history, err := h.UseCase.UserModerationRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting moderation history") {
+		return
+	}
+
+	ctx.JSON(200, history)
+}
+
+func (h *Handler) moderationRequest(ctx *gin.Context, reasonRequired bool) (entity.ModerationRequest, bool) {
+	var body entity.ModerationRequest
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return body, false
+	}
+
+	body.Reason = strings.TrimSpace(body.Reason)
+	if reasonRequired && body.Reason == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Reason is required", 400)
+		return body, false
+	}
+
+	return body, true
+}
+
+// moderateUser moves the user from one of the statuses in from to status, records the action in the moderation
+// history and revokes the sessions unless the user is restored, in one transaction.
+// It writes the error response itself and reports whether the user was moderated.
+func (h *Handler) moderateUser(ctx *gin.Context, userID string, moderation entity.UserModeration, from []string, status string) (entity.UserModeration, bool) {
+	change, user, ok := h.userModerationChange(ctx, userID, moderation, from, status)
+	if !ok {
+		return moderation, false
+	}
+
+	// the status read above must still hold, a concurrent moderation makes this one fail
+	moderation, revoked, err := h.UseCase.UserModerationRepo.Moderate(ctx, change)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.ReturnError(ctx, config.ErrorConflict, "User was changed meanwhile, try again", http.StatusConflict)
+		return moderation, false
+	}
+	if h.HandleDbError(ctx, err, "Error moderating user") {
+		return moderation, false
+	}
+
+	h.UseCase.SessionRepo.Invalidate(ctx, revoked...)
+
+	h.auditModeration(ctx, user, change)
+
+	return moderation, true
+}
+
+// userModerationChange checks that the caller may move the user from one of the statuses in from to status.
+// It writes the error response itself.
+func (h *Handler) userModerationChange(ctx *gin.Context, userID string, moderation entity.UserModeration, from []string, status string) (entity.UserModerationChange, entity.User, bool) {
+	principal := h.principal(ctx)
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: userID})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return entity.UserModerationChange{}, user, false
+	}
+
+	h.auditTarget(ctx, "user", user.ID)
+
+	if user.ID == principal.UserID {
+		h.ReturnError(ctx, config.ErrorBadRequest, "You can't moderate your own account", 400)
+		return entity.UserModerationChange{}, user, false
+	}
+
+	// admins are moderated by superadmins, superadmins by nobody
+	if user.UserRole == "superadmin" {
+		h.ReturnError(ctx, config.ErrorForbidden, "Superadmins can't be moderated", http.StatusForbidden)
+		return entity.UserModerationChange{}, user, false
+	}
+	if user.UserRole != "user" && !h.authorize(ctx, usecase.OpUserModerateAdmin, user.ID) {
+		return entity.UserModerationChange{}, user, false
+	}
+
+	if !slices.Contains(from, user.Status) {
+		h.ReturnError(ctx, config.ErrorConflict, "Can't "+moderation.Action+" a user who is "+user.Status, http.StatusConflict)
+		return entity.UserModerationChange{}, user, false
+	}
+
+	moderation.UserID = user.ID
+	moderation.ModeratorID = principal.UserID
+
+	return entity.UserModerationChange{
+		Moderation: moderation,
+		From:       user.Status,
+		Status:     status,
+	}, user, true
+}
+
+func (h *Handler) auditModeration(ctx *gin.Context, user entity.User, change entity.UserModerationChange) {
+	h.auditReason(ctx, change.Moderation.Reason)
+	h.auditChange(ctx, user.ID,
+		map[string]interface{}{"status": user.Status, "suspended_until": nullable(user.SuspendedUntil)},
+		map[string]interface{}{"status": change.Status, "suspended_until": nullable(change.Moderation.SuspendedUntil)},
+	)
+}
+
+// nullable maps an empty string to nil, so it compares equal to a column set to NULL.
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
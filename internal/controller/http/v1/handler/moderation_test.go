@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+func newModerationHandler() (*Handler, *fakeUserRepo, *fakeUserModerationRepo, *fakeSessionRepo) {
+	users := &fakeUserRepo{users: map[string]entity.User{
+		"admin": {ID: "admin", UserRole: "admin", Status: "active"},
+		"u1":    {ID: "u1", UserRole: "user", Status: "active"},
+		"u2":    {ID: "u2", UserRole: "user", Status: "blocked"},
+		"root":  {ID: "root", UserRole: "superadmin", Status: "active"},
+	}}
+	moderations := &fakeUserModerationRepo{users: users, revoke: []string{"s1", "s2"}}
+	sessions := &fakeSessionRepo{}
+
+	return &Handler{
+		Logger: logger.New("error"),
+		Config: &config.Config{},
+		UseCase: &usecase.UseCase{
+			UserRepo:           users,
+			UserModerationRepo: moderations,
+			SessionRepo:        sessions,
+		},
+	}, users, moderations, sessions
+}
+
+var moderator = entity.Principal{UserID: "admin", Role: "admin", SessionID: "s0"}
+
+func TestSuspendUserStoresUTC(t *testing.T) {
+	h, users, moderations, sessions := newModerationHandler()
+
+	ctx, recorder := newTestContext("POST", "/v1/admin/users/u1/suspend",
+		`{"reason": "spam", "suspended_until": "2999-01-01T05:00:00+05:00"}`, moderator, gin.Param{Key: "id", Value: "u1"})
+
+	h.SuspendUser(ctx)
+
+	if recorder.Code != 200 {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if len(moderations.changes) != 1 {
+		t.Fatalf("changes = %v, want one", moderations.changes)
+	}
+
+	change := moderations.changes[0]
+	if change.From != "active" || change.Status != "suspended" || change.Moderation.ModeratorID != "admin" {
+		t.Errorf("change = %+v", change)
+	}
+	if change.Moderation.SuspendedUntil != "2999-01-01T00:00:00Z" {
+		t.Errorf("suspended_until = %s, want 2999-01-01T00:00:00Z", change.Moderation.SuspendedUntil)
+	}
+	if users.users["u1"].Status != "suspended" {
+		t.Errorf("status = %s, want suspended", users.users["u1"].Status)
+	}
+	if len(sessions.invalidated) != 2 {
+		t.Errorf("invalidated sessions = %v, want the revoked ones", sessions.invalidated)
+	}
+}
+
+func TestModerateUserRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		id     string
+		body   string
+		handle func(h *Handler, ctx *gin.Context)
+		want   int
+	}{
+		{
+			name:   "suspension in the past",
+			path:   "/v1/admin/users/u1/suspend",
+			id:     "u1",
+			body:   `{"reason": "spam", "suspended_until": "2000-01-01T00:00:00Z"}`,
+			handle: (*Handler).SuspendUser,
+			want:   400,
+		},
+		{
+			name:   "own account",
+			path:   "/v1/admin/users/admin/ban",
+			id:     "admin",
+			body:   `{"reason": "spam"}`,
+			handle: (*Handler).BanUser,
+			want:   400,
+		},
+		{
+			name:   "superadmin",
+			path:   "/v1/admin/users/root/ban",
+			id:     "root",
+			body:   `{"reason": "spam"}`,
+			handle: (*Handler).BanUser,
+			want:   403,
+		},
+		{
+			name:   "banned user",
+			path:   "/v1/admin/users/u2/ban",
+			id:     "u2",
+			body:   `{"reason": "spam"}`,
+			handle: (*Handler).BanUser,
+			want:   409,
+		},
+		{
+			name:   "active user restored",
+			path:   "/v1/admin/users/u1/restore",
+			id:     "u1",
+			body:   `{}`,
+			handle: (*Handler).RestoreUser,
+			want:   409,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _, moderations, _ := newModerationHandler()
+
+			ctx, recorder := newTestContext("POST", tt.path, tt.body, moderator, gin.Param{Key: "id", Value: tt.id})
+
+			tt.handle(h, ctx)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if len(moderations.changes) != 0 {
+				t.Errorf("user was moderated: %v", moderations.changes)
+			}
+		})
+	}
+}
+
+// A moderation that lost the race against another one fails instead of overwriting it.
+func TestModerateUserConflict(t *testing.T) {
+	h, users, _, sessions := newModerationHandler()
+
+	ctx, recorder := newTestContext("POST", "/v1/admin/users/u1/ban", `{"reason": "spam"}`, moderator, gin.Param{Key: "id", Value: "u1"})
+
+	// the user is read as active, then someone else bans it before the transaction runs
+	h.UseCase.UserRepo = &racingUserRepo{fakeUserRepo: users, status: "blocked"}
+
+	h.BanUser(ctx)
+
+	if recorder.Code != 409 {
+		t.Fatalf("status = %d, want 409: %s", recorder.Code, recorder.Body.String())
+	}
+	if len(sessions.invalidated) != 0 {
+		t.Errorf("invalidated sessions = %v, want none", sessions.invalidated)
+	}
+}
+
+// racingUserRepo changes the status of a user right after it was read.
+type racingUserRepo struct {
+	*fakeUserRepo
+	status string
+}
+
+func (r *racingUserRepo) GetSingle(ctx context.Context, req entity.UserSingleRequest) (entity.User, error) {
+	user, err := r.fakeUserRepo.GetSingle(ctx, req)
+	if err == nil {
+		changed := user
+		changed.Status = r.status
+		r.users[user.ID] = changed
+	}
+
+	return user, err
+}
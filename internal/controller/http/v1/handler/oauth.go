@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/hash"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/oidc"
+	"github.com/jackc/pgx/v4"
+)
+
+const oauthStateTTL = 10 * 60 // seconds
+
+var usernameCleaner = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// OAuthStart godoc
+// @Router /auth/oauth/{provider}/start [get]
+// @Summary Start OAuth login
+// @Description Redirects to the identity provider (authorization code flow with PKCE)
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Param platform query string false "platform"
+// @Success 302
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) OAuthStart(ctx *gin.Context) {
+	provider, ok := h.OAuth[ctx.Param("provider")]
+	if !ok {
+		h.ReturnError(ctx, config.ErrorNotFound, "Unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+		return
+	}
+
+	st := entity.OAuthState{
+		Provider: provider.Name(),
+		Platform: ctx.DefaultQuery("platform", "web"),
+	}
+
+	st.Verifier, err = oidc.RandomString(32)
+	if err == nil {
+		st.Nonce, err = oidc.RandomString(16)
+	}
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+		return
+	}
+
+	body, _ := json.Marshal(st)
+
+	err = h.Redis.Set(ctx, fmt.Sprintf("oauth-state-%s", state), string(body), oauthStateTTL)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error saving oauth state", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, st.Nonce, st.Verifier)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback godoc
+// @Router /auth/oauth/{provider}/callback [get]
+// @Summary OAuth login callback
+// @Description Exchanges the authorization code, links or creates the user and creates a session
+// @Tags auth
+// @Produce  json
+// @Param provider path string true "Provider name"
+// @Param code query string true "code"
+// @Param state query string true "state"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) OAuthCallback(ctx *gin.Context) {
+	provider, ok := h.OAuth[ctx.Param("provider")]
+	if !ok {
+		h.ReturnError(ctx, config.ErrorNotFound, "Unknown identity provider", http.StatusNotFound)
+		return
+	}
+
+	if e := ctx.Query("error"); e != "" {
+		h.ReturnError(ctx, config.ErrorUnauthorized, "Identity provider returned an error: "+e, http.StatusBadRequest)
+		return
+	}
+
+	key := fmt.Sprintf("oauth-state-%s", ctx.Query("state"))
+
+	raw, err := h.Redis.Get(ctx, key)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid or expired state", http.StatusBadRequest)
+		return
+	}
+
+	// state is single use
+	err = h.Redis.Del(ctx, key)
+	if err != nil {
+		h.Logger.Error(err, "Error deleting oauth state")
+	}
+
+	var st entity.OAuthState
+	if json.Unmarshal([]byte(raw), &st) != nil || st.Provider != provider.Name() {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid or expired state", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := provider.Exchange(ctx, ctx.Query("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		h.Logger.Error(err, "oauth exchange")
+		h.ReturnError(ctx, config.ErrorInvalidToken, "Could not verify identity provider response", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.oauthUser(ctx, provider.Name(), claims)
+	if !ok {
+		return
+	}
+
+	if !h.platformAllowed(ctx, user, st.Platform) {
+		return
+	}
+
+	h.startSession(ctx, user, st.Platform)
+}
+
+// oauthUser resolves the user of an external identity: an already linked user,
+// an existing user with the same verified email (linking it), or a newly created one.
+func (h *Handler) oauthUser(ctx *gin.Context, provider string, claims oidc.Claims) (entity.User, bool) {
+	identity, err := h.UseCase.IdentityRepo.GetSingle(ctx, entity.IdentitySingleRequest{
+		Provider: provider,
+		Subject:  claims.Subject,
+	})
+	if err == nil {
+		user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: identity.UserID})
+		if h.HandleDbError(ctx, err, "Error getting user") {
+			return entity.User{}, false
+		}
+
+		return user, true
+	}
+	if err != pgx.ErrNoRows {
+		h.HandleDbError(ctx, err, "Error getting identity")
+		return entity.User{}, false
+	}
+
+	// linking by an unverified email would let anyone take over an account
+	if claims.Email == "" || !claims.EmailVerified {
+		h.ReturnError(ctx, config.ErrorInvalidEmail, "Identity provider did not return a verified email", http.StatusBadRequest)
+		return entity.User{}, false
+	}
+
+	email := strings.ToLower(claims.Email)
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{Email: email})
+	switch {
+	case err == pgx.ErrNoRows:
+		user, err = h.createOAuthUser(ctx, email, claims.Name)
+		if h.HandleDbError(ctx, err, "Error creating user") {
+			return entity.User{}, false
+		}
+	case err != nil:
+		h.HandleDbError(ctx, err, "Error getting user")
+		return entity.User{}, false
+	case user.Status == "inverify":
+		// provider already verified the address
+		if !h.claimUnverifiedUser(ctx, &user) {
+			return entity.User{}, false
+		}
+	}
+
+	_, err = h.UseCase.IdentityRepo.Create(ctx, entity.Identity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    email,
+	})
+	if h.HandleDbError(ctx, err, "Error linking identity") {
+		return entity.User{}, false
+	}
+
+	return user, true
+}
+
+func (h *Handler) createOAuthUser(ctx *gin.Context, email, name string) (entity.User, error) {
+	local := usernameCleaner.ReplaceAllString(strings.ToLower(strings.SplitN(email, "@", 2)[0]), "_")
+	if len(local) > 40 {
+		local = local[:40]
+	}
+
+	suffix, err := oidc.RandomString(6)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if name == "" {
+		name = local
+	}
+	if runes := []rune(name); len(runes) > 50 {
+		name = string(runes[:50])
+	}
+
+	password, err := unusablePassword()
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	return h.UseCase.UserRepo.Create(ctx, entity.User{
+		FullName: name,
+		UserType: "user",
+		UserRole: "user",
+		Username: local + "_" + usernameCleaner.ReplaceAllString(strings.ToLower(suffix), ""),
+		Email:    email,
+		Status:   "active",
+		Password: password,
+		Gender:   "unspecified",
+	})
+}
+
+// claimUnverifiedUser activates an unverified account for the owner of its verified email. Anyone could have
+// registered the address before, so the password, sessions and pending codes they set up are dropped first.
+// It writes the error response itself.
+func (h *Handler) claimUnverifiedUser(ctx *gin.Context, user *entity.User) bool {
+	if !h.revokeSessions(ctx, user.ID) {
+		return false
+	}
+
+	// a leftover code is harmless once the account is active and has no sessions, a failure is only logged
+	for _, key := range []string{verificationKey(user.Email), emailChangeKey(user.ID)} {
+		if err := h.Redis.Del(ctx, key); err != nil {
+			h.Logger.Error(err, "Error removing pending code")
+		}
+	}
+
+	password, err := unusablePassword()
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Oops, something went wrong!!!", http.StatusInternalServerError)
+		return false
+	}
+
+	before := *user
+	user.Status = "active"
+	user.Password = password
+
+	_, err = h.UseCase.UserRepo.Update(ctx, *user)
+	if h.HandleDbError(ctx, err, "Error activating user") {
+		return false
+	}
+
+	h.auditChange(ctx, user.ID, before, *user)
+
+	return true
+}
+
+// unusablePassword is the hash of a random secret nobody knows, password login stays impossible until the
+// user sets a password.
+func unusablePassword() (string, error) {
+	secret, err := oidc.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	return hash.HashPassword(secret)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/hash"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/oidc"
+)
+
+func TestOAuthUserClaimsUnverifiedAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	password, err := hash.HashPassword("squatter-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := &fakeUserRepo{users: map[string]entity.User{
+		"u1": {ID: "u1", Email: "owner@example.com", Status: "inverify", Password: password},
+	}}
+	sessions := &fakeSessionRepo{sessions: []entity.Session{
+		{ID: "s1", UserID: "u1", IsActive: true},
+		{ID: "s2", UserID: "u2", IsActive: true},
+	}}
+	identities := &fakeIdentityRepo{}
+	redis := &fakeRedis{values: map[string]string{
+		verificationKey("owner@example.com"): "123456",
+		emailChangeKey("u1"):                 "654321:squatter@example.com",
+	}}
+
+	h := &Handler{
+		Logger: logger.New("error"),
+		Config: &config.Config{},
+		UseCase: &usecase.UseCase{
+			UserRepo:     users,
+			SessionRepo:  sessions,
+			IdentityRepo: identities,
+		},
+		Redis: redis,
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/auth/oauth/google/callback", nil)
+
+	user, ok := h.oauthUser(ctx, "google", oidc.Claims{
+		Subject:       "sub-1",
+		Email:         "Owner@example.com",
+		EmailVerified: true,
+	})
+	if !ok {
+		t.Fatal("oauthUser failed")
+	}
+
+	stored := users.users["u1"]
+	if user.ID != "u1" || stored.Status != "active" {
+		t.Errorf("user = %s %s, want u1 active", user.ID, stored.Status)
+	}
+	if hash.CheckPasswordHash("squatter-password", stored.Password) {
+		t.Error("password set before the email was verified still works")
+	}
+	if sessions.sessions[0].IsActive {
+		t.Error("session of the unverified account is still active")
+	}
+	if !sessions.sessions[1].IsActive {
+		t.Error("session of another user was revoked")
+	}
+	if len(redis.values) != 0 {
+		t.Errorf("pending codes = %v, want none", redis.values)
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != "u1" {
+		t.Errorf("identities = %v, want one linked to u1", identities.identities)
+	}
+}
+
+func TestOAuthUserRequiresVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	users := &fakeUserRepo{users: map[string]entity.User{
+		"u1": {ID: "u1", Email: "owner@example.com", Status: "inverify"},
+	}}
+	identities := &fakeIdentityRepo{}
+
+	h := &Handler{
+		Logger: logger.New("error"),
+		Config: &config.Config{},
+		UseCase: &usecase.UseCase{
+			UserRepo:     users,
+			IdentityRepo: identities,
+		},
+	}
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest("GET", "/auth/oauth/google/callback", nil)
+
+	_, ok := h.oauthUser(ctx, "google", oidc.Claims{
+		Subject: "sub-1",
+		Email:   "owner@example.com",
+	})
+	if ok || recorder.Code != 400 {
+		t.Errorf("oauthUser = %v %d, want rejected with 400", ok, recorder.Code)
+	}
+	if users.users["u1"].Status != "inverify" || len(identities.identities) != 0 {
+		t.Error("account was activated or linked by an unverified email")
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/google/uuid"
+)
+
+// VotePoll godoc
+// @Router /tweet/{id}/vote [post]
+// @Summary Vote on the poll of a tweet
+// @Description Votes for an option of the poll of a published tweet while the poll is open, a user votes once per poll. The results of a poll are shown once the caller has voted or the poll has ended.
+// @Security BearerAuth
+// @Tags tweet
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tweet ID"
+// @Param vote body entity.PollVote true "Option to vote for"
+// @Success 200 {object} entity.Poll
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) VotePoll(ctx *gin.Context) {
+	var (
+		body entity.PollVote
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil || uuid.Validate(body.OptionId) != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	tweet, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting tweet") {
+		return
+	}
+
+	tweet.Owner, err = h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: tweet.Owner.ID})
+	if h.HandleDbError(ctx, err, "Error getting tweet owner") {
+		return
+	}
+
+	// only polls others can see are voted on
+	if tweet.Status != "published" || !tweetVisible(h.principal(ctx), tweet) {
+		h.ReturnError(ctx, config.ErrorNotFound, "Tweet not found", http.StatusNotFound)
+		return
+	}
+
+	body.TweetId = tweet.Id
+	body.UserId = h.principal(ctx).UserID
+
+	open, err := h.UseCase.PollRepo.Vote(ctx, body)
+	if isUniqueViolation(err) {
+		h.ReturnError(ctx, config.ErrorConflict, "You already voted on this poll", http.StatusConflict)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error voting on poll") {
+		return
+	}
+
+	if !open {
+		h.ReturnError(ctx, config.ErrorConflict, "Poll has ended", http.StatusConflict)
+		return
+	}
+
+	if !h.attachPoll(ctx, &tweet) {
+		return
+	}
+
+	ctx.JSON(200, tweet.Poll)
+}
+
+// attachPolls sets the polls of tweets as the caller sees them, a poll given in a request body never stays. It
+// writes the error response itself.
+func (h *Handler) attachPolls(ctx *gin.Context, tweets []entity.Tweet) bool {
+	ids := make([]string, 0, len(tweets))
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.Id)
+	}
+
+	polls, err := h.UseCase.PollRepo.GetByTweets(ctx, ids, h.principal(ctx).UserID)
+	if h.HandleDbError(ctx, err, "Error getting polls") {
+		return false
+	}
+
+	for i := range tweets {
+		tweets[i].Poll = nil
+
+		poll, ok := polls[tweets[i].Id]
+		if !ok {
+			continue
+		}
+
+		usecase.HidePollResults(&poll)
+		tweets[i].Poll = &poll
+	}
+
+	return true
+}
+
+func (h *Handler) attachPoll(ctx *gin.Context, tweet *entity.Tweet) bool {
+	tweets := []entity.Tweet{*tweet}
+	if !h.attachPolls(ctx, tweets) {
+		return false
+	}
+
+	*tweet = tweets[0]
+
+	return true
+}
@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// GetRbacPolicies godoc
+// @Router /admin/rbac/policies [get]
+// @Summary Get RBAC policies
+// @Description Get RBAC policies currently enforced by this replica
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Param subject query string false "subject"
+// @Success 200 {object} entity.RbacPolicyList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetRbacPolicies(ctx *gin.Context) {
+	var (
+		response = entity.RbacPolicyList{Items: []entity.RbacPolicy{}}
+		subject  = ctx.Query("subject")
+	)
+
+	for _, rule := range h.Enforcer.Policies() {
+		if len(rule) < 3 || (subject != "" && rule[0] != subject) {
+			continue
+		}
+
+		response.Items = append(response.Items, entity.RbacPolicy{
+			Subject: rule[0],
+			Object:  rule[1],
+			Action:  rule[2],
+		})
+	}
+	response.Count = len(response.Items)
+
+	ctx.JSON(200, response)
+}
+
+// AddRbacPolicy godoc
+// @Router /admin/rbac/policies [post]
+// @Summary Add an RBAC policy
+// @Description Add an RBAC policy, object is a route pattern (keyMatch) and action a method regex, e.g. GET|POST
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Param body body entity.RbacPolicy true "Policy"
+// @Success 201 {object} entity.RbacPolicy
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) AddRbacPolicy(ctx *gin.Context) {
+	var (
+		body entity.RbacPolicy
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	if !h.validRbacPolicy(ctx, body) {
+		return
+	}
+
+	err = h.UseCase.CasbinRuleRepo.Create(ctx, "p", []string{body.Subject, body.Object, body.Action})
+	if h.HandleDbError(ctx, err, "Error creating policy") {
+		return
+	}
+
+	h.auditChange(ctx, rbacRuleID("p", body.Subject, body.Object, body.Action), nil, body)
+
+	if !h.rbacChanged(ctx) {
+		return
+	}
+
+	ctx.JSON(201, body)
+}
+
+// RemoveRbacPolicy godoc
+// @Router /admin/rbac/policies [delete]
+// @Summary Remove an RBAC policy
+// @Description Remove an RBAC policy, the rule has to match exactly
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Param body body entity.RbacPolicy true "Policy"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) RemoveRbacPolicy(ctx *gin.Context) {
+	var (
+		body entity.RbacPolicy
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	rows, err := h.UseCase.CasbinRuleRepo.Delete(ctx, "p", []string{body.Subject, body.Object, body.Action})
+	if h.HandleDbError(ctx, err, "Error removing policy") {
+		return
+	}
+
+	if rows.RowsEffected == 0 {
+		h.ReturnError(ctx, config.ErrorNotFound, "Policy not found", http.StatusNotFound)
+		return
+	}
+
+	h.auditChange(ctx, rbacRuleID("p", body.Subject, body.Object, body.Action), body, nil)
+
+	if !h.rbacChanged(ctx) {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Policy removed successfully",
+	})
+}
+
+// GetRbacRoles godoc
+// @Router /admin/rbac/roles [get]
+// @Summary Get RBAC role assignments
+// @Description Get RBAC role assignments (subject inherits permissions of role)
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.RbacRoleList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetRbacRoles(ctx *gin.Context) {
+	response := entity.RbacRoleList{Items: []entity.RbacRole{}}
+
+	for _, rule := range h.Enforcer.Roles() {
+		if len(rule) < 2 {
+			continue
+		}
+
+		response.Items = append(response.Items, entity.RbacRole{
+			Subject: rule[0],
+			Role:    rule[1],
+		})
+	}
+	response.Count = len(response.Items)
+
+	ctx.JSON(200, response)
+}
+
+// AddRbacRole godoc
+// @Router /admin/rbac/roles [post]
+// @Summary Assign an RBAC role
+// @Description Assign a role to a subject, the subject inherits all permissions of the role
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Param body body entity.RbacRole true "Role assignment"
+// @Success 201 {object} entity.RbacRole
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) AddRbacRole(ctx *gin.Context) {
+	var (
+		body entity.RbacRole
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	if !validRbacValue(body.Subject) || !validRbacValue(body.Role) || body.Subject == body.Role {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Subject and role are required and must differ", 400)
+		return
+	}
+
+	err = h.UseCase.CasbinRuleRepo.Create(ctx, "g", []string{body.Subject, body.Role})
+	if h.HandleDbError(ctx, err, "Error assigning role") {
+		return
+	}
+
+	h.auditChange(ctx, rbacRuleID("g", body.Subject, body.Role), nil, body)
+
+	if !h.rbacChanged(ctx) {
+		return
+	}
+
+	ctx.JSON(201, body)
+}
+
+// RemoveRbacRole godoc
+// @Router /admin/rbac/roles [delete]
+// @Summary Remove an RBAC role assignment
+// @Description Remove an RBAC role assignment
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Param body body entity.RbacRole true "Role assignment"
+// @Success 200 {object} entity.SuccessResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) RemoveRbacRole(ctx *gin.Context) {
+	var (
+		body entity.RbacRole
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	rows, err := h.UseCase.CasbinRuleRepo.Delete(ctx, "g", []string{body.Subject, body.Role})
+	if h.HandleDbError(ctx, err, "Error removing role assignment") {
+		return
+	}
+
+	if rows.RowsEffected == 0 {
+		h.ReturnError(ctx, config.ErrorNotFound, "Role assignment not found", http.StatusNotFound)
+		return
+	}
+
+	h.auditChange(ctx, rbacRuleID("g", body.Subject, body.Role), body, nil)
+
+	if !h.rbacChanged(ctx) {
+		return
+	}
+
+	ctx.JSON(200, entity.SuccessResponse{
+		Message: "Role assignment removed successfully",
+	})
+}
+
+// CheckRbac godoc
+// @Router /admin/rbac/check [post]
+// @Summary Dry run an authorization check
+// @Description Tells whether a role (or the role of a user) would be allowed to call a route, path is the route pattern from the router, e.g. /v1/user/:id
+// @Security BearerAuth
+// @Tags rbac
+// @Accept  json
+// @Produce  json
+// @Param body body entity.RbacCheckRequest true "Check"
+// @Success 200 {object} entity.RbacCheckResponse
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) CheckRbac(ctx *gin.Context) {
+	var (
+		body entity.RbacCheckRequest
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	if body.Path == "" || body.Method == "" || (body.Role == "" && body.UserID == "") {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Path, method and either role or user_id are required", 400)
+		return
+	}
+
+	if body.Role == "" {
+		user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: body.UserID})
+		if h.HandleDbError(ctx, err, "Error getting user") {
+			return
+		}
+
+		body.Role = user.UserRole
+	}
+
+	method := strings.ToUpper(body.Method)
+
+	allowed, err := h.Enforcer.Enforce(body.Role, body.Path, method)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Error enforcing policy: "+err.Error(), 400)
+		return
+	}
+
+	ctx.JSON(200, entity.RbacCheckResponse{
+		Allowed: allowed,
+		Role:    body.Role,
+		Path:    body.Path,
+		Method:  method,
+	})
+}
+
+func (h *Handler) validRbacPolicy(ctx *gin.Context, policy entity.RbacPolicy) bool {
+	if !validRbacValue(policy.Subject) || !validRbacValue(policy.Object) || !validRbacValue(policy.Action) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Subject, object and action are required", 400)
+		return false
+	}
+
+	if !strings.HasPrefix(policy.Object, "/") {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Object must be a path starting with /", 400)
+		return false
+	}
+
+	if _, err := regexp.Compile(policy.Action); err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Action must be a valid regular expression", 400)
+		return false
+	}
+
+	return true
+}
+
+// rbacChanged applies a stored policy change locally and tells the other replicas to reload.
+func (h *Handler) rbacChanged(ctx *gin.Context) bool {
+	err := h.Enforcer.Reload()
+	if err != nil {
+		h.Logger.Error(err, "Error reloading rbac policy")
+		h.ReturnError(ctx, config.ErrorInternalServer, "Policy is saved but could not be applied", http.StatusInternalServerError)
+		return false
+	}
+
+	// other replicas still pick the change up on their periodic reload
+	err = h.Enforcer.Notify()
+	if err != nil {
+		h.Logger.Error(err, "Error notifying replicas about rbac policy change")
+	}
+
+	return true
+}
+
+// validRbacValue rejects empty values and commas, policies are exchanged as csv lines.
+func validRbacValue(value string) bool {
+	return strings.TrimSpace(value) != "" && !strings.Contains(value, ",")
+}
+
+// rbacRuleID identifies a rule in the audit log the way it is written in a policy csv.
+func rbacRuleID(ptype string, values ...string) string {
+	return ptype + ", " + strings.Join(values, ", ")
+}
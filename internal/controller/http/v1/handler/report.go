@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// reportDetailsMaxLength caps the free text of a report.
+const reportDetailsMaxLength = 1000
+
+// ReportTweet godoc
+// @Router /tweet/{id}/report [post]
+// @Summary Report a tweet
+// @Description Reports a tweet to the moderators, a tweet can be reported once until the report is resolved. The reporter is emailed the outcome.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tweet ID"
+// @Param body body entity.ReportRequest true "Category and details"
+// @Success 201 {object} entity.Report
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) ReportTweet(ctx *gin.Context) {
+	body, ok := h.reportRequest(ctx)
+	if !ok {
+		return
+	}
+
+	tweet, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting tweet") {
+		return
+	}
+
+	owner, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: tweet.Owner.ID})
+	if h.HandleDbError(ctx, err, "Error getting tweet owner") {
+		return
+	}
+
+	// only what others can see can be reported
+	if tweet.Status != "published" || moderatedStatus(owner.Status) {
+		h.ReturnError(ctx, config.ErrorNotFound, "Tweet not found", http.StatusNotFound)
+		return
+	}
+
+	if owner.ID == h.principal(ctx).UserID {
+		h.ReturnError(ctx, config.ErrorBadRequest, "You can't report your own tweet", 400)
+		return
+	}
+
+	h.createReport(ctx, "tweet", tweet.Id, body)
+}
+
+// ReportUser godoc
+// @Router /user/{id}/report [post]
+// @Summary Report a user
+// @Description Reports an account to the moderators, an account can be reported once until the report is resolved. The reporter is emailed the outcome.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param body body entity.ReportRequest true "Category and details"
+// @Success 201 {object} entity.Report
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) ReportUser(ctx *gin.Context) {
+	body, ok := h.reportRequest(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return
+	}
+
+	if user.ID == h.principal(ctx).UserID {
+		h.ReturnError(ctx, config.ErrorBadRequest, "You can't report your own account", 400)
+		return
+	}
+
+	if moderatedStatus(user.Status) {
+		h.ReturnError(ctx, config.ErrorConflict, "User is already "+user.Status, http.StatusConflict)
+		return
+	}
+
+	h.createReport(ctx, "user", user.ID, body)
+}
+
+// GetReports godoc
+// @Router /admin/reports [get]
+// @Summary Get the moderation queue
+// @Description Get the reported tweets and users with open reports, the reports of a target are merged. The most reported and most severe come first.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Param target_type query string false "tweet or user"
+// @Param category query string false "category"
+// @Success 200 {object} entity.ReportQueue
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetReports(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+
+	req.Filters = append(req.Filters, entity.Filter{
+		Column: "status",
+		Type:   "eq",
+		Value:  "open",
+	})
+
+	for _, column := range []string{"target_type", "category"} {
+		if value := ctx.Query(column); value != "" {
+			req.Filters = append(req.Filters, entity.Filter{
+				Column: column,
+				Type:   "eq",
+				Value:  value,
+			})
+		}
+	}
+
+	req.OrderBy = append(req.OrderBy,
+		entity.OrderBy{Column: "reports", Order: "desc"},
+		entity.OrderBy{Column: "severity", Order: "desc"},
+		entity.OrderBy{Column: "first_reported_at", Order: "asc"},
+	)
+
+	queue, err := h.UseCase.ReportRepo.GetQueue(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting reports") {
+		return
+	}
+
+	ctx.JSON(200, queue)
+}
+
+// ResolveReports godoc
+// @Router /admin/reports/resolve [post]
+// @Summary Resolve the reports of a target
+// @Description Resolves all open reports of a tweet or user with an action: dismiss, hide_tweet, delete_tweet or suspend_author. Every reporter is emailed the outcome. A hidden or removed tweet is shown again by setting its status back with PUT /tweet.
+// @Security BearerAuth
+// @Tags report
+// @Accept  json
+// @Produce  json
+// @Param body body entity.ReportResolveRequest true "Target and action"
+// @Success 200 {object} entity.ReportResolution
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) ResolveReports(ctx *gin.Context) {
+	var (
+		body      entity.ReportResolveRequest
+		principal = h.principal(ctx)
+	)
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return
+	}
+
+	body.Reason = strings.TrimSpace(body.Reason)
+
+	if body.TargetType != "tweet" && body.TargetType != "user" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "target_type must be tweet or user", 400)
+		return
+	}
+
+	if _, err := uuid.Parse(body.TargetID); err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid target_id", 400)
+		return
+	}
+
+	switch body.Action {
+	case usecase.ReportDismiss, usecase.ReportSuspendAuthor:
+	case usecase.ReportHideTweet, usecase.ReportDeleteTweet:
+		if body.TargetType != "tweet" {
+			h.ReturnError(ctx, config.ErrorBadRequest, body.Action+" applies to reported tweets only", 400)
+			return
+		}
+	default:
+		h.ReturnError(ctx, config.ErrorBadRequest, "action must be dismiss, hide_tweet, delete_tweet or suspend_author", 400)
+		return
+	}
+
+	h.auditTarget(ctx, body.TargetType, body.TargetID)
+	h.auditReason(ctx, body.Reason)
+
+	resolve := entity.ReportResolve{
+		Report: entity.Report{
+			TargetType: body.TargetType,
+			TargetID:   body.TargetID,
+			Action:     body.Action,
+			ResolvedBy: principal.UserID,
+		},
+	}
+
+	var (
+		author entity.User
+		ok     bool
+	)
+
+	switch body.Action {
+	case usecase.ReportHideTweet:
+		resolve.Tweet, ok = h.tweetStatusChange(ctx, body.TargetID, "hidden")
+	case usecase.ReportDeleteTweet:
+		resolve.Tweet, ok = h.tweetStatusChange(ctx, body.TargetID, "removed")
+	case usecase.ReportSuspendAuthor:
+		resolve.Moderation, author, ok = h.suspendAuthor(ctx, body)
+	default:
+		ok = true
+	}
+	if !ok {
+		return
+	}
+
+	// the tweet or user read above must still be as it was, a concurrent change makes the resolution fail
+	reports, revoked, err := h.UseCase.ReportRepo.Resolve(ctx, resolve)
+	if errors.Is(err, pgx.ErrNoRows) {
+		h.ReturnError(ctx, config.ErrorConflict, "Reported "+body.TargetType+" was changed meanwhile, try again", http.StatusConflict)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error resolving reports") {
+		return
+	}
+
+	// another moderator resolved them meanwhile
+	if len(reports) == 0 {
+		h.ReturnError(ctx, config.ErrorNotFound, "No open reports for this "+body.TargetType, http.StatusNotFound)
+		return
+	}
+
+	h.UseCase.SessionRepo.Invalidate(ctx, revoked...)
+
+	if resolve.Tweet != nil {
+		h.auditChange(ctx, resolve.Tweet.TweetID,
+			map[string]interface{}{"status": resolve.Tweet.From},
+			map[string]interface{}{"status": resolve.Tweet.Status},
+		)
+	}
+	if resolve.Moderation != nil {
+		h.auditModeration(ctx, author, *resolve.Moderation)
+	}
+
+	// the outcome is queued even if the client hangs up, a failure doesn't undo the resolution
+	err = h.UseCase.NotifyReporters(context.WithoutCancel(ctx.Request.Context()), reports)
+	if err != nil {
+		h.Logger.Error(err, "Error notifying reporters")
+	}
+
+	ctx.JSON(200, entity.ReportResolution{
+		TargetType: body.TargetType,
+		TargetID:   body.TargetID,
+		Action:     body.Action,
+		Reports:    reports,
+	})
+}
+
+func (h *Handler) reportRequest(ctx *gin.Context) (entity.ReportRequest, bool) {
+	var body entity.ReportRequest
+
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Invalid request body", 400)
+		return body, false
+	}
+
+	if _, ok := usecase.ReportCategories[body.Category]; !ok {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Unknown report category", 400)
+		return body, false
+	}
+
+	body.Details = strings.TrimSpace(body.Details)
+	if len(body.Details) > reportDetailsMaxLength {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Details must be at most "+strconv.Itoa(reportDetailsMaxLength)+" characters", 400)
+		return body, false
+	}
+
+	return body, true
+}
+
+// createReport files the report of the caller, the language of the request is kept to email the outcome in it.
+func (h *Handler) createReport(ctx *gin.Context, targetType, targetID string, body entity.ReportRequest) {
+	report, err := h.UseCase.ReportRepo.Create(ctx, entity.Report{
+		ReporterID: h.principal(ctx).UserID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Category:   body.Category,
+		Severity:   usecase.ReportCategories[body.Category],
+		Details:    body.Details,
+		Locale:     h.UseCase.MailTemplates.Match(ctx.GetHeader("Accept-Language")),
+	})
+	if isUniqueViolation(err) {
+		h.ReturnError(ctx, config.ErrorConflict, "You already reported this "+targetType, http.StatusConflict)
+		return
+	}
+	if h.HandleDbError(ctx, err, "Error creating report") {
+		return
+	}
+
+	h.auditChange(ctx, targetID, nil, map[string]interface{}{"report": report.ID, "category": report.Category})
+
+	ctx.JSON(201, report)
+}
+
+// tweetStatusChange checks that a tweet can be moved to a moderated status, it writes the error response itself.
+func (h *Handler) tweetStatusChange(ctx *gin.Context, tweetID, status string) (*entity.TweetStatusChange, bool) {
+	tweet, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: tweetID})
+	if h.HandleDbError(ctx, err, "Error getting tweet") {
+		return nil, false
+	}
+
+	if tweet.Status == "removed" || tweet.Status == status {
+		h.ReturnError(ctx, config.ErrorConflict, "Tweet is already "+tweet.Status, http.StatusConflict)
+		return nil, false
+	}
+
+	return &entity.TweetStatusChange{
+		TweetID: tweet.Id,
+		From:    tweet.Status,
+		Status:  status,
+	}, true
+}
+
+// suspendAuthor checks that the reported user or the owner of the reported tweet can be suspended, it writes the
+// error response itself.
+func (h *Handler) suspendAuthor(ctx *gin.Context, body entity.ReportResolveRequest) (*entity.UserModerationChange, entity.User, bool) {
+	if body.Reason == "" {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Reason is required", 400)
+		return nil, entity.User{}, false
+	}
+
+	until, err := time.Parse(time.RFC3339, body.SuspendedUntil)
+	if err != nil || !until.After(time.Now()) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "suspended_until must be an RFC3339 timestamp in the future", 400)
+		return nil, entity.User{}, false
+	}
+
+	authorID := body.TargetID
+	if body.TargetType == "tweet" {
+		tweet, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: body.TargetID})
+		if h.HandleDbError(ctx, err, "Error getting tweet") {
+			return nil, entity.User{}, false
+		}
+		authorID = tweet.Owner.ID
+	}
+
+	change, author, ok := h.userModerationChange(ctx, authorID, entity.UserModeration{
+		Action:         usecase.ModerationSuspend,
+		Reason:         body.Reason,
+		SuspendedUntil: until.UTC().Format(time.RFC3339),
+	}, []string{"active", "suspended"}, "suspended")
+	if !ok {
+		return nil, author, false
+	}
+
+	return &change, author, true
+}
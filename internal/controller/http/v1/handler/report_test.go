@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+const (
+	reportedTweetID = "6f1c2b7e-3d4a-4c5b-9e8f-0a1b2c3d4e5f"
+	reportedUserID  = "0e9d8c7b-6a5f-4e3d-8c2b-1a0f9e8d7c6b"
+)
+
+func newReportHandler(reports *fakeReportRepo) (*Handler, *fakeSessionRepo) {
+	users := &fakeUserRepo{users: map[string]entity.User{
+		"admin":        {ID: "admin", UserRole: "admin", Status: "active"},
+		reportedUserID: {ID: reportedUserID, UserRole: "user", Status: "active"},
+	}}
+	tweets := &fakeTweetRepo{tweets: map[string]entity.Tweet{
+		reportedTweetID: {Id: reportedTweetID, Status: "published", Owner: entity.User{ID: reportedUserID}},
+	}}
+	sessions := &fakeSessionRepo{}
+
+	return &Handler{
+		Logger: logger.New("error"),
+		Config: &config.Config{},
+		UseCase: &usecase.UseCase{
+			UserRepo:    users,
+			TweetRepo:   tweets,
+			ReportRepo:  reports,
+			SessionRepo: sessions,
+		},
+	}, sessions
+}
+
+func resolveReports(h *Handler, body string) int {
+	ctx, recorder := newTestContext("POST", "/v1/admin/reports/resolve", body, moderator)
+
+	h.ResolveReports(ctx)
+
+	return recorder.Code
+}
+
+func TestResolveReportsSuspendsAuthorWithClaim(t *testing.T) {
+	reports := &fakeReportRepo{reports: []entity.Report{{ID: "r1", ReporterID: "reporter"}}, revoke: []string{"s1"}}
+	h, sessions := newReportHandler(reports)
+
+	code := resolveReports(h, `{"target_type": "tweet", "target_id": "`+reportedTweetID+`", "action": "suspend_author",
+		"reason": "spam", "suspended_until": "2999-01-01T03:00:00+03:00"}`)
+	if code != 200 {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	if len(reports.resolved) != 1 {
+		t.Fatalf("resolved = %v, want one resolution", reports.resolved)
+	}
+
+	resolve := reports.resolved[0]
+	if resolve.Report.Action != usecase.ReportSuspendAuthor || resolve.Report.ResolvedBy != "admin" || resolve.Tweet != nil {
+		t.Errorf("resolution = %+v", resolve)
+	}
+	if resolve.Moderation == nil {
+		t.Fatal("author is not suspended with the resolution")
+	}
+	if m := resolve.Moderation; m.Moderation.UserID != reportedUserID || m.From != "active" || m.Status != "suspended" {
+		t.Errorf("moderation = %+v", m)
+	}
+	if until := resolve.Moderation.Moderation.SuspendedUntil; until != "2999-01-01T00:00:00Z" {
+		t.Errorf("suspended_until = %s, want 2999-01-01T00:00:00Z", until)
+	}
+	if len(sessions.invalidated) != 1 {
+		t.Errorf("invalidated sessions = %v, want the revoked one", sessions.invalidated)
+	}
+}
+
+func TestResolveReportsHidesTweetWithClaim(t *testing.T) {
+	reports := &fakeReportRepo{reports: []entity.Report{{ID: "r1", ReporterID: "reporter"}}}
+	h, _ := newReportHandler(reports)
+
+	code := resolveReports(h, `{"target_type": "tweet", "target_id": "`+reportedTweetID+`", "action": "hide_tweet"}`)
+	if code != 200 {
+		t.Fatalf("status = %d, want 200", code)
+	}
+
+	if len(reports.resolved) != 1 || reports.resolved[0].Tweet == nil {
+		t.Fatalf("resolved = %v, want the tweet hidden with the resolution", reports.resolved)
+	}
+
+	want := entity.TweetStatusChange{TweetID: reportedTweetID, From: "published", Status: "hidden"}
+	if *reports.resolved[0].Tweet != want {
+		t.Errorf("tweet change = %+v, want %+v", *reports.resolved[0].Tweet, want)
+	}
+}
+
+func TestResolveReportsFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports *fakeReportRepo
+		body    string
+		want    int
+	}{
+		{
+			name:    "resolved by another moderator meanwhile",
+			reports: &fakeReportRepo{reports: []entity.Report{}},
+			body:    `{"target_type": "user", "target_id": "` + reportedUserID + `", "action": "dismiss"}`,
+			want:    404,
+		},
+		{
+			name:    "tweet changed meanwhile",
+			reports: &fakeReportRepo{err: pgx.ErrNoRows},
+			body:    `{"target_type": "tweet", "target_id": "` + reportedTweetID + `", "action": "delete_tweet"}`,
+			want:    409,
+		},
+		{
+			name:    "suspension in the past",
+			reports: &fakeReportRepo{},
+			body: `{"target_type": "user", "target_id": "` + reportedUserID + `", "action": "suspend_author",
+				"reason": "spam", "suspended_until": "2000-01-01T00:00:00Z"}`,
+			want: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, sessions := newReportHandler(tt.reports)
+
+			if code := resolveReports(h, tt.body); code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+			if len(sessions.invalidated) != 0 {
+				t.Errorf("invalidated sessions = %v, want none", sessions.invalidated)
+			}
+		})
+	}
+}
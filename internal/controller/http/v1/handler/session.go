@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/useragent"
 )
 
 // GetSession godoc
@@ -31,6 +34,10 @@ func (h *Handler) GetSession(ctx *gin.Context) {
 		return
 	}
 
+	if !h.AuthorizeOwner(ctx, session.UserID) {
+		return
+	}
+
 	ctx.JSON(200, session)
 }
 
@@ -56,8 +63,12 @@ func (h *Handler) GetSessions(ctx *gin.Context) {
 	limit := ctx.DefaultQuery("limit", "10")
 	userId := ctx.DefaultQuery("user_id", "")
 
-	if ctx.GetHeader("user_type") == "user" {
-		userId = ctx.GetHeader("sub")
+	if userId == "" && !h.principal(ctx).IsAdmin() {
+		userId = h.principal(ctx).UserID
+	}
+
+	if !h.AuthorizeOwner(ctx, userId) {
+		return
 	}
 
 	req.Page, _ = strconv.Atoi(page)
@@ -105,11 +116,22 @@ func (h *Handler) UpdateSession(ctx *gin.Context) {
 		return
 	}
 
+	existing, err := h.UseCase.SessionRepo.GetSingle(ctx, entity.Id{ID: body.ID})
+	if h.HandleDbError(ctx, err, "Error getting session") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, existing.UserID) {
+		return
+	}
+
 	session, err := h.UseCase.SessionRepo.Update(ctx, body)
 	if h.HandleDbError(ctx, err, "Error updating session") {
 		return
 	}
 
+	h.auditChange(ctx, session.ID, existing, session)
+
 	ctx.JSON(200, session)
 }
 
@@ -131,12 +153,191 @@ func (h *Handler) DeleteSession(ctx *gin.Context) {
 
 	req.ID = ctx.Param("id")
 
-	err := h.UseCase.SessionRepo.Delete(ctx, req)
+	session, err := h.UseCase.SessionRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting session") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, session.UserID) {
+		return
+	}
+
+	err = h.UseCase.SessionRepo.Delete(ctx, req)
 	if h.HandleDbError(ctx, err, "Error deleting session") {
 		return
 	}
 
+	h.auditChange(ctx, session.ID, session, nil)
+
 	ctx.JSON(200, entity.SuccessResponse{
 		Message: "Session deleted successfully",
 	})
 }
+
+// GetMySessions godoc
+// @Router /me/sessions [get]
+// @Summary Get active sessions of the current user
+// @Description Get active sessions of the current user with parsed device info
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Success 200 {object} entity.DeviceSessionList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMySessions(ctx *gin.Context) {
+	var (
+		req      entity.GetListFilter
+		response entity.DeviceSessionList
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+	req.Filters = append(req.Filters,
+		entity.Filter{
+			Column: "user_id",
+			Type:   "eq",
+			Value:  h.principal(ctx).UserID,
+		},
+		entity.Filter{
+			Column: "is_active",
+			Type:   "eq",
+			Value:  "true",
+		},
+	)
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "last_active_at",
+		Order:  "desc nulls last",
+	})
+
+	sessions, err := h.UseCase.SessionRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting sessions") {
+		return
+	}
+
+	for _, session := range sessions.Items {
+		info := useragent.Parse(session.UserAgent)
+		response.Items = append(response.Items, entity.DeviceSession{
+			Session: session,
+			Device: entity.Device{
+				Browser:        info.Browser,
+				BrowserVersion: info.BrowserVersion,
+				OS:             info.OS,
+				Type:           info.Device,
+			},
+			IsCurrent: session.ID == h.principal(ctx).SessionID,
+		})
+	}
+	response.Count = sessions.Count
+
+	ctx.JSON(200, response)
+}
+
+// RevokeOtherSessions godoc
+// @Router /me/sessions/revoke-others [post]
+// @Summary Revoke all sessions except the current one
+// @Description Revoke all sessions except the current one
+// @Security BearerAuth
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} entity.RowsEffected
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) RevokeOtherSessions(ctx *gin.Context) {
+	rows, err := h.UseCase.SessionRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{
+			{
+				Column: "user_id",
+				Type:   "eq",
+				Value:  h.principal(ctx).UserID,
+			},
+			{
+				Column: "id",
+				Type:   "neq",
+				Value:  h.principal(ctx).SessionID,
+			},
+			{
+				Column: "is_active",
+				Type:   "eq",
+				Value:  "true",
+			},
+		},
+		Items: []entity.UpdateFieldItem{
+			{Column: "is_active", Value: false},
+			{Column: "updated_at", Value: "now()"},
+		},
+	})
+	if h.HandleDbError(ctx, err, "Error revoking sessions") {
+		return
+	}
+
+	h.auditTarget(ctx, "user", h.principal(ctx).UserID)
+
+	ctx.JSON(200, rows)
+}
+
+// sessionExpired reports whether the session outlived its absolute lifetime
+// or has been idle for longer than the configured idle timeout.
+func (h *Handler) sessionExpired(session entity.Session) bool {
+	now := time.Now().UTC()
+
+	expiresAt, err := time.Parse(time.RFC3339, session.ExpiresAt)
+	if err == nil && now.After(expiresAt) {
+		return true
+	}
+
+	lastActive := session.LastActiveAt
+	if lastActive == "" {
+		lastActive = session.CreatedAt
+	}
+
+	lastActiveAt, err := time.Parse(time.RFC3339, lastActive)
+	if err == nil && h.Config.Session.IdleTimeout > 0 && now.Sub(lastActiveAt) > h.Config.Session.IdleTimeout {
+		return true
+	}
+
+	return false
+}
+
+// touchSession updates last_active_at at most once per touch interval,
+// redis key works as a throttle so hot paths don't write on every request.
+func (h *Handler) touchSession(ctx *gin.Context, sessionID string) {
+	key := fmt.Sprintf("session-touch-%s", sessionID)
+
+	if _, err := h.Redis.Get(ctx, key); err == nil {
+		return
+	}
+
+	_, err := h.UseCase.SessionRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{{Column: "id", Type: "eq", Value: sessionID}},
+		Items:  []entity.UpdateFieldItem{{Column: "last_active_at", Value: time.Now().UTC()}},
+	})
+	if err != nil {
+		// the next request tries again, the session would otherwise look idle for a whole interval
+		h.Logger.Error(err, "Error touching session")
+		return
+	}
+
+	err = h.Redis.Set(ctx, key, "1", int(h.Config.Session.TouchInterval.Seconds()))
+	if err != nil {
+		h.Logger.Error(err, "Error setting session touch key")
+	}
+}
+
+func (h *Handler) deactivateSession(ctx *gin.Context, sessionID string) {
+	_, err := h.UseCase.SessionRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{{Column: "id", Type: "eq", Value: sessionID}},
+		Items: []entity.UpdateFieldItem{
+			{Column: "is_active", Value: false},
+			{Column: "updated_at", Value: "now()"},
+		},
+	})
+	if err != nil {
+		h.Logger.Error(err, "Error deactivating session")
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+// touchedSessionRepo counts the last_active_at updates, failing them while err is set.
+type touchedSessionRepo struct {
+	usecase.SessionRepoI
+	err     error
+	touches int
+}
+
+func (r *touchedSessionRepo) UpdateField(_ context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	if r.err != nil {
+		return entity.RowsEffected{}, r.err
+	}
+
+	r.touches++
+
+	return entity.RowsEffected{RowsEffected: 1}, nil
+}
+
+func TestTouchSessionRetriesAfterFailedUpdate(t *testing.T) {
+	sessions := &touchedSessionRepo{err: errors.New("connection refused")}
+	redis := &fakeRedis{values: map[string]string{}}
+
+	cfg := &config.Config{}
+	cfg.Session.TouchInterval = time.Minute
+
+	h := &Handler{
+		Logger:  logger.New("error"),
+		Config:  cfg,
+		UseCase: &usecase.UseCase{SessionRepo: sessions},
+		Redis:   redis,
+	}
+
+	ctx, _ := newTestContext("GET", "/v1/me/sessions", "", entity.Principal{})
+
+	h.touchSession(ctx, "s1")
+	if len(redis.values) != 0 {
+		t.Fatalf("touch throttled after a failed update: %v", redis.values)
+	}
+
+	sessions.err = nil
+	h.touchSession(ctx, "s1")
+	if sessions.touches != 1 {
+		t.Fatalf("touches = %d, want the failed touch tried again", sessions.touches)
+	}
+
+	h.touchSession(ctx, "s1")
+	if sessions.touches != 1 {
+		t.Errorf("touches = %d, want the second touch throttled", sessions.touches)
+	}
+}
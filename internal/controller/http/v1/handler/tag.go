@@ -35,6 +35,8 @@ func (h *Handler) CreateTag(ctx *gin.Context) {
 		return
 	}
 
+	h.auditChange(ctx, tag.Id, nil, tag)
+
 	ctx.JSON(200, tag)
 }
 
@@ -134,11 +136,18 @@ func (h *Handler) UpdateTag(ctx *gin.Context) {
 		return
 	}
 
+	existing, err := h.UseCase.TagRepo.GetSingle(ctx, entity.Id{ID: body.Id})
+	if h.HandleDbError(ctx, err, "Error getting tag") {
+		return
+	}
+
 	tag, err := h.UseCase.TagRepo.Update(ctx, body)
 	if h.HandleDbError(ctx, err, "Error updating tag") {
 		return
 	}
 
+	h.auditChange(ctx, tag.Id, existing, tag)
+
 	ctx.JSON(200, tag)
 }
 
@@ -160,11 +169,18 @@ func (h *Handler) DeleteTag(ctx *gin.Context) {
 
 	req.ID = ctx.Param("id")
 
-	err := h.UseCase.TagRepo.Delete(ctx, req)
+	tag, err := h.UseCase.TagRepo.GetSingle(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting tag") {
+		return
+	}
+
+	err = h.UseCase.TagRepo.Delete(ctx, req)
 	if h.HandleDbError(ctx, err, "Error deleting tag") {
 		return
 	}
 
+	h.auditChange(ctx, tag.Id, tag, nil)
+
 	ctx.JSON(200, entity.SuccessResponse{
 		Message: "Tag deleted successfully",
 	})
@@ -1,18 +1,25 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
 )
 
+// maxListedAttachments is how many attachments of a tweet are read, tweets from before the attachment limits
+// can hold more than a tweet can be given now.
+const maxListedAttachments = 100
+
 // CreateTweet godoc
 // @Router /tweet [post]
 // @Summary Create a new tweet
-// @Description Create a new tweet
+// @Description Create a new tweet as a draft, scheduled or published, a draft by default. A poll has 2 to 4 options and is open for 5 minutes to 7 days after the tweet is published. Drafts and scheduled tweets are visible to their owner only. A scheduled tweet is published at its publish_at, which has to be in the future, and is tagged then. Attachments reference uploaded media by media_id and are shown in the order they are listed, with their alt_text. A tweet holds at most 4 photos or 1 video.
 // @Security BearerAuth
 // @Tags tweet
 // @Accept  json
@@ -32,40 +39,73 @@ func (h *Handler) CreateTweet(ctx *gin.Context) {
 		return
 	}
 
-	// Add user ID from header
-	userID := ctx.GetHeader("sub")
-	if userID == "" {
-		h.ReturnError(ctx, "User ID is missing", "User ID is missing", 400)
+	// Owner is always the caller
+	body.Owner.ID = h.principal(ctx).UserID
+
+	// A tweet is created as a draft, scheduled or published
+	if body.Status == "" {
+		body.Status = "draft"
+	}
+
+	if usecase.CheckTweetTransition("draft", body.Status) != nil {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Tweet is created as draft, scheduled or published", 400)
 		return
 	}
-	body.Owner.ID = userID
 
-	// Extract tags
-	taggedTweet, err := h.UseCase.TagRepo.TagTweetByContent(ctx, body)
+	// Scheduled tweets are published by the scheduler
+	body.PublishAt, err = usecase.CheckTweetSchedule(body, time.Now())
 	if err != nil {
-		h.ReturnError(ctx, config.ErrorBadRequest, "AI error: "+err.Error(), 400)
+		h.ReturnError(ctx, config.ErrorBadRequest, "Scheduled tweet needs a publish_at in the future", 400)
 		return
 	}
 
-	// Add tags to the tweet object
-	body.Tags = taggedTweet.Tags
+	if body.Poll != nil {
+		err = usecase.CheckPoll(body.Poll)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, err.Error(), 400)
+			return
+		}
+	}
+
+	// Attachments reference uploaded media
+	if !h.attachMedia(ctx, body.Owner.ID, nil, body.Attachments) {
+		return
+	}
 
-	// Create the tweet in the database
+	// Extract tags, a scheduled tweet is tagged when it is published
+	if body.Status != "scheduled" {
+		taggedTweet, err := h.UseCase.TagRepo.TagTweetByContent(ctx, body)
+		if err != nil {
+			h.ReturnError(ctx, config.ErrorBadRequest, "AI error: "+err.Error(), 400)
+			return
+		}
+
+		// Add tags to the tweet object
+		body.Tags = taggedTweet.Tags
+	}
+
+	// Create the tweet with its attachments and poll in the database, all of them or none
 	tweet, err := h.UseCase.TweetRepo.Create(ctx, body)
 	if err != nil {
 		h.HandleDbError(ctx, err, "Error creating tweet")
 		return
 	}
 
-	// Handle attachments
-	tweet.Attachments, err = h.UseCase.TweetAttachmentsRepo.MultipleUpsert(ctx, entity.AttachmentMultipleInsertRequest{
-		TweetId:     tweet.Id,
-		Attachments: body.Attachments,
+	attachments, err := h.UseCase.TweetAttachmentsRepo.GetList(ctx, entity.GetListFilter{
+		Filters: []entity.Filter{{Column: "tweet_id", Type: "eq", Value: tweet.Id}},
+		Page:    1,
+		Limit:   maxListedAttachments,
 	})
-	if err != nil {
-		h.HandleDbError(ctx, err, "Error creating tweet attachments")
+	if h.HandleDbError(ctx, err, "Error getting tweet attachments") {
 		return
 	}
+	tweet.Attachments = attachments.Items
+
+	if body.Poll != nil && !h.attachPoll(ctx, &tweet) {
+		return
+	}
+
+	h.signAttachments(tweet.Attachments)
 
 	// Send final response
 	ctx.JSON(201, tweet)
@@ -105,7 +145,7 @@ func (h *Handler) GetTweet(ctx *gin.Context) {
 				},
 			},
 			Page:  1,
-			Limit: 10,
+			Limit: maxListedAttachments,
 		},
 	)
 	if h.HandleDbError(ctx, err, "Error getting tweet attachments") {
@@ -119,13 +159,25 @@ func (h *Handler) GetTweet(ctx *gin.Context) {
 		return
 	}
 
+	if !tweetVisible(h.principal(ctx), tweet) {
+		h.ReturnError(ctx, config.ErrorNotFound, "Tweet not found", http.StatusNotFound)
+		return
+	}
+
+	if !h.attachPoll(ctx, &tweet) {
+		return
+	}
+
+	h.signAttachments(tweet.Attachments)
+	h.signAvatar(&tweet.Owner)
+
 	ctx.JSON(200, tweet)
 }
 
 // GetTweets godoc
 // @Router /tweet/list [get]
 // @Summary Get a list of tweets
-// @Description Get a list of tweets
+// @Description Get a list of published tweets, the caller's drafts and scheduled tweets are listed by /me/drafts and /me/scheduled
 // @Security BearerAuth
 // @Tags tweet
 // @Accept  json
@@ -154,6 +206,13 @@ func (h *Handler) GetTweets(ctx *gin.Context) {
 		},
 	)
 
+	// drafts and scheduled tweets are listed to their owner only, by GetMyDrafts and GetMyScheduledTweets
+	req.Filters = append(req.Filters, entity.Filter{
+		Column: "status",
+		Type:   "eq",
+		Value:  "published",
+	})
+
 	req.OrderBy = append(req.OrderBy, entity.OrderBy{
 		Column: "created_at",
 		Order:  "desc",
@@ -164,13 +223,21 @@ func (h *Handler) GetTweets(ctx *gin.Context) {
 		return
 	}
 
+	if !h.attachPolls(ctx, tweets.Items) {
+		return
+	}
+
+	for _, tweet := range tweets.Items {
+		h.signAttachments(tweet.Attachments)
+	}
+
 	ctx.JSON(200, tweets)
 }
 
 // UpdateTweet godoc
 // @Router /tweet [put]
 // @Summary Update a tweet
-// @Description Update a tweet
+// @Description Update a tweet. The content of a published tweet can be edited within the edit window of publishing, the content it replaces is kept in the tweet's history. A tweet that isn't published yet can be scheduled, or rescheduled, with a publish_at in the future, a published tweet can't go back to draft or scheduled. The listed attachments become the attachments of the tweet in the listed order: the ones with an id are kept and can get another alt_text, the ones without are added and the ones left out are removed. A tweet holds at most 4 photos or 1 video. The poll of a tweet is set when it is created and can't be changed.
 // @Security BearerAuth
 // @Tags tweet
 // @Accept  json
@@ -178,6 +245,8 @@ func (h *Handler) GetTweets(ctx *gin.Context) {
 // @Param tweet body entity.Tweet true "Tweet object"
 // @Success 200 {object} entity.Tweet
 // @Failure 400 {object} entity.ErrorResponse
+// @Failure 403 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
 func (h *Handler) UpdateTweet(ctx *gin.Context) {
     var (
         body entity.Tweet
@@ -190,39 +259,342 @@ func (h *Handler) UpdateTweet(ctx *gin.Context) {
         return
     }
 
-    // Validate ownership
-    if body.Owner.ID != ctx.GetHeader("sub") {
-        h.ReturnError(ctx, config.ErrorForbidden, "You have no access to the tweet", http.StatusForbidden)
+    // Validate ownership against the stored tweet, never the request body
+    existing, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: body.Id})
+    if h.HandleDbError(ctx, err, "Error getting tweet") {
+        return
+    }
+
+    if !h.AuthorizeOwner(ctx, existing.Owner.ID) {
+        return
+    }
+    body.Owner = existing.Owner
+
+    if body.Status == "" {
+        body.Status = existing.Status
+    }
+
+    // only moderators hide, remove and show again a tweet
+    if (moderatedTweetStatus(existing.Status) || moderatedTweetStatus(body.Status)) && !h.principal(ctx).IsAdmin() {
+        h.ReturnError(ctx, config.ErrorForbidden, "Tweet status is set by moderators", http.StatusForbidden)
+        return
+    }
+
+    current, err := h.UseCase.TweetAttachmentsRepo.GetList(ctx, entity.GetListFilter{
+        Filters: []entity.Filter{{Column: "tweet_id", Type: "eq", Value: existing.Id}},
+        Page:    1,
+        Limit:   maxListedAttachments,
+    })
+    if h.HandleDbError(ctx, err, "Error getting tweet attachments") {
+        return
+    }
+    existing.Attachments = current.Items
+
+    // the listed attachments replace the current ones, none listed removes them all
+    if body.Attachments == nil {
+        body.Attachments = []entity.Attachment{}
+    }
+
+    // published tweets are edited within the edit window only, content and attachments alike, the repo keeps
+    // what an edit replaces as a revision
+    if !h.checkTweetEdit(ctx, usecase.CheckTweetEdit(existing, body, h.Config.Tweet.EditWindow, time.Now())) {
+        return
+    }
+
+    // a published tweet stays published
+    if usecase.CheckTweetTransition(existing.Status, body.Status) != nil {
+        h.ReturnError(ctx, config.ErrorConflict, "Tweet can't go from "+existing.Status+" to "+body.Status, http.StatusConflict)
         return
     }
 
-    // Tag the tweet content
-    taggedTweet, err := h.UseCase.TagRepo.TagTweetByContent(ctx, body)
+    body.PublishAt, err = usecase.CheckTweetSchedule(body, time.Now())
     if err != nil {
-        h.ReturnError(ctx, config.ErrorInternalServer, "Error tagging tweet", 500)
+        h.ReturnError(ctx, config.ErrorBadRequest, "Scheduled tweet needs a publish_at in the future", 400)
+        return
+    }
+
+    // New attachments reference uploaded media of the owner, the listed order is their new order
+    if !h.attachMedia(ctx, existing.Owner.ID, current.Items, body.Attachments) {
         return
     }
 
-    // Update the tweet in the database
-    tweet, err := h.UseCase.TweetRepo.Update(ctx, taggedTweet)
+    // Tag the tweet content, a scheduled tweet is tagged when it is published
+    taggedTweet := body
+    if body.Status != "scheduled" {
+        taggedTweet, err = h.UseCase.TagRepo.TagTweetByContent(ctx, body)
+        if err != nil {
+            h.ReturnError(ctx, config.ErrorInternalServer, "Error tagging tweet", 500)
+            return
+        }
+    }
+
+    // Update the tweet and its attachments in the database, the edit window is checked again on the locked tweet
+    tweet, err := h.UseCase.TweetRepo.Update(ctx, taggedTweet, func(stored entity.Tweet) error {
+        return usecase.CheckTweetEdit(stored, body, h.Config.Tweet.EditWindow, time.Now())
+    })
+    if errors.Is(err, usecase.ErrTweetEditWindow) {
+        h.checkTweetEdit(ctx, err)
+        return
+    }
     if h.HandleDbError(ctx, err, "Error updating tweet") {
         return
     }
 
-    // Upsert tweet attachments
-    tweet.Attachments, err = h.UseCase.TweetAttachmentsRepo.MultipleUpsert(ctx, entity.AttachmentMultipleInsertRequest{
-        TweetId:     tweet.Id,
-        Attachments: body.Attachments,
+    attachments, err := h.UseCase.TweetAttachmentsRepo.GetList(ctx, entity.GetListFilter{
+        Filters: []entity.Filter{{Column: "tweet_id", Type: "eq", Value: tweet.Id}},
+        Page:    1,
+        Limit:   maxListedAttachments,
     })
-    if h.HandleDbError(ctx, err, "Error upserting tweet attachments") {
+    if h.HandleDbError(ctx, err, "Error getting tweet attachments") {
+        return
+    }
+    tweet.Attachments = attachments.Items
+
+    if !h.attachPoll(ctx, &tweet) {
         return
     }
 
+    h.signAttachments(tweet.Attachments)
+
     // Return the updated tweet
     ctx.JSON(200, tweet)
 }
 
 
+// GetTweetHistory godoc
+// @Router /tweet/{id}/history [get]
+// @Summary Get the edit history of a tweet
+// @Description Get the contents and attachments of a tweet replaced by edits, newest first. created_at is when a content was published or edited in.
+// @Security BearerAuth
+// @Tags tweet
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tweet ID"
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Success 200 {object} entity.TweetRevisionList
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+func (h *Handler) GetTweetHistory(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+
+	tweet, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting tweet") {
+		return
+	}
+
+	tweet.Owner, err = h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: tweet.Owner.ID})
+	if h.HandleDbError(ctx, err, "Error getting tweet owner") {
+		return
+	}
+
+	if !tweetVisible(h.principal(ctx), tweet) {
+		h.ReturnError(ctx, config.ErrorNotFound, "Tweet not found", http.StatusNotFound)
+		return
+	}
+
+	req.Filters = append(req.Filters, entity.Filter{
+		Column: "tweet_id",
+		Type:   "eq",
+		Value:  tweet.Id,
+	})
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "created_at",
+		Order:  "desc",
+	})
+
+	history, err := h.UseCase.TweetRevisionRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting tweet history") {
+		return
+	}
+
+	ctx.JSON(200, history)
+}
+
+// PublishTweet godoc
+// @Router /tweet/{id}/publish [post]
+// @Summary Publish a tweet
+// @Description Publish a draft or a scheduled tweet now
+// @Security BearerAuth
+// @Tags tweet
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Tweet ID"
+// @Success 200 {object} entity.Tweet
+// @Failure 400 {object} entity.ErrorResponse
+// @Failure 404 {object} entity.ErrorResponse
+// @Failure 409 {object} entity.ErrorResponse
+func (h *Handler) PublishTweet(ctx *gin.Context) {
+	existing, err := h.UseCase.TweetRepo.GetSingle(ctx, entity.Id{ID: ctx.Param("id")})
+	if h.HandleDbError(ctx, err, "Error getting tweet") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, existing.Owner.ID) {
+		return
+	}
+
+	// published and moderated tweets are moved by moderators only
+	if existing.Status != "draft" && existing.Status != "scheduled" {
+		h.ReturnError(ctx, config.ErrorConflict, "Tweet is already "+existing.Status, http.StatusConflict)
+		return
+	}
+
+	existing.Status = "published"
+
+	// a scheduled tweet isn't tagged until it is published
+	tweet, err := h.UseCase.TagRepo.TagTweetByContent(ctx, existing)
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error tagging tweet", 500)
+		return
+	}
+
+	tweet, err = h.UseCase.TweetRepo.Update(ctx, tweet, nil)
+	if h.HandleDbError(ctx, err, "Error publishing tweet") {
+		return
+	}
+
+	attachments, err := h.UseCase.TweetAttachmentsRepo.GetList(ctx, entity.GetListFilter{
+		Filters: []entity.Filter{{Column: "tweet_id", Type: "eq", Value: tweet.Id}},
+		Page:    1,
+		Limit:   maxListedAttachments,
+	})
+	if h.HandleDbError(ctx, err, "Error getting tweet attachments") {
+		return
+	}
+
+	tweet.Attachments = attachments.Items
+
+	if !h.attachPoll(ctx, &tweet) {
+		return
+	}
+
+	h.signAttachments(tweet.Attachments)
+
+	ctx.JSON(200, tweet)
+}
+
+// GetMyDrafts godoc
+// @Router /me/drafts [get]
+// @Summary Get the caller's drafts
+// @Description Get the drafts of the caller, the last edited first
+// @Security BearerAuth
+// @Tags tweet
+// @Accept  json
+// @Produce  json
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Success 200 {object} entity.TweetList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMyDrafts(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+	req.Filters = append(req.Filters,
+		entity.Filter{
+			Column: "owner_id",
+			Type:   "eq",
+			Value:  h.principal(ctx).UserID,
+		},
+		entity.Filter{
+			Column: "status",
+			Type:   "eq",
+			Value:  "draft",
+		},
+	)
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "updated_at",
+		Order:  "desc",
+	})
+
+	tweets, err := h.UseCase.TweetRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting drafts") {
+		return
+	}
+
+	if !h.attachPolls(ctx, tweets.Items) {
+		return
+	}
+
+	for _, tweet := range tweets.Items {
+		h.signAttachments(tweet.Attachments)
+	}
+
+	ctx.JSON(200, tweets)
+}
+
+// GetMyScheduledTweets godoc
+// @Router /me/scheduled [get]
+// @Summary Get the caller's scheduled tweets
+// @Description Get the tweets of the caller waiting to be published, the next to be published first
+// @Security BearerAuth
+// @Tags tweet
+// @Accept  json
+// @Produce  json
+// @Param page query number true "page"
+// @Param limit query number true "limit"
+// @Success 200 {object} entity.TweetList
+// @Failure 400 {object} entity.ErrorResponse
+func (h *Handler) GetMyScheduledTweets(ctx *gin.Context) {
+	var (
+		req entity.GetListFilter
+	)
+
+	page := ctx.DefaultQuery("page", "1")
+	limit := ctx.DefaultQuery("limit", "10")
+
+	req.Page, _ = strconv.Atoi(page)
+	req.Limit, _ = strconv.Atoi(limit)
+	req.Filters = append(req.Filters,
+		entity.Filter{
+			Column: "owner_id",
+			Type:   "eq",
+			Value:  h.principal(ctx).UserID,
+		},
+		entity.Filter{
+			Column: "status",
+			Type:   "eq",
+			Value:  "scheduled",
+		},
+	)
+
+	req.OrderBy = append(req.OrderBy, entity.OrderBy{
+		Column: "publish_at",
+		Order:  "asc",
+	})
+
+	tweets, err := h.UseCase.TweetRepo.GetList(ctx, req)
+	if h.HandleDbError(ctx, err, "Error getting scheduled tweets") {
+		return
+	}
+
+	if !h.attachPolls(ctx, tweets.Items) {
+		return
+	}
+
+	for _, tweet := range tweets.Items {
+		h.signAttachments(tweet.Attachments)
+	}
+
+	ctx.JSON(200, tweets)
+}
+
 // DeleteTweet godoc
 // @Router /tweet/{id} [delete]
 // @Summary Delete a tweet
@@ -246,8 +618,7 @@ func (h *Handler) DeleteTweet(ctx *gin.Context) {
 		return
 	}
 
-	if tweet.Owner.ID != ctx.GetHeader("sub") {
-		h.ReturnError(ctx, config.ErrorForbidden, "You have no access to the tweet", http.StatusForbidden)
+	if !h.AuthorizeOwner(ctx, tweet.Owner.ID) {
 		return
 	}
 
@@ -260,3 +631,17 @@ func (h *Handler) DeleteTweet(ctx *gin.Context) {
 		Message: "Tweet deleted successfully",
 	})
 }
+
+// checkTweetEdit writes the error response of a failed usecase.CheckTweetEdit, it tells whether the edit can go on.
+func (h *Handler) checkTweetEdit(ctx *gin.Context, err error) bool {
+	if errors.Is(err, usecase.ErrTweetEditWindow) {
+		h.ReturnError(ctx, config.ErrorForbidden, "Tweet can no longer be edited", http.StatusForbidden)
+		return false
+	}
+	if err != nil {
+		h.ReturnError(ctx, config.ErrorInternalServer, "Error checking tweet edit", 500)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+const editedTweetID = "3c5e7a90-1b2d-4f6e-8a0c-2d4f6b8a0c1e"
+
+func TestUpdateTweetEditWindow(t *testing.T) {
+	owner := entity.Principal{UserID: "u1", Role: "user"}
+	now := time.Now().UTC()
+
+	published := func(ago time.Duration) entity.Tweet {
+		return entity.Tweet{
+			Id:          editedTweetID,
+			Owner:       entity.User{ID: "u1"},
+			Content:     "hello",
+			Status:      "published",
+			PublishedAt: now.Add(-ago).Format(time.RFC3339),
+			Attachments: []entity.Attachment{{Id: "a1", AltText: "a cat"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		tweet  entity.Tweet
+		locked *entity.Tweet
+		body   string
+		want   int
+	}{
+		{
+			name:  "attachment removed within window",
+			tweet: published(10 * time.Minute),
+			body:  `{"id": "` + editedTweetID + `", "content": "hello", "attachments": []}`,
+			want:  200,
+		},
+		{
+			name:  "attachment removed after window",
+			tweet: published(2 * time.Hour),
+			body:  `{"id": "` + editedTweetID + `", "content": "hello", "attachments": []}`,
+			want:  403,
+		},
+		{
+			name:  "alt text changed after window",
+			tweet: published(2 * time.Hour),
+			body:  `{"id": "` + editedTweetID + `", "content": "hello", "attachments": [{"id": "a1", "alt_text": "a dog"}]}`,
+			want:  403,
+		},
+		{
+			name:  "unchanged after window",
+			tweet: published(2 * time.Hour),
+			body:  `{"id": "` + editedTweetID + `", "content": "hello", "attachments": [{"id": "a1", "alt_text": "a cat"}]}`,
+			want:  200,
+		},
+		{
+			name:   "window over by the time the tweet is locked",
+			tweet:  published(10 * time.Minute),
+			locked: &entity.Tweet{Id: editedTweetID, Content: "hello", PublishedAt: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+			body:   `{"id": "` + editedTweetID + `", "content": "hi", "attachments": [{"id": "a1", "alt_text": "a cat"}]}`,
+			want:   403,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tweets := &fakeTweetRepo{tweets: map[string]entity.Tweet{editedTweetID: tt.tweet}, locked: map[string]entity.Tweet{}}
+			if tt.locked != nil {
+				tt.locked.Attachments = tt.tweet.Attachments
+				tweets.locked[editedTweetID] = *tt.locked
+			}
+
+			cfg := &config.Config{}
+			cfg.Tweet.EditWindow = time.Hour
+
+			h := &Handler{
+				Logger: logger.New("error"),
+				Config: cfg,
+				UseCase: &usecase.UseCase{
+					TweetRepo:            tweets,
+					TweetAttachmentsRepo: &fakeAttachmentRepo{attachments: map[string][]entity.Attachment{editedTweetID: tt.tweet.Attachments}},
+					TagRepo:              &fakeTagRepo{},
+					PollRepo:             &fakePollRepo{},
+				},
+			}
+
+			ctx, recorder := newTestContext("PUT", "/v1/tweet", tt.body, owner)
+			ctx.Set(operationKey, usecase.OpTweetUpdate)
+
+			h.UpdateTweet(ctx)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if tt.want != 200 && len(tweets.updated) != 0 {
+				t.Errorf("tweet updated: %v", tweets.updated)
+			}
+		})
+	}
+}
+
+func TestCreateTweetWithPoll(t *testing.T) {
+	body := `{"content": "tabs or spaces?", "status": "published",
+		"poll": {"options": [{"text": "tabs"}, {"text": "spaces"}], "duration_minutes": 60}}`
+
+	tests := []struct {
+		name    string
+		err     error
+		want    int
+		created int
+	}{
+		{name: "created with the tweet", want: 201, created: 1},
+		{name: "nothing created when the insert fails", err: errors.New("poll insert failed"), want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tweets := &fakeTweetRepo{tweets: map[string]entity.Tweet{}, err: tt.err}
+			h := &Handler{
+				Logger: logger.New("error"),
+				Config: &config.Config{},
+				UseCase: &usecase.UseCase{
+					TweetRepo:            tweets,
+					TweetAttachmentsRepo: &fakeAttachmentRepo{},
+					TagRepo:              &fakeTagRepo{},
+					PollRepo:             &fakePollRepo{},
+				},
+			}
+
+			ctx, recorder := newTestContext("POST", "/v1/tweet", body, entity.Principal{UserID: "u1", Role: "user"})
+
+			h.CreateTweet(ctx)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if len(tweets.created) != tt.created {
+				t.Fatalf("created %d tweets, want %d", len(tweets.created), tt.created)
+			}
+			if tt.created > 0 && (tweets.created[0].Poll == nil || len(tweets.created[0].Poll.Options) != 2) {
+				t.Errorf("poll = %+v, want it created with the tweet", tweets.created[0].Poll)
+			}
+		})
+	}
+}
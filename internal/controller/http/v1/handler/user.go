@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/hash"
 )
 
@@ -42,6 +43,8 @@ func (h *Handler) CreateUser(ctx *gin.Context) {
 		return
 	}
 
+	h.auditChange(ctx, user.ID, nil, user)
+
 	ctx.JSON(201, user)
 }
 
@@ -69,6 +72,7 @@ func (h *Handler) GetUser(ctx *gin.Context) {
 	}
 
 	user.Password = ""
+	h.signAvatar(&user)
 
 	ctx.JSON(200, user)
 }
@@ -125,6 +129,10 @@ func (h *Handler) GetUsers(ctx *gin.Context) {
 		return
 	}
 
+	for i := range users.Items {
+		h.signAvatar(&users.Items[i])
+	}
+
 	ctx.JSON(200, users)
 }
 
@@ -150,8 +158,55 @@ func (h *Handler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
-	if ctx.GetHeader("user_type") == "user" {
-		body.ID = ctx.GetHeader("sub")
+	if body.ID == "" {
+		body.ID = h.principal(ctx).UserID
+	}
+
+	existing, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: body.ID})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return
+	}
+
+	if !h.AuthorizeOwner(ctx, existing.ID) {
+		return
+	}
+
+	if body.UserRole == "" {
+		body.UserRole = existing.UserRole
+	}
+	if body.Status == "" {
+		body.Status = existing.Status
+	}
+	if body.Email == "" {
+		body.Email = existing.Email
+	}
+
+	// users change their email through /me/email so the new address gets verified
+	if body.Email != existing.Email && !h.authorize(ctx, usecase.OpUserManage, existing.ID) {
+		return
+	}
+
+	// suspensions and bans go through the moderation endpoints, which keep their history and revoke sessions
+	if body.Status != existing.Status && (moderatedStatus(body.Status) || moderatedStatus(existing.Status)) {
+		h.ReturnError(ctx, config.ErrorBadRequest, "Use the moderation endpoints to suspend, ban or restore a user", 400)
+		return
+	}
+
+	// role and status are managed by admins, superadmins only by superadmins
+	if body.UserRole != existing.UserRole || body.Status != existing.Status {
+		if !h.authorize(ctx, usecase.OpUserManage, existing.ID) {
+			return
+		}
+	}
+	if body.UserRole != existing.UserRole && (body.UserRole == "superadmin" || existing.UserRole == "superadmin") {
+		if !h.authorize(ctx, usecase.OpUserGrantSuperAdmin, existing.ID) {
+			return
+		}
+	}
+
+	// an avatar is a photo the user uploaded, so its variants can be shown
+	if body.AvatarId != existing.AvatarId && body.AvatarId != "" && !h.avatarMedia(ctx, existing.ID, body.AvatarId) {
+		return
 	}
 
 	if body.Password != "" {
@@ -167,6 +222,17 @@ func (h *Handler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	h.auditChange(ctx, user.ID, existing, user)
+	if user.UserRole != existing.UserRole {
+		h.auditEvent(ctx, entity.AuditLog{
+			Action:     usecase.AuditUserRoleChange,
+			TargetType: "user",
+			TargetID:   user.ID,
+			Before:     map[string]interface{}{"user_role": existing.UserRole},
+			After:      map[string]interface{}{"user_role": user.UserRole},
+		})
+	}
+
 	ctx.JSON(200, user)
 }
 
@@ -188,15 +254,27 @@ func (h *Handler) DeleteUser(ctx *gin.Context) {
 
 	req.ID = ctx.Param("id")
 
-	if ctx.GetHeader("user_type") == "user" {
-		req.ID = ctx.GetHeader("sub")
+	if !h.AuthorizeOwner(ctx, req.ID) {
+		return
+	}
+
+	user, err := h.UseCase.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: req.ID})
+	if h.HandleDbError(ctx, err, "Error getting user") {
+		return
+	}
+
+	// tokens of a deleted user must not outlive it, cached sessions included
+	if !h.revokeSessions(ctx, user.ID) {
+		return
 	}
 
-	err := h.UseCase.UserRepo.Delete(ctx, req)
+	err = h.UseCase.UserRepo.Delete(ctx, req)
 	if h.HandleDbError(ctx, err, "Error deleting user") {
 		return
 	}
 
+	h.auditChange(ctx, user.ID, user, nil)
+
 	ctx.JSON(200, entity.SuccessResponse{
 		Message: "User deleted successfully",
 	})
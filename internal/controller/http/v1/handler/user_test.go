@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+)
+
+func TestDeleteUserRevokesSessions(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]entity.User{
+		"u1": {ID: "u1", UserRole: "user", Status: "active"},
+	}}
+	sessions := &fakeSessionRepo{sessions: []entity.Session{
+		{ID: "s1", UserID: "u1", IsActive: true},
+		{ID: "s2", UserID: "u2", IsActive: true},
+	}}
+
+	h := &Handler{
+		Logger: logger.New("error"),
+		Config: &config.Config{},
+		UseCase: &usecase.UseCase{
+			UserRepo:    users,
+			SessionRepo: sessions,
+		},
+	}
+
+	ctx, recorder := newTestContext("DELETE", "/v1/user/u1", "", entity.Principal{UserID: "u1", Role: "user", SessionID: "s1"},
+		gin.Param{Key: "id", Value: "u1"})
+	ctx.Set(operationKey, usecase.OpUserDelete)
+
+	h.DeleteUser(ctx)
+
+	if recorder.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	if _, ok := users.users["u1"]; ok {
+		t.Error("user was not deleted")
+	}
+	if sessions.sessions[0].IsActive {
+		t.Error("session of the deleted user is still active")
+	}
+	if !sessions.sessions[1].IsActive {
+		t.Error("session of another user was revoked")
+	}
+}
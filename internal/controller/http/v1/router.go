@@ -4,7 +4,6 @@ package v1
 import (
 	"net/http"
 
-	"github.com/casbin/casbin"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
@@ -17,8 +16,89 @@ import (
 	"github.com/golanguzb70/udevslabs-twitter/internal/controller/http/v1/handler"
 	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/ratelimit"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/rbac"
 )
 
+// routeOperations maps every v1 route to the usecase operation whose policy guards it.
+var routeOperations = map[string]usecase.Operation{
+	"POST /v1/user":            usecase.OpUserCreate,
+	"GET /v1/user/list":        usecase.OpUserList,
+	"GET /v1/user/:id":         usecase.OpUserGet,
+	"PUT /v1/user":             usecase.OpUserUpdate,
+	"DELETE /v1/user/:id":      usecase.OpUserDelete,
+	"POST /v1/user/:id/report": usecase.OpUserReport,
+
+	"GET /v1/session/list":   usecase.OpSessionList,
+	"GET /v1/session/:id":    usecase.OpSessionGet,
+	"PUT /v1/session":        usecase.OpSessionUpdate,
+	"DELETE /v1/session/:id": usecase.OpSessionDelete,
+
+	"GET /v1/me/sessions":                usecase.OpMeSessionList,
+	"POST /v1/me/sessions/revoke-others": usecase.OpMeSessionRevokeOthers,
+	"POST /v1/me/tokens":                 usecase.OpMeTokenCreate,
+	"GET /v1/me/tokens":                  usecase.OpMeTokenList,
+	"DELETE /v1/me/tokens/:id":           usecase.OpMeTokenDelete,
+	"POST /v1/me/delete":                 usecase.OpMeDelete,
+	"GET /v1/me/export":                  usecase.OpMeExport,
+	"POST /v1/me/email":                  usecase.OpMeEmailChange,
+	"POST /v1/me/email/confirm":          usecase.OpMeEmailConfirm,
+	"GET /v1/me/drafts":                  usecase.OpMeDraftList,
+	"GET /v1/me/scheduled":               usecase.OpMeScheduledList,
+
+	"POST /v1/auth/logout":                  usecase.OpAuthLogout,
+	"POST /v1/auth/register":                usecase.OpAuthRegister,
+	"POST /v1/auth/verify-email":            usecase.OpAuthVerifyEmail,
+	"POST /v1/auth/resend-verification":     usecase.OpAuthResendVerification,
+	"POST /v1/auth/login":                   usecase.OpAuthLogin,
+	"GET /v1/auth/oauth/:provider/start":    usecase.OpAuthOAuth,
+	"GET /v1/auth/oauth/:provider/callback": usecase.OpAuthOAuth,
+	"POST /v1/auth/cancel-deletion":         usecase.OpAuthCancelDeletion,
+
+	"POST /v1/tag":       usecase.OpTagCreate,
+	"GET /v1/tag/list":   usecase.OpTagList,
+	"GET /v1/tag/:id":    usecase.OpTagGet,
+	"PUT /v1/tag":        usecase.OpTagUpdate,
+	"DELETE /v1/tag/:id": usecase.OpTagDelete,
+
+	"POST /v1/follower":     usecase.OpFollowerUpsert,
+	"GET /v1/follower/list": usecase.OpFollowerList,
+
+	"POST /v1/tweet":             usecase.OpTweetCreate,
+	"GET /v1/tweet/list":         usecase.OpTweetList,
+	"GET /v1/tweet/:id":          usecase.OpTweetGet,
+	"PUT /v1/tweet":              usecase.OpTweetUpdate,
+	"DELETE /v1/tweet/:id":       usecase.OpTweetDelete,
+	"POST /v1/tweet/:id/report":  usecase.OpTweetReport,
+	"GET /v1/tweet/:id/history":  usecase.OpTweetHistory,
+	"POST /v1/tweet/:id/publish": usecase.OpTweetPublish,
+	"POST /v1/tweet/:id/vote":    usecase.OpTweetVote,
+
+	"POST /v1/media":            usecase.OpMediaUpload,
+	"POST /v1/media/uploads":    usecase.OpMediaUpload,
+	"PUT /v1/media/uploads/:id": usecase.OpMediaChunk,
+	"GET /v1/media/uploads/:id": usecase.OpMediaGet,
+	"GET /v1/media/:id":         usecase.OpMediaServe,
+
+	"GET /v1/admin/rbac/policies":    usecase.OpRbacManage,
+	"POST /v1/admin/rbac/policies":   usecase.OpRbacManage,
+	"DELETE /v1/admin/rbac/policies": usecase.OpRbacManage,
+	"GET /v1/admin/rbac/roles":       usecase.OpRbacManage,
+	"POST /v1/admin/rbac/roles":      usecase.OpRbacManage,
+	"DELETE /v1/admin/rbac/roles":    usecase.OpRbacManage,
+	"POST /v1/admin/rbac/check":      usecase.OpRbacCheck,
+
+	"GET /v1/admin/audit": usecase.OpAuditList,
+
+	"POST /v1/admin/users/:id/suspend":   usecase.OpUserModerate,
+	"POST /v1/admin/users/:id/ban":       usecase.OpUserModerate,
+	"POST /v1/admin/users/:id/restore":   usecase.OpUserModerate,
+	"GET /v1/admin/users/:id/moderation": usecase.OpUserModerationList,
+
+	"GET /v1/admin/reports":          usecase.OpReportList,
+	"POST /v1/admin/reports/resolve": usecase.OpReportResolve,
+}
+
 // NewRouter -.
 // Swagger spec:
 // @title       Go Clean Template API
@@ -28,20 +108,24 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useCase *usecase.UseCase, redis rediscache.RedisCache) {
+func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useCase *usecase.UseCase, redis rediscache.RedisCache, limiter *ratelimit.Limiter, enforcer *rbac.Enforcer) {
 	// Options
+	// gin.Context falls back to the request context, which carries the authenticated entity.Principal
+	engine.ContextWithFallback = true
 	engine.Use(gin.Logger())
 	engine.Use(gin.Recovery())
 
-	handlerV1 := handler.NewHandler(l, config, useCase, redis)
+	handlerV1 := handler.NewHandler(l, config, useCase, redis, limiter, enforcer)
 
 	// Swagger - Place this before AuthMiddleware
 	url := ginSwagger.URL("swagger/doc.json") // The URL pointing to API definition
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
 
-	// Initialize Casbin enforcer
-	e := casbin.NewEnforcer("config/rbac.conf", "config/policy.csv")
-	engine.Use(handlerV1.AuthMiddleware(e)) // Apply authentication middleware to all routes except Swagger
+	// requests denied by the casbin policy are audited too, so the audit runs first
+	engine.Use(handlerV1.Audit(routeOperations))
+
+	// Casbin enforcer is loaded from postgres in app.Run
+	engine.Use(handlerV1.AuthMiddleware(enforcer)) // Apply authentication middleware to all routes except Swagger
 
 	// K8s probe
 	engine.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
@@ -50,23 +134,40 @@ func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useC
 	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Routes
-	v1 := engine.Group("/v1") 
+	v1 := engine.Group("/v1", handlerV1.Authorize(routeOperations))
 	{
 		v1.POST("/user", handlerV1.CreateUser)
 		v1.GET("/user/list", handlerV1.GetUsers)
 		v1.GET("/user/:id", handlerV1.GetUser)
 		v1.PUT("/user", handlerV1.UpdateUser)
 		v1.DELETE("/user/:id", handlerV1.DeleteUser)
+		v1.POST("/user/:id/report", handlerV1.ReportUser)
 
 		v1.GET("/session/list", handlerV1.GetSessions)
 		v1.GET("/session/:id", handlerV1.GetSession)
 		v1.PUT("/session", handlerV1.UpdateSession)
 		v1.DELETE("/session/:id", handlerV1.DeleteSession)
 
+		v1.GET("/me/sessions", handlerV1.GetMySessions)
+		v1.POST("/me/sessions/revoke-others", handlerV1.RevokeOtherSessions)
+		v1.POST("/me/tokens", handlerV1.CreateMyToken)
+		v1.GET("/me/tokens", handlerV1.GetMyTokens)
+		v1.DELETE("/me/tokens/:id", handlerV1.DeleteMyToken)
+		v1.POST("/me/delete", handlerV1.DeleteMyAccount)
+		v1.GET("/me/export", handlerV1.ExportMyData)
+		v1.POST("/me/email", handlerV1.ChangeMyEmail)
+		v1.POST("/me/email/confirm", handlerV1.ConfirmMyEmail)
+		v1.GET("/me/drafts", handlerV1.GetMyDrafts)
+		v1.GET("/me/scheduled", handlerV1.GetMyScheduledTweets)
+
 		v1.POST("/auth/logout", handlerV1.Logout)
 		v1.POST("/auth/register", handlerV1.Register)
 		v1.POST("/auth/verify-email", handlerV1.VerifyEmail)
+		v1.POST("/auth/resend-verification", handlerV1.ResendVerification)
 		v1.POST("/auth/login", handlerV1.Login)
+		v1.GET("/auth/oauth/:provider/start", handlerV1.OAuthStart)
+		v1.GET("/auth/oauth/:provider/callback", handlerV1.OAuthCallback)
+		v1.POST("/auth/cancel-deletion", handlerV1.CancelAccountDeletion)
 
 		v1.POST("/tag", handlerV1.CreateTag)
 		v1.GET("/tag/list", handlerV1.GetTags)
@@ -82,8 +183,35 @@ func NewRouter(engine *gin.Engine, l *logger.Logger, config *config.Config, useC
 		v1.GET("/tweet/:id", handlerV1.GetTweet)
 		v1.PUT("/tweet", handlerV1.UpdateTweet)
 		v1.DELETE("/tweet/:id", handlerV1.DeleteTweet)
+		v1.POST("/tweet/:id/report", handlerV1.ReportTweet)
+		v1.GET("/tweet/:id/history", handlerV1.GetTweetHistory)
+		v1.POST("/tweet/:id/publish", handlerV1.PublishTweet)
+		v1.POST("/tweet/:id/vote", handlerV1.VotePoll)
+
+		v1.POST("/media", handlerV1.UploadMedia)
+		v1.POST("/media/uploads", handlerV1.CreateMediaUpload)
+		v1.PUT("/media/uploads/:id", handlerV1.UploadMediaChunk)
+		v1.GET("/media/uploads/:id", handlerV1.GetMediaUpload)
+		v1.GET("/media/:id", handlerV1.ServeMedia)
+
+		v1.GET("/admin/rbac/policies", handlerV1.GetRbacPolicies)
+		v1.POST("/admin/rbac/policies", handlerV1.AddRbacPolicy)
+		v1.DELETE("/admin/rbac/policies", handlerV1.RemoveRbacPolicy)
+		v1.GET("/admin/rbac/roles", handlerV1.GetRbacRoles)
+		v1.POST("/admin/rbac/roles", handlerV1.AddRbacRole)
+		v1.DELETE("/admin/rbac/roles", handlerV1.RemoveRbacRole)
+		v1.POST("/admin/rbac/check", handlerV1.CheckRbac)
+
+		v1.GET("/admin/audit", handlerV1.GetAuditLog)
+
+		v1.POST("/admin/users/:id/suspend", handlerV1.SuspendUser)
+		v1.POST("/admin/users/:id/ban", handlerV1.BanUser)
+		v1.POST("/admin/users/:id/restore", handlerV1.RestoreUser)
+		v1.GET("/admin/users/:id/moderation", handlerV1.GetUserModeration)
+
+		v1.GET("/admin/reports", handlerV1.GetReports)
+		v1.POST("/admin/reports/resolve", handlerV1.ResolveReports)
 
-		
 	}
 
 	// user := v1.Group("/user")
@@ -0,0 +1,412 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fileadapter "github.com/casbin/casbin/persist/file-adapter"
+	"github.com/gin-gonic/gin"
+	rediscache "github.com/golanguzb70/redis-cache"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/controller/http/v1/handler"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/jwt"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/rbac"
+)
+
+// access is the expected decision for each kind of caller. Owner is a user acting on
+// its own resource, other is a user acting on someone else's.
+type access struct {
+	anonymous, owner, other, admin, superadmin bool
+}
+
+var (
+	anyone        = access{anonymous: true, owner: true, other: true, admin: true, superadmin: true}
+	authenticated = access{owner: true, other: true, admin: true, superadmin: true}
+	ownerOrAdmin  = access{owner: true, admin: true, superadmin: true}
+	ownerOnly     = access{owner: true}
+	adminOnly     = access{admin: true, superadmin: true}
+	superOnly     = access{superadmin: true}
+)
+
+// auditLog keeps the entries the router writes in memory.
+type auditLog struct {
+	usecase.AuditLogRepoI
+	entries []entity.AuditLog
+}
+
+func (r *auditLog) Create(_ context.Context, req entity.AuditLog) (entity.AuditLog, error) {
+	r.entries = append(r.entries, req)
+	return req, nil
+}
+
+// sessionStore serves the sessions tokens of the tests point to.
+type sessionStore struct {
+	usecase.SessionRepoI
+	sessions map[string]entity.Session
+}
+
+func (r *sessionStore) GetSingle(_ context.Context, req entity.Id) (entity.Session, error) {
+	session, ok := r.sessions[req.ID]
+	if !ok {
+		return session, pgx.ErrNoRows
+	}
+
+	return session, nil
+}
+
+// touchedCache answers every key as present, so sessions look recently touched.
+type touchedCache struct {
+	rediscache.RedisCache
+}
+
+func (touchedCache) Get(context.Context, string) (string, error) {
+	return "1", nil
+}
+
+var testSessions = &sessionStore{sessions: map[string]entity.Session{
+	"user-session": {ID: "user-session", UserID: "user-id", IsActive: true},
+}}
+
+func newTestEngine(t *testing.T, audit *auditLog) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	enforcer, err := rbac.New("../../../../config/rbac.conf", fileadapter.NewAdapter("../../../../config/policy.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	NewRouter(engine, logger.New("error"), &config.Config{JWT: config.JWT{Secret: "secret"}}, &usecase.UseCase{AuditLogRepo: audit, SessionRepo: testSessions}, touchedCache{}, nil, enforcer)
+
+	return engine
+}
+
+const ownerID = "owner-id"
+
+// newAuthorizeEngine serves a single route behind the Authorize middleware for principal. The handler of the
+// route answers 200, after checking the owner when checkOwner is set.
+func newAuthorizeEngine(principal entity.Principal, method, path string, checkOwner bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	h := handler.NewHandler(logger.New("error"), &config.Config{}, nil, nil, nil, nil)
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(entity.ContextWithPrincipal(c.Request.Context(), principal))
+	})
+
+	engine.Group("", h.Authorize(routeOperations)).Handle(method, path, func(c *gin.Context) {
+		if checkOwner && !h.AuthorizeOwner(c, ownerID) {
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	return engine
+}
+
+// routeAccess is who may call each v1 route.
+var routeAccess = []struct {
+	route string
+	want  access
+}{
+	{"POST /v1/user", adminOnly},
+	{"GET /v1/user/list", adminOnly},
+	{"GET /v1/user/:id", authenticated},
+	{"PUT /v1/user", ownerOrAdmin},
+	{"DELETE /v1/user/:id", ownerOrAdmin},
+	{"POST /v1/user/:id/report", authenticated},
+
+	{"GET /v1/session/list", ownerOrAdmin},
+	{"GET /v1/session/:id", ownerOrAdmin},
+	{"PUT /v1/session", ownerOrAdmin},
+	{"DELETE /v1/session/:id", ownerOrAdmin},
+
+	{"GET /v1/me/sessions", authenticated},
+	{"POST /v1/me/sessions/revoke-others", authenticated},
+	{"POST /v1/me/tokens", authenticated},
+	{"GET /v1/me/tokens", authenticated},
+	{"DELETE /v1/me/tokens/:id", ownerOnly},
+	{"POST /v1/me/delete", authenticated},
+	{"GET /v1/me/export", authenticated},
+	{"POST /v1/me/email", authenticated},
+	{"POST /v1/me/email/confirm", authenticated},
+	{"GET /v1/me/drafts", authenticated},
+	{"GET /v1/me/scheduled", authenticated},
+
+	{"POST /v1/auth/logout", authenticated},
+	{"POST /v1/auth/register", anyone},
+	{"POST /v1/auth/verify-email", anyone},
+	{"POST /v1/auth/resend-verification", anyone},
+	{"POST /v1/auth/login", anyone},
+	{"GET /v1/auth/oauth/:provider/start", anyone},
+	{"GET /v1/auth/oauth/:provider/callback", anyone},
+	{"POST /v1/auth/cancel-deletion", anyone},
+
+	{"POST /v1/tag", adminOnly},
+	{"GET /v1/tag/list", authenticated},
+	{"GET /v1/tag/:id", authenticated},
+	{"PUT /v1/tag", adminOnly},
+	{"DELETE /v1/tag/:id", adminOnly},
+
+	{"POST /v1/follower", ownerOrAdmin},
+	{"GET /v1/follower/list", ownerOrAdmin},
+
+	{"POST /v1/tweet", authenticated},
+	{"GET /v1/tweet/list", authenticated},
+	{"GET /v1/tweet/:id", authenticated},
+	{"PUT /v1/tweet", ownerOrAdmin},
+	{"DELETE /v1/tweet/:id", ownerOrAdmin},
+	{"POST /v1/tweet/:id/report", authenticated},
+	{"GET /v1/tweet/:id/history", authenticated},
+	{"POST /v1/tweet/:id/publish", ownerOnly},
+	{"POST /v1/tweet/:id/vote", authenticated},
+
+	{"POST /v1/media", authenticated},
+	{"POST /v1/media/uploads", authenticated},
+	{"PUT /v1/media/uploads/:id", ownerOnly},
+	{"GET /v1/media/uploads/:id", ownerOrAdmin},
+	{"GET /v1/media/:id", anyone},
+
+	{"GET /v1/admin/rbac/policies", superOnly},
+	{"POST /v1/admin/rbac/policies", superOnly},
+	{"DELETE /v1/admin/rbac/policies", superOnly},
+	{"GET /v1/admin/rbac/roles", superOnly},
+	{"POST /v1/admin/rbac/roles", superOnly},
+	{"DELETE /v1/admin/rbac/roles", superOnly},
+	{"POST /v1/admin/rbac/check", adminOnly},
+
+	{"GET /v1/admin/audit", adminOnly},
+
+	{"POST /v1/admin/users/:id/suspend", adminOnly},
+	{"POST /v1/admin/users/:id/ban", adminOnly},
+	{"POST /v1/admin/users/:id/restore", adminOnly},
+	{"GET /v1/admin/users/:id/moderation", adminOnly},
+
+	{"GET /v1/admin/reports", adminOnly},
+	{"POST /v1/admin/reports/resolve", adminOnly},
+}
+
+func TestRouteOperations(t *testing.T) {
+	callers := []struct {
+		name      string
+		principal entity.Principal
+		want      func(access) bool
+	}{
+		{"anonymous", entity.Principal{}, func(a access) bool { return a.anonymous }},
+		{"owner", entity.Principal{UserID: ownerID, Role: "user"}, func(a access) bool { return a.owner }},
+		{"other", entity.Principal{UserID: "other-id", Role: "user"}, func(a access) bool { return a.other }},
+		{"admin", entity.Principal{UserID: "admin-id", Role: "admin"}, func(a access) bool { return a.admin }},
+		{"superadmin", entity.Principal{UserID: "superadmin-id", Role: "superadmin"}, func(a access) bool { return a.superadmin }},
+	}
+
+	tested := map[string]bool{}
+
+	for _, tt := range routeAccess {
+		tested[tt.route] = true
+
+		t.Run(tt.route, func(t *testing.T) {
+			op, ok := routeOperations[tt.route]
+			if !ok {
+				t.Fatalf("no operation is declared for %s", tt.route)
+			}
+
+			for _, c := range callers {
+				for _, check := range []string{"checked", "unchecked"} {
+					method, path, _ := strings.Cut(tt.route, " ")
+					engine := newAuthorizeEngine(c.principal, method, path, check == "checked")
+
+					req := httptest.NewRequest(method, strings.ReplaceAll(path, ":", ""), nil)
+					rec := httptest.NewRecorder()
+					engine.ServeHTTP(rec, req)
+
+					allowed := rec.Code == http.StatusOK
+					if allowed != c.want(tt.want) && !(check == "unchecked" && usecase.Policies[op].Owned) {
+						t.Errorf("%s, owner %s: status = %d, want allowed = %v", c.name, check, rec.Code, c.want(tt.want))
+					}
+
+					// an owned operation is never served unless the handler checked the owner
+					if check == "unchecked" && usecase.Policies[op].Owned && allowed {
+						t.Errorf("%s: served without an owner check", c.name)
+					}
+				}
+			}
+		})
+	}
+
+	// every registered route has to be declared and tested, so a new route can't slip through unguarded
+	for _, r := range newTestEngine(t, &auditLog{}).Routes() {
+		if !strings.HasPrefix(r.Path, "/v1/") {
+			continue
+		}
+
+		route := r.Method + " " + r.Path
+		if _, ok := routeOperations[route]; !ok {
+			t.Errorf("route %s has no operation in routeOperations", route)
+		}
+		if !tested[route] {
+			t.Errorf("route %s is not covered by this test", route)
+		}
+	}
+
+	for route := range routeOperations {
+		if !tested[route] {
+			t.Errorf("routeOperations has %s which is not covered by this test", route)
+		}
+	}
+}
+
+// The casbin policy runs before Authorize, it has to let through every caller Authorize allows.
+func TestPolicyAllowsRouteOperations(t *testing.T) {
+	enforcer, err := rbac.New("../../../../config/rbac.conf", fileadapter.NewAdapter("../../../../config/policy.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roles := []struct {
+		role string
+		want func(access) bool
+	}{
+		{"unauthorized", func(a access) bool { return a.anonymous }},
+		{"user", func(a access) bool { return a.owner || a.other }},
+		{"admin", func(a access) bool { return a.admin }},
+		{"superadmin", func(a access) bool { return a.superadmin }},
+	}
+
+	for _, tt := range routeAccess {
+		method, path, _ := strings.Cut(tt.route, " ")
+
+		for _, r := range roles {
+			if !r.want(tt.want) {
+				continue
+			}
+
+			// AuthMiddleware enforces the route pattern, not the request path
+			ok, err := enforcer.Enforce(r.role, path, method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Errorf("%s: policy denies %s", tt.route, r.role)
+			}
+		}
+	}
+}
+
+// Identity comes from verified tokens only, headers that used to carry it must be ignored.
+func TestIdentityHeadersAreIgnored(t *testing.T) {
+	engine := newTestEngine(t, &auditLog{})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{
+			name:    "admin route with spoofed role",
+			method:  http.MethodGet,
+			path:    "/v1/user/list",
+			headers: map[string]string{"sub": "someone", "user_role": "superadmin", "user_type": "admin"},
+			want:    http.StatusForbidden,
+		},
+		{
+			name:    "logout with spoofed session",
+			method:  http.MethodPost,
+			path:    "/v1/auth/logout",
+			headers: map[string]string{"sub": "someone", "session_id": "someone-elses-session"},
+			want:    http.StatusForbidden,
+		},
+		{
+			name:    "invalid token with spoofed role",
+			method:  http.MethodGet,
+			path:    "/v1/user/list",
+			headers: map[string]string{"Authorization": "Bearer invalid", "user_role": "superadmin"},
+			want:    http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+// Requests the casbin policy denies never reach Authorize, they have to be audited all the same.
+func TestPolicyDenialsAreAudited(t *testing.T) {
+	audit := &auditLog{}
+	engine := newTestEngine(t, audit)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/admin/users/target-id/suspend", nil))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	if len(audit.entries) != 1 {
+		t.Fatalf("audit entries = %v, want one", audit.entries)
+	}
+
+	entry := audit.entries[0]
+	if entry.Action != string(usecase.OpUserModerate) || entry.Status != http.StatusForbidden || entry.TargetID != "target-id" {
+		t.Errorf("audit entry = %+v, want a denied %s of target-id", entry, usecase.OpUserModerate)
+	}
+
+	// a signed in user is denied by the policy as well, and recorded as the actor
+	token, err := jwt.GenerateJWT(map[string]interface{}{
+		"sub":        "user-id",
+		"user_role":  "user",
+		"session_id": "user-session",
+	}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/users/target-id/ban", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+
+	if len(audit.entries) != 2 {
+		t.Fatalf("audit entries = %v, want two", audit.entries)
+	}
+
+	entry = audit.entries[1]
+	if entry.ActorID != "user-id" || entry.Status != http.StatusForbidden || entry.TargetID != "target-id" {
+		t.Errorf("audit entry = %+v, want a denied %s of target-id by user-id", entry, usecase.OpUserModerate)
+	}
+
+	// routes that aren't audited write nothing
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if len(audit.entries) != 2 {
+		t.Errorf("audit entries = %v, want only the denied requests", audit.entries)
+	}
+}
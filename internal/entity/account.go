@@ -0,0 +1,28 @@
+package entity
+
+type AccountDeletion struct {
+	DeleteScheduledAt string `json:"delete_scheduled_at"`
+}
+
+type DataExport struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Status    string `json:"status"` // pending, processing, ready, failed
+	Error     string `json:"error"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type DataExportList struct {
+	Items []DataExport `json:"items"`
+	Count int          `json:"count"`
+}
+
+type EmailChangeRequest struct {
+	Email string `json:"email"`
+}
+
+type EmailChangeConfirm struct {
+	Otp string `json:"otp"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+type ApiToken struct {
+	ID         string   `json:"id"`
+	UserID     string   `json:"user_id"`
+	Name       string   `json:"name"`
+	Lookup     string   `json:"lookup"`
+	TokenHash  string   `json:"-"`
+	Token      string   `json:"token,omitempty"` // plaintext, returned only once on creation
+	Scopes     []string `json:"scopes"`
+	LastUsedAt string   `json:"last_used_at"`
+	ExpiresAt  string   `json:"expires_at"`
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+type ApiTokenCreateRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type ApiTokenList struct {
+	Items []ApiToken `json:"items"`
+	Count int        `json:"count"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+type AuditLog struct {
+	ID         string                 `json:"id"`
+	ActorID    string                 `json:"actor_id"`
+	ActorRole  string                 `json:"actor_role"`
+	Action     string                 `json:"action"`
+	Method     string                 `json:"method"`
+	Path       string                 `json:"path"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Before     map[string]interface{} `json:"before"` // only the fields that changed
+	After      map[string]interface{} `json:"after"`
+	Status     int                    `json:"status"`
+	Reason     string                 `json:"reason"`
+	IPAddress  string                 `json:"ip_address"`
+	UserAgent  string                 `json:"user_agent"`
+	CreatedAt  string                 `json:"created_at"`
+}
+
+type AuditLogList struct {
+	Items []AuditLog `json:"items"`
+	Count int        `json:"count"`
+}
@@ -15,8 +15,28 @@ type RegisterRequest struct {
 	Password string `json:"password"`
 }
 
+type ResendVerification struct {
+	Email string `json:"email"`
+}
+
 type VerifyEmail struct {
 	Email    string `json:"email"`
 	Otp      string `json:"otp"`
 	Platform string `json:"platform"`
 }
+
+type LoginAttempt struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	Identifier string `json:"identifier"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	Success    bool   `json:"success"`
+	Reason     string `json:"reason"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type LoginAttemptList struct {
+	Items []LoginAttempt `json:"items"`
+	Count int            `json:"count"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+type Identity struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type IdentitySingleRequest struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+}
+
+type IdentityList struct {
+	Items []Identity `json:"items"`
+	Count int        `json:"count"`
+}
+
+type OAuthState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	Platform string `json:"platform"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+type MailOutbox struct {
+	ID            string `json:"id"`
+	Recipient     string `json:"recipient"`
+	Template      string `json:"template"`
+	Subject       string `json:"subject"`
+	TextBody      string `json:"text_body"`
+	HTMLBody      string `json:"html_body"`
+	Status        string `json:"status"` // pending, sending, failed
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "encoding/json"
+
+type Media struct {
+	ID               string                  `json:"id"`
+	OwnerID          string                  `json:"owner_id"`
+	Kind             string                  `json:"kind"` // photo, video
+	ContentType      string                  `json:"content_type"`
+	Size             int64                   `json:"size"`
+	Received         int64                   `json:"received"` // bytes of a chunked upload stored so far, the offset to resume from
+	Chunks           int                     `json:"-"`
+	Status           string                  `json:"status"` // uploading, ready, deleting once it is garbage collected
+	StorageKey       string                  `json:"-"`
+	Width            int                     `json:"width"`
+	Height           int                     `json:"height"`
+	Duration         float64                 `json:"duration,omitempty"` // seconds of a video
+	Blurhash         string                  `json:"blurhash"`           // placeholder shown while a photo or video loads
+	Variants         map[string]MediaVariant `json:"variants"`           // original, thumbnail, medium and large, or poster and hls
+	VariantsStatus   string                  `json:"variants_status"`    // none, pending, processing, ready, skipped, failed, rejected
+	VariantsError    string                  `json:"variants_error,omitempty"`
+	VariantsAttempts int                     `json:"-"`
+	CreatedAt        string                  `json:"created_at"`
+	UpdatedAt        string                  `json:"updated_at"`
+}
+
+// MediaVariant is a size of a photo, made by the image pipeline. Its file path is never sent to clients, it is
+// stored with MarshalMediaVariants.
+type MediaVariant struct {
+	FilePath    string `json:"-"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url,omitempty"` // signed, set in responses only
+}
+
+// storedMediaVariant is a MediaVariant as the variants column keeps it, with its file path.
+type storedMediaVariant struct {
+	MediaVariant
+	FilePath string `json:"filepath"`
+}
+
+// MarshalMediaVariants encodes variants by name for the variants column.
+func MarshalMediaVariants(variants map[string]MediaVariant) ([]byte, error) {
+	stored := make(map[string]storedMediaVariant, len(variants))
+	for name, variant := range variants {
+		stored[name] = storedMediaVariant{MediaVariant: variant, FilePath: variant.FilePath}
+	}
+
+	return json.Marshal(stored)
+}
+
+// UnmarshalMediaVariants decodes the variants column into variants by name.
+func UnmarshalMediaVariants(data []byte) (map[string]MediaVariant, error) {
+	stored := map[string]storedMediaVariant{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+
+	variants := make(map[string]MediaVariant, len(stored))
+	for name, variant := range stored {
+		variant.MediaVariant.FilePath = variant.FilePath
+		variants[name] = variant.MediaVariant
+	}
+
+	return variants, nil
+}
+
+type MediaUploadRequest struct {
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
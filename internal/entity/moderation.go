@@ -0,0 +1,28 @@
+package entity
+
+type ModerationRequest struct {
+	Reason         string `json:"reason"`
+	SuspendedUntil string `json:"suspended_until"` // RFC3339, required to suspend
+}
+
+type UserModeration struct {
+	ID             string `json:"id"`
+	UserID         string `json:"user_id"`
+	ModeratorID    string `json:"moderator_id"`
+	Action         string `json:"action"` // suspend, ban, restore
+	Reason         string `json:"reason"`
+	SuspendedUntil string `json:"suspended_until,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// UserModerationChange moves a user from the status From to Status and records Moderation in the history.
+type UserModerationChange struct {
+	Moderation UserModeration
+	From       string
+	Status     string
+}
+
+type UserModerationList struct {
+	Items []UserModeration `json:"items"`
+	Count int              `json:"count"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+// Poll of a tweet, voting is open for DurationMinutes after the tweet is published.
+type Poll struct {
+	Options         []PollOption `json:"options"`
+	DurationMinutes int          `json:"duration_minutes"`
+	EndsAt          string       `json:"ends_at,omitempty"` // set once the tweet is published
+	Ended           bool         `json:"ended"`
+	TotalVotes      *int64       `json:"total_votes,omitempty"` // hidden until the caller votes or the poll ends
+	Vote            string       `json:"vote,omitempty"`        // option the caller voted for
+}
+
+type PollOption struct {
+	Id    string `json:"id"`
+	Text  string `json:"text"`
+	Votes *int64 `json:"votes,omitempty"` // hidden until the caller votes or the poll ends
+}
+
+type PollVote struct {
+	TweetId  string `json:"-"`
+	UserId   string `json:"-"`
+	OptionId string `json:"option_id"`
+}
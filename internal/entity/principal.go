@@ -0,0 +1,39 @@
+package entity
+
+import "context"
+
+// Principal is the authenticated caller of a request, the zero value is an anonymous caller.
+type Principal struct {
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"`
+	Type      string `json:"type"`
+	Platform  string `json:"platform"`
+	SessionID string `json:"session_id"`
+	TokenID   string `json:"token_id"` // personal access token the request is made with, SessionID is empty then
+}
+
+// SystemPrincipal is the actor of the background jobs, it has no user.
+var SystemPrincipal = Principal{Role: "system"}
+
+// IsAuthenticated -.
+func (p Principal) IsAuthenticated() bool {
+	return p.UserID != ""
+}
+
+// IsAdmin -.
+func (p Principal) IsAdmin() bool {
+	return p.UserID != "" && (p.Role == "admin" || p.Role == "superadmin")
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of ctx, anonymous when there is none.
+func PrincipalFromContext(ctx context.Context) Principal {
+	principal, _ := ctx.Value(principalKey{}).(Principal)
+	return principal
+}
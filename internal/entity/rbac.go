@@ -0,0 +1,38 @@
+package entity
+
+type RbacPolicy struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+}
+
+type RbacPolicyList struct {
+	Items []RbacPolicy `json:"items"`
+	Count int          `json:"count"`
+}
+
+// RbacRole assigns a role to a subject (role inheritance), e.g. admin inherits user.
+type RbacRole struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+}
+
+type RbacRoleList struct {
+	Items []RbacRole `json:"items"`
+	Count int        `json:"count"`
+}
+
+// RbacCheckRequest is checked for Role, or for the role of UserID when Role is empty.
+type RbacCheckRequest struct {
+	Role   string `json:"role"`
+	UserID string `json:"user_id"`
+	Path   string `json:"path"`
+	Method string `json:"method"`
+}
+
+type RbacCheckResponse struct {
+	Allowed bool   `json:"allowed"`
+	Role    string `json:"role"`
+	Path    string `json:"path"`
+	Method  string `json:"method"`
+}
@@ -0,0 +1,68 @@
+package entity
+
+type ReportRequest struct {
+	Category string `json:"category"` // spam, harassment, hate, violence, sexual, self_harm, misinformation, impersonation, other
+	Details  string `json:"details"`
+}
+
+type Report struct {
+	ID         string `json:"id"`
+	ReporterID string `json:"reporter_id"`
+	TargetType string `json:"target_type"` // tweet, user
+	TargetID   string `json:"target_id"`
+	Category   string `json:"category"`
+	Severity   int    `json:"severity"`
+	Details    string `json:"details"`
+	Locale     string `json:"-"`
+	Status     string `json:"status"`           // open, resolved
+	Action     string `json:"action,omitempty"` // the action it was resolved with
+	ResolvedBy string `json:"resolved_by,omitempty"`
+	ResolvedAt string `json:"resolved_at,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// ReportQueueItem is a reported target with its open reports merged.
+type ReportQueueItem struct {
+	TargetType      string   `json:"target_type"`
+	TargetID        string   `json:"target_id"`
+	Reports         int      `json:"reports"`
+	Severity        int      `json:"severity"` // the highest severity of the reports
+	Categories      []string `json:"categories"`
+	FirstReportedAt string   `json:"first_reported_at"`
+	LastReportedAt  string   `json:"last_reported_at"`
+}
+
+type ReportQueue struct {
+	Items []ReportQueueItem `json:"items"`
+	Count int               `json:"count"`
+}
+
+type ReportResolveRequest struct {
+	TargetType     string `json:"target_type"`
+	TargetID       string `json:"target_id"`
+	Action         string `json:"action"` // dismiss, hide_tweet, delete_tweet, suspend_author
+	Reason         string `json:"reason"`
+	SuspendedUntil string `json:"suspended_until"` // RFC3339, required to suspend the author
+}
+
+// ReportResolve resolves the open reports of a target, the tweet status change or user moderation of the action
+// is applied in the same transaction.
+type ReportResolve struct {
+	Report     Report // target, action and resolver of the reports
+	Tweet      *TweetStatusChange
+	Moderation *UserModerationChange
+}
+
+// TweetStatusChange moves a tweet from the status From to Status.
+type TweetStatusChange struct {
+	TweetID string
+	From    string
+	Status  string
+}
+
+type ReportResolution struct {
+	TargetType string   `json:"target_type"`
+	TargetID   string   `json:"target_id"`
+	Action     string   `json:"action"`
+	Reports    []Report `json:"reports"`
+}
@@ -13,6 +13,24 @@ type Session struct {
 	UpdatedAt    string `json:"updated_at"`
 }
 
+type Device struct {
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version"`
+	OS             string `json:"os"`
+	Type           string `json:"type"`
+}
+
+type DeviceSession struct {
+	Session
+	Device    Device `json:"device"`
+	IsCurrent bool   `json:"is_current"`
+}
+
+type DeviceSessionList struct {
+	Items []DeviceSession `json:"sessions"`
+	Count int             `json:"count"`
+}
+
 type SessionList struct {
 	Items []Session `json:"sessions"`
 	Count int       `json:"count"`
@@ -1,12 +1,19 @@
 package entity
 
 type Attachment struct {
-	Id          string `json:"id"`
-	TweetId     string `json:"-"`
-	FilePath    string `json:"filepath"`
-	ContentType string `json:"content_type"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
+	Id          string                  `json:"id"`
+	TweetId     string                  `json:"-"`
+	MediaId     string                  `json:"media_id"`
+	FilePath    string                  `json:"-"`
+	ContentType string                  `json:"content_type"`
+	Position    int                     `json:"position"` // set from the order of the attachments of the tweet
+	AltText     string                  `json:"alt_text"`
+	URL         string                  `json:"url,omitempty"` // signed url of the media
+	Blurhash    string                  `json:"blurhash"`      // of the media, set once its variants are made
+	Variants    map[string]MediaVariant `json:"variants"`      // of the media, by variant name
+	Status      string                  `json:"status"`        // ready, or processing and failed for a video being transcoded
+	CreatedAt   string                  `json:"created_at"`
+	UpdatedAt   string                  `json:"updated_at"`
 }
 
 type AttachmentList struct {
@@ -25,14 +32,57 @@ type Tweet struct {
 	Content     string              `json:"content"`
 	Tags        map[string][]string `json:"tags"`
 	Attachments []Attachment        `json:"attachments"`
-	Status      string              `json:"status"`
+	Poll        *Poll               `json:"poll,omitempty"`
+	Status      string              `json:"status"`               // draft, scheduled or published, hidden and removed by moderators
+	PublishAt   string              `json:"publish_at,omitempty"` // when a scheduled tweet gets published
+	PublishedAt string              `json:"published_at,omitempty"`
+	EditedAt    string              `json:"edited_at,omitempty"` // last edit of the content after publishing
+	EditCount   int                 `json:"edit_count"`
 	CreatedAt   string              `json:"created_at"`
 	UpdatedAt   string              `json:"updated_at"`
 }
 
+// EditedBy reports whether edit changes the content of the tweet or its attachments: adds, removes or reorders
+// them or changes their alt text. Kept attachments of edit carry their id, new ones have none.
+func (t Tweet) EditedBy(edit Tweet) bool {
+	if edit.Content != t.Content || len(edit.Attachments) != len(t.Attachments) {
+		return true
+	}
+
+	for i, attachment := range edit.Attachments {
+		if attachment.Id == "" || attachment.Id != t.Attachments[i].Id || attachment.AltText != t.Attachments[i].AltText {
+			return true
+		}
+	}
+
+	return false
+}
+
 type TweetList struct {
 	Items []Tweet `json:"items"`
 	Count int64   `json:"count"`
 }
 
+// TweetRevision is a content of a tweet and its attachments replaced by an edit.
+type TweetRevision struct {
+	Id          string               `json:"id"`
+	TweetId     string               `json:"tweet_id"`
+	Content     string               `json:"content"`
+	Tags        map[string][]string  `json:"tags"`
+	Attachments []RevisionAttachment `json:"attachments"`
+	CreatedAt   string               `json:"created_at"` // when the content was published or edited in
+}
+
+// RevisionAttachment is an attachment of a tweet revision, in the order of the revision.
+type RevisionAttachment struct {
+	MediaId     string `json:"media_id"`
+	ContentType string `json:"content_type"`
+	AltText     string `json:"alt_text"`
+}
+
+type TweetRevisionList struct {
+	Items []TweetRevision `json:"items"`
+	Count int64           `json:"count"`
+}
+
 
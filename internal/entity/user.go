@@ -14,6 +14,12 @@ type User struct {
 	Gender      string `json:"gender"`
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
+
+	DeleteScheduledAt string `json:"delete_scheduled_at,omitempty"` // set while a deactivated account waits for deletion
+	PendingEmail      string `json:"pending_email,omitempty"`       // new address waiting for confirmation
+	SuspendedUntil    string `json:"suspended_until,omitempty"`     // set while a suspended account waits to be restored
+
+	AvatarVariants map[string]MediaVariant `json:"avatar_variants,omitempty"` // of the media avatar_id refers to
 }
 
 type UserSingleRequest struct {
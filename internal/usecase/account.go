@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// AuditAccountPurge is written when a deactivated account is deleted after its grace period.
+	AuditAccountPurge = "user.purge"
+	// AuditUnverifiedPurge is written when an account is deleted for never verifying its email.
+	AuditUnverifiedPurge = "user.purge_unverified"
+)
+
+// exportPageSize is the page size the export reads the user's data with.
+const exportPageSize = 100
+
+// exportStaleAfter is how long an export may stay processing before another replica takes it over.
+const exportStaleAfter = 15 * time.Minute
+
+// PurgeDeactivatedAccounts deletes the accounts whose deletion grace period is over and returns how many were deleted.
+func (u *UseCase) PurgeDeactivatedAccounts(ctx context.Context) (int, error) {
+	users, err := u.UserRepo.GetList(ctx, entity.GetListFilter{
+		Limit: exportPageSize,
+		Filters: []entity.Filter{
+			{Column: "status", Type: "eq", Value: "deactivated"},
+			{Column: "delete_scheduled_at", Type: "lte", Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+		OrderBy: []entity.OrderBy{{Column: "delete_scheduled_at", Order: "asc"}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("usecase - PurgeDeactivatedAccounts - UserRepo.GetList: %w", err)
+	}
+
+	for _, user := range users.Items {
+		err = u.UserRepo.Delete(ctx, entity.Id{ID: user.ID})
+		if err != nil {
+			return 0, fmt.Errorf("usecase - PurgeDeactivatedAccounts - UserRepo.Delete: %w", err)
+		}
+
+		err = u.Audit(ctx, entity.SystemPrincipal, entity.AuditLog{
+			Action:     AuditAccountPurge,
+			TargetType: "user",
+			TargetID:   user.ID,
+			Before:     map[string]interface{}{"username": user.Username, "delete_scheduled_at": user.DeleteScheduledAt},
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(users.Items), nil
+}
+
+// PurgeUnverifiedAccounts deletes the accounts registered more than olderThan ago that never verified their email,
+// which frees their email and username for a new registration. It returns how many were deleted.
+func (u *UseCase) PurgeUnverifiedAccounts(ctx context.Context, olderThan time.Duration) (int, error) {
+	users, err := u.UserRepo.GetList(ctx, entity.GetListFilter{
+		Limit: exportPageSize,
+		Filters: []entity.Filter{
+			{Column: "status", Type: "eq", Value: "inverify"},
+			{Column: "created_at", Type: "lte", Value: time.Now().UTC().Add(-olderThan).Format(time.RFC3339)},
+		},
+		OrderBy: []entity.OrderBy{{Column: "created_at", Order: "asc"}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("usecase - PurgeUnverifiedAccounts - UserRepo.GetList: %w", err)
+	}
+
+	for _, user := range users.Items {
+		err = u.UserRepo.Delete(ctx, entity.Id{ID: user.ID})
+		if err != nil {
+			return 0, fmt.Errorf("usecase - PurgeUnverifiedAccounts - UserRepo.Delete: %w", err)
+		}
+
+		err = u.Audit(ctx, entity.SystemPrincipal, entity.AuditLog{
+			Action:     AuditUnverifiedPurge,
+			TargetType: "user",
+			TargetID:   user.ID,
+			Before:     map[string]interface{}{"username": user.Username, "email": user.Email, "created_at": user.CreatedAt},
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(users.Items), nil
+}
+
+// ProcessDataExports builds the archives of pending exports until none is left, ready archives are kept for ttl.
+func (u *UseCase) ProcessDataExports(ctx context.Context, ttl time.Duration) error {
+	_, err := u.DataExportRepo.DeleteExpired(ctx)
+	if err != nil {
+		return fmt.Errorf("usecase - ProcessDataExports - DataExportRepo.DeleteExpired: %w", err)
+	}
+
+	for ctx.Err() == nil {
+		export, err := u.DataExportRepo.Claim(ctx, exportStaleAfter)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("usecase - ProcessDataExports - DataExportRepo.Claim: %w", err)
+		}
+
+		items := []entity.UpdateFieldItem{
+			{Column: "updated_at", Value: time.Now().UTC()},
+		}
+
+		archive, buildErr := u.BuildDataExport(ctx, export.UserID)
+		if buildErr != nil {
+			items = append(items,
+				entity.UpdateFieldItem{Column: "status", Value: "failed"},
+				entity.UpdateFieldItem{Column: "error", Value: buildErr.Error()},
+			)
+		} else {
+			items = append(items,
+				entity.UpdateFieldItem{Column: "status", Value: "ready"},
+				entity.UpdateFieldItem{Column: "archive", Value: archive},
+				entity.UpdateFieldItem{Column: "expires_at", Value: time.Now().UTC().Add(ttl)},
+			)
+		}
+
+		_, err = u.DataExportRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+			Filter: []entity.Filter{{Column: "id", Type: "eq", Value: export.ID}},
+			Items:  items,
+		})
+		if err != nil {
+			return fmt.Errorf("usecase - ProcessDataExports - DataExportRepo.UpdateField: %w", err)
+		}
+
+		if buildErr != nil {
+			return fmt.Errorf("usecase - ProcessDataExports - BuildDataExport %s: %w", export.ID, buildErr)
+		}
+	}
+
+	return ctx.Err()
+}
+
+// exportAttachment is an attachment with the tweet it belongs to, which entity.Attachment doesn't serialize.
+type exportAttachment struct {
+	TweetID string `json:"tweet_id"`
+	entity.Attachment
+}
+
+// BuildDataExport collects everything stored about a user into a ZIP archive of json files.
+func (u *UseCase) BuildDataExport(ctx context.Context, userID string) ([]byte, error) {
+	user, err := u.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: userID})
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+
+	tweets, err := collectPages(func(page int) ([]entity.Tweet, error) {
+		list, err := u.TweetRepo.GetList(ctx, exportFilter(page, "owner_id", userID, "created_at"))
+		return list.Items, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []exportAttachment{}
+	for i := range tweets {
+		tweets[i].Owner = entity.User{ID: user.ID, Username: user.Username}
+		for _, attachment := range tweets[i].Attachments {
+			attachments = append(attachments, exportAttachment{TweetID: tweets[i].Id, Attachment: attachment})
+		}
+	}
+
+	followers, err := collectPages(func(page int) ([]entity.User, error) {
+		list, err := u.FollowerRepo.GetList(ctx, exportFilter(page, "following_id", userID, "f.created_at"))
+		return list.Items, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	following, err := collectPages(func(page int) ([]entity.User, error) {
+		list, err := u.FollowerRepo.GetList(ctx, exportFilter(page, "follower_id", userID, "f.created_at"))
+		return list.Items, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := collectPages(func(page int) ([]entity.Session, error) {
+		list, err := u.SessionRepo.GetList(ctx, exportFilter(page, "user_id", userID, "created_at"))
+		return list.Items, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		buf    bytes.Buffer
+		writer = zip.NewWriter(&buf)
+	)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"tweets.json", tweets},
+		{"attachments.json", attachments},
+		{"followers.json", followers},
+		{"following.json", following},
+		{"sessions.json", sessions},
+	}
+
+	for _, file := range files {
+		w, err := writer.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(file.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func exportFilter(page int, column, userID, orderBy string) entity.GetListFilter {
+	return entity.GetListFilter{
+		Page:    page,
+		Limit:   exportPageSize,
+		Filters: []entity.Filter{{Column: column, Type: "eq", Value: userID}},
+		OrderBy: []entity.OrderBy{{Column: orderBy, Order: "asc"}},
+	}
+}
+
+// collectPages calls fetch with increasing pages until a page comes back short.
+func collectPages[T any](fetch func(page int) ([]T, error)) ([]T, error) {
+	items := []T{}
+
+	for page := 1; ; page++ {
+		batch, err := fetch(page)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, batch...)
+
+		if len(batch) < exportPageSize {
+			return items, nil
+		}
+	}
+}
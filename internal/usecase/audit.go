@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// Actions recorded by usecase hooks rather than by a route.
+const (
+	AuditUserRoleChange = "user.role_change"
+	AuditLoginLockout   = "auth.lockout"
+)
+
+// AuditedOperations are written to audit_log, mapped to the type of resource they act on.
+var AuditedOperations = map[Operation]string{
+	OpUserCreate:   "user",
+	OpUserUpdate:   "user",
+	OpUserDelete:   "user",
+	OpUserModerate: "user",
+	OpUserReport:   "user",
+
+	OpSessionUpdate: "session",
+	OpSessionDelete: "session",
+
+	OpMeSessionRevokeOthers: "session",
+	OpMeTokenCreate:         "api_token",
+	OpMeTokenDelete:         "api_token",
+	OpMeDelete:              "user",
+	OpMeExport:              "user",
+	OpMeEmailChange:         "user",
+	OpMeEmailConfirm:        "user",
+
+	OpAuthLogin:              "user",
+	OpAuthLogout:             "session",
+	OpAuthRegister:           "user",
+	OpAuthVerifyEmail:        "user",
+	OpAuthOAuth:              "user",
+	OpAuthCancelDeletion:     "user",
+	OpAuthResendVerification: "user",
+
+	OpTagCreate: "tag",
+	OpTagUpdate: "tag",
+	OpTagDelete: "tag",
+
+	OpTweetReport:   "tweet",
+	OpReportResolve: "report",
+
+	OpRbacManage: "rbac",
+}
+
+// auditRedacted fields never reach audit_log, a change of them is recorded without the value.
+var auditRedacted = map[string]bool{
+	"password":      true,
+	"access_token":  true,
+	"token":         true,
+	"token_hash":    true,
+	"otp":           true,
+	"client_secret": true,
+}
+
+const auditRedactedValue = "[redacted]"
+
+// Audit appends an entry to audit_log with actor as its actor, entity.SystemPrincipal for background jobs.
+func (u *UseCase) Audit(ctx context.Context, actor entity.Principal, entry entity.AuditLog) error {
+	entry.ActorID = actor.UserID
+	entry.ActorRole = actor.Role
+
+	_, err := u.AuditLogRepo.Create(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("usecase - Audit - %s: %w", entry.Action, err)
+	}
+
+	return nil
+}
+
+// AuditDiff returns the fields that differ between two states of a resource.
+// A nil state stands for a resource that did not exist, e.g. before it was created.
+func AuditDiff(before, after interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	beforeDiff := map[string]interface{}{}
+	afterDiff := map[string]interface{}{}
+
+	for key, value := range beforeFields {
+		if other, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, other) {
+			beforeDiff[key] = value
+		}
+	}
+	for key, value := range afterFields {
+		if other, ok := beforeFields[key]; !ok || !reflect.DeepEqual(value, other) {
+			afterDiff[key] = value
+		}
+	}
+
+	for _, diff := range []map[string]interface{}{beforeDiff, afterDiff} {
+		for key := range diff {
+			if auditRedacted[strings.ToLower(key)] {
+				diff[key] = auditRedactedValue
+			}
+		}
+	}
+
+	return beforeDiff, afterDiff, nil
+}
+
+// auditFields flattens a resource to its json fields, values that are not objects are kept under "value".
+func auditFields(state interface{}) (map[string]interface{}, error) {
+	if state == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		var value interface{}
+		if err = json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"value": value}, nil
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,113 @@
+package usecase_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+func TestAuditDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     interface{}
+		after      interface{}
+		wantBefore map[string]interface{}
+		wantAfter  map[string]interface{}
+	}{
+		{
+			name:       "only changed fields",
+			before:     entity.User{ID: "1", FullName: "a", UserRole: "user"},
+			after:      entity.User{ID: "1", FullName: "a", UserRole: "admin"},
+			wantBefore: map[string]interface{}{"user_role": "user"},
+			wantAfter:  map[string]interface{}{"user_role": "admin"},
+		},
+		{
+			name:       "secrets are redacted",
+			before:     map[string]interface{}{"password": "old-hash"},
+			after:      map[string]interface{}{"password": "new-hash"},
+			wantBefore: map[string]interface{}{"password": "[redacted]"},
+			wantAfter:  map[string]interface{}{"password": "[redacted]"},
+		},
+		{
+			name:       "created",
+			before:     nil,
+			after:      map[string]interface{}{"slug": "go"},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{"slug": "go"},
+		},
+		{
+			name:       "deleted",
+			before:     map[string]interface{}{"slug": "go"},
+			after:      nil,
+			wantBefore: map[string]interface{}{"slug": "go"},
+			wantAfter:  map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after, err := usecase.AuditDiff(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(before, tt.wantBefore) {
+				t.Errorf("before = %v, want %v", before, tt.wantBefore)
+			}
+			if !reflect.DeepEqual(after, tt.wantAfter) {
+				t.Errorf("after = %v, want %v", after, tt.wantAfter)
+			}
+		})
+	}
+}
+
+type purgedUserRepo struct {
+	usecase.UserRepoI
+	users   []entity.User
+	deleted []string
+}
+
+func (r *purgedUserRepo) GetList(_ context.Context, _ entity.GetListFilter) (entity.UserList, error) {
+	return entity.UserList{Items: r.users}, nil
+}
+
+func (r *purgedUserRepo) Delete(_ context.Context, req entity.Id) error {
+	r.deleted = append(r.deleted, req.ID)
+	return nil
+}
+
+type auditLogRepo struct {
+	usecase.AuditLogRepoI
+	entries []entity.AuditLog
+}
+
+func (r *auditLogRepo) Create(_ context.Context, req entity.AuditLog) (entity.AuditLog, error) {
+	r.entries = append(r.entries, req)
+	return req, nil
+}
+
+// Jobs audit as the system, even when their context happens to carry the principal of a request.
+func TestPurgeAuditsAsSystem(t *testing.T) {
+	users := &purgedUserRepo{users: []entity.User{{ID: "u1", Username: "gone"}}}
+	audit := &auditLogRepo{}
+	uc := &usecase.UseCase{UserRepo: users, AuditLogRepo: audit}
+
+	ctx := entity.ContextWithPrincipal(context.Background(), entity.Principal{UserID: "admin-id", Role: "admin"})
+
+	n, err := uc.PurgeDeactivatedAccounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 || len(audit.entries) != 1 {
+		t.Fatalf("purged %d, audit entries = %v, want one of each", n, audit.entries)
+	}
+
+	entry := audit.entries[0]
+	if entry.ActorID != "" || entry.ActorRole != entity.SystemPrincipal.Role || entry.TargetID != "u1" {
+		t.Errorf("audit entry = %+v, want a purge of u1 by the system", entry)
+	}
+}
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// ErrForbidden is returned when the principal is not allowed to perform an operation.
+var ErrForbidden = errors.New("forbidden")
+
+// Policy decides whether a principal may perform an operation.
+// Owned policies depend on the owner of the resource, which is known only after it is loaded.
+type Policy struct {
+	Name  string
+	Owned bool
+	allow func(principal entity.Principal, ownerID string) bool
+}
+
+var (
+	Anyone = Policy{Name: "anyone", allow: func(entity.Principal, string) bool {
+		return true
+	}}
+	Authenticated = Policy{Name: "authenticated", allow: func(p entity.Principal, _ string) bool {
+		return p.IsAuthenticated()
+	}}
+	Admin = Policy{Name: "admin", allow: func(p entity.Principal, _ string) bool {
+		return p.IsAdmin()
+	}}
+	SuperAdmin = Policy{Name: "superadmin", allow: func(p entity.Principal, _ string) bool {
+		return p.IsAuthenticated() && p.Role == "superadmin"
+	}}
+	Owner = Policy{Name: "owner", Owned: true, allow: func(p entity.Principal, ownerID string) bool {
+		return p.IsAuthenticated() && p.UserID == ownerID
+	}}
+	OwnerOrAdmin = Policy{Name: "owner or admin", Owned: true, allow: func(p entity.Principal, ownerID string) bool {
+		return p.IsAuthenticated() && (p.UserID == ownerID || p.IsAdmin())
+	}}
+)
+
+// Operation is a single action of the api, every route maps to one.
+type Operation string
+
+const (
+	OpUserCreate          Operation = "user.create"
+	OpUserList            Operation = "user.list"
+	OpUserGet             Operation = "user.get"
+	OpUserUpdate          Operation = "user.update"
+	OpUserManage          Operation = "user.manage"
+	OpUserGrantSuperAdmin Operation = "user.grant_superadmin"
+	OpUserDelete          Operation = "user.delete"
+	OpUserModerate        Operation = "user.moderate"
+	OpUserModerateAdmin   Operation = "user.moderate_admin"
+	OpUserModerationList  Operation = "user.moderation.list"
+	OpUserReport          Operation = "user.report"
+
+	OpSessionList   Operation = "session.list"
+	OpSessionGet    Operation = "session.get"
+	OpSessionUpdate Operation = "session.update"
+	OpSessionDelete Operation = "session.delete"
+
+	OpMeSessionList         Operation = "me.session.list"
+	OpMeSessionRevokeOthers Operation = "me.session.revoke_others"
+	OpMeTokenCreate         Operation = "me.token.create"
+	OpMeTokenList           Operation = "me.token.list"
+	OpMeTokenDelete         Operation = "me.token.delete"
+	OpMeDelete              Operation = "me.delete"
+	OpMeExport              Operation = "me.export"
+	OpMeEmailChange         Operation = "me.email.change"
+	OpMeEmailConfirm        Operation = "me.email.confirm"
+	OpMeDraftList           Operation = "me.draft.list"
+	OpMeScheduledList       Operation = "me.scheduled.list"
+
+	OpAuthLogin              Operation = "auth.login"
+	OpAuthLogout             Operation = "auth.logout"
+	OpAuthRegister           Operation = "auth.register"
+	OpAuthVerifyEmail        Operation = "auth.verify_email"
+	OpAuthOAuth              Operation = "auth.oauth"
+	OpAuthCancelDeletion     Operation = "auth.cancel_deletion"
+	OpAuthResendVerification Operation = "auth.resend_verification"
+
+	OpTagCreate Operation = "tag.create"
+	OpTagList   Operation = "tag.list"
+	OpTagGet    Operation = "tag.get"
+	OpTagUpdate Operation = "tag.update"
+	OpTagDelete Operation = "tag.delete"
+
+	OpFollowerUpsert Operation = "follower.upsert"
+	OpFollowerList   Operation = "follower.list"
+
+	OpTweetCreate  Operation = "tweet.create"
+	OpTweetList    Operation = "tweet.list"
+	OpTweetGet     Operation = "tweet.get"
+	OpTweetUpdate  Operation = "tweet.update"
+	OpTweetDelete  Operation = "tweet.delete"
+	OpTweetReport  Operation = "tweet.report"
+	OpTweetHistory Operation = "tweet.history"
+	OpTweetPublish Operation = "tweet.publish"
+	OpTweetVote    Operation = "tweet.vote"
+
+	OpReportList    Operation = "report.list"
+	OpReportResolve Operation = "report.resolve"
+
+	OpMediaUpload Operation = "media.upload"
+	OpMediaChunk  Operation = "media.chunk"
+	OpMediaGet    Operation = "media.get"
+	OpMediaServe  Operation = "media.serve"
+
+	OpRbacManage Operation = "rbac.manage"
+	OpRbacCheck  Operation = "rbac.check"
+
+	OpAuditList Operation = "audit.list"
+)
+
+// Policies declares who may perform each operation, operations missing here are denied.
+var Policies = map[Operation]Policy{
+	OpUserCreate:          Admin,
+	OpUserList:            Admin,
+	OpUserGet:             Authenticated,
+	OpUserUpdate:          OwnerOrAdmin,
+	OpUserManage:          Admin,
+	OpUserGrantSuperAdmin: SuperAdmin,
+	OpUserDelete:          OwnerOrAdmin,
+	OpUserModerate:        Admin,
+	OpUserModerateAdmin:   SuperAdmin,
+	OpUserModerationList:  Admin,
+	OpUserReport:          Authenticated,
+
+	OpSessionList:   OwnerOrAdmin,
+	OpSessionGet:    OwnerOrAdmin,
+	OpSessionUpdate: OwnerOrAdmin,
+	OpSessionDelete: OwnerOrAdmin,
+
+	OpMeSessionList:         Authenticated,
+	OpMeSessionRevokeOthers: Authenticated,
+	OpMeTokenCreate:         Authenticated,
+	OpMeTokenList:           Authenticated,
+	OpMeTokenDelete:         Owner,
+	OpMeDelete:              Authenticated,
+	OpMeExport:              Authenticated,
+	OpMeEmailChange:         Authenticated,
+	OpMeEmailConfirm:        Authenticated,
+	OpMeDraftList:           Authenticated,
+	OpMeScheduledList:       Authenticated,
+
+	OpAuthLogin:              Anyone,
+	OpAuthLogout:             Authenticated,
+	OpAuthRegister:           Anyone,
+	OpAuthVerifyEmail:        Anyone,
+	OpAuthOAuth:              Anyone,
+	OpAuthCancelDeletion:     Anyone,
+	OpAuthResendVerification: Anyone,
+
+	OpTagCreate: Admin,
+	OpTagList:   Authenticated,
+	OpTagGet:    Authenticated,
+	OpTagUpdate: Admin,
+	OpTagDelete: Admin,
+
+	OpFollowerUpsert: OwnerOrAdmin,
+	OpFollowerList:   OwnerOrAdmin,
+
+	OpTweetCreate:  Authenticated,
+	OpTweetList:    Authenticated,
+	OpTweetGet:     Authenticated,
+	OpTweetUpdate:  OwnerOrAdmin,
+	OpTweetDelete:  OwnerOrAdmin,
+	OpTweetReport:  Authenticated,
+	OpTweetHistory: Authenticated,
+	OpTweetPublish: Owner,
+	OpTweetVote:    Authenticated,
+
+	OpReportList:    Admin,
+	OpReportResolve: Admin,
+
+	OpMediaUpload: Authenticated,
+	OpMediaChunk:  Owner,
+	OpMediaGet:    OwnerOrAdmin,
+	OpMediaServe:  Anyone, // signed urls are checked by the handler
+
+	OpRbacManage: SuperAdmin,
+	OpRbacCheck:  Admin,
+
+	OpAuditList: Admin,
+}
+
+// Authorize checks the policy of op for principal, ownerID is the owner of the resource for owned policies.
+func Authorize(principal entity.Principal, op Operation, ownerID string) error {
+	policy, ok := Policies[op]
+	if !ok {
+		return fmt.Errorf("usecase - Authorize: unknown operation %q: %w", op, ErrForbidden)
+	}
+
+	if !policy.allow(principal, ownerID) {
+		return ErrForbidden
+	}
+
+	return nil
+}
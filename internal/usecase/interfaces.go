@@ -3,7 +3,9 @@ package usecase
 
 import (
 	"context"
+	"time"
 
+	"github.com/casbin/casbin/persist"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
 )
 
@@ -28,6 +30,77 @@ type (
 		Update(ctx context.Context, req entity.Session) (entity.Session, error)
 		Delete(ctx context.Context, req entity.Id) error
 		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
+		Invalidate(ctx context.Context, ids ...string)
+	}
+
+	// Login attempt repo
+	LoginAttemptRepoI interface {
+		Create(ctx context.Context, req entity.LoginAttempt) (entity.LoginAttempt, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.LoginAttemptList, error)
+	}
+
+	// Identity repo
+	IdentityRepoI interface {
+		Create(ctx context.Context, req entity.Identity) (entity.Identity, error)
+		GetSingle(ctx context.Context, req entity.IdentitySingleRequest) (entity.Identity, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.IdentityList, error)
+		Delete(ctx context.Context, req entity.Id) error
+	}
+
+	// Api token repo
+	ApiTokenRepoI interface {
+		Create(ctx context.Context, req entity.ApiToken) (entity.ApiToken, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.ApiToken, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.ApiTokenList, error)
+		Delete(ctx context.Context, req entity.Id) error
+		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
+	}
+
+	// Audit log repo, entries are never updated
+	AuditLogRepoI interface {
+		Create(ctx context.Context, req entity.AuditLog) (entity.AuditLog, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.AuditLogList, error)
+		DeleteBefore(ctx context.Context, before time.Time) (entity.RowsEffected, error)
+	}
+
+	// User moderation repo, the history of suspensions, bans and restores
+	UserModerationRepoI interface {
+		Create(ctx context.Context, req entity.UserModeration) (entity.UserModeration, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.UserModerationList, error)
+		Moderate(ctx context.Context, req entity.UserModerationChange) (entity.UserModeration, []string, error)
+	}
+
+	// Report repo, the open reports make up the moderation queue
+	ReportRepoI interface {
+		Create(ctx context.Context, req entity.Report) (entity.Report, error)
+		GetQueue(ctx context.Context, req entity.GetListFilter) (entity.ReportQueue, error)
+		Resolve(ctx context.Context, req entity.ReportResolve) ([]entity.Report, []string, error)
+	}
+
+	// Data export repo
+	DataExportRepoI interface {
+		Create(ctx context.Context, req entity.DataExport) (entity.DataExport, error)
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.DataExportList, error)
+		GetArchive(ctx context.Context, req entity.Id) ([]byte, error)
+		Claim(ctx context.Context, staleAfter time.Duration) (entity.DataExport, error)
+		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
+		DeleteExpired(ctx context.Context) (entity.RowsEffected, error)
+	}
+
+	// Mail outbox repo, delivered messages are deleted
+	MailOutboxRepoI interface {
+		Create(ctx context.Context, req entity.MailOutbox) (entity.MailOutbox, error)
+		Claim(ctx context.Context, staleAfter time.Duration) (entity.MailOutbox, error)
+		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
+		Delete(ctx context.Context, req entity.Id) error
+	}
+
+	// Casbin rule repo, it is the casbin adapter as well
+	CasbinRuleRepoI interface {
+		persist.Adapter
+		Create(ctx context.Context, ptype string, rule []string) error
+		Delete(ctx context.Context, ptype string, rule []string) (entity.RowsEffected, error)
+		Seed(ctx context.Context, path string) error
 	}
 
 	// Tag Repo
@@ -55,6 +128,19 @@ type (
 		GetList(ctx context.Context, req entity.GetListFilter) (entity.UserList, error)
 	}
 
+	// Media repo, the bytes are in the media storage
+	MediaRepoI interface {
+		Create(ctx context.Context, req entity.Media) (entity.Media, error)
+		GetSingle(ctx context.Context, req entity.Id) (entity.Media, error)
+		ClaimVariants(ctx context.Context, kind string, staleAfter time.Duration) (entity.Media, error)
+		FailVariants(ctx context.Context, id, status, reason string) error
+		MarkOrphaned(ctx context.Context, unusedFor time.Duration) (int64, error)
+		ListOrphaned(ctx context.Context, orphanedFor time.Duration, offset, limit int) ([]entity.Media, error)
+		ClaimOrphaned(ctx context.Context, id string) (bool, error)
+		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
+		Delete(ctx context.Context, req entity.Id) error
+	}
+
 	// Tweet attachment
 	TweetAttachentRepoI interface {
 		Create(ctx context.Context, req entity.Attachment) (entity.Attachment, error)
@@ -69,8 +155,22 @@ type (
 		Create(ctx context.Context, req entity.Tweet) (entity.Tweet, error)
 		GetSingle(ctx context.Context, req entity.Id) (entity.Tweet, error)
 		GetList(ctx context.Context, req entity.GetListFilter) (entity.TweetList, error)
-		Update(ctx context.Context, req entity.Tweet) (entity.Tweet, error)
+		Update(ctx context.Context, req entity.Tweet, check func(stored entity.Tweet) error) (entity.Tweet, error)
+		ClaimScheduled(ctx context.Context) (entity.Tweet, error)
+		ClaimUntagged(ctx context.Context, retryAfter time.Duration) (entity.Tweet, error)
 		Delete(ctx context.Context, req entity.Id) error
 		UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error)
 	}
+
+	// Tweet revision repo, the contents of tweets replaced by edits
+	TweetRevisionRepoI interface {
+		GetList(ctx context.Context, req entity.GetListFilter) (entity.TweetRevisionList, error)
+	}
+
+	// Poll repo, the polls of tweets and their votes
+	PollRepoI interface {
+		GetByTweets(ctx context.Context, tweetIDs []string, userID string) (map[string]entity.Poll, error)
+		Vote(ctx context.Context, req entity.PollVote) (bool, error)
+		CloseDue(ctx context.Context) (string, error)
+	}
 )
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/mailer"
+	"github.com/jackc/pgx/v4"
+)
+
+// Mail templates, each has a <name>.txt and <name>.html in every locale of templates/mail.
+const (
+	MailVerifyEmail       = "verify_email"
+	MailLoginLockout      = "login_lockout"
+	MailEmailChangeOtp    = "email_change_otp"
+	MailEmailChangeNotice = "email_change_notice"
+	MailReportOutcome     = "report_outcome"
+)
+
+// mailStaleAfter is how long a message may stay sending before another replica retries it,
+// it has to be longer than the SMTP timeout.
+const mailStaleAfter = 5 * time.Minute
+
+// mailMaxBackoff caps the delay between two attempts of a message.
+const mailMaxBackoff = 6 * time.Hour
+
+//go:embed templates/mail
+var mailTemplates embed.FS
+
+// NewMailTemplates parses the embedded mail templates, fallback is the locale used when none of the reader's matches.
+func NewMailTemplates(fallback string) (*mailer.Templates, error) {
+	fsys, err := fs.Sub(mailTemplates, "templates/mail")
+	if err != nil {
+		return nil, err
+	}
+
+	return mailer.NewTemplates(fsys, fallback)
+}
+
+// SendMail renders a message in the language closest to acceptLanguage and queues it for ProcessMailOutbox.
+// Delivery happens in the background, so a failing mail server doesn't fail the request.
+func (u *UseCase) SendMail(ctx context.Context, to, template, acceptLanguage string, data interface{}) error {
+	msg, err := u.MailTemplates.Render(template, acceptLanguage, data)
+	if err != nil {
+		return fmt.Errorf("usecase - SendMail - Render: %w", err)
+	}
+
+	_, err = u.MailOutboxRepo.Create(ctx, entity.MailOutbox{
+		Recipient: to,
+		Template:  template,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HTMLBody:  msg.HTML,
+	})
+	if err != nil {
+		return fmt.Errorf("usecase - SendMail - MailOutboxRepo.Create: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessMailOutbox delivers the due messages until none is left. A failed delivery is retried with
+// exponential backoff starting at backoff, after maxAttempts the message is left failed.
+func (u *UseCase) ProcessMailOutbox(ctx context.Context, m mailer.Mailer, maxAttempts int, backoff time.Duration) error {
+	var (
+		failed  int
+		lastErr error
+	)
+
+	for ctx.Err() == nil {
+		msg, err := u.MailOutboxRepo.Claim(ctx, mailStaleAfter)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("usecase - ProcessMailOutbox - MailOutboxRepo.Claim: %w", err)
+		}
+
+		sendErr := m.Send(ctx, mailer.Message{
+			To:      []string{msg.Recipient},
+			Subject: msg.Subject,
+			Text:    msg.TextBody,
+			HTML:    msg.HTMLBody,
+		})
+		if sendErr == nil {
+			err = u.MailOutboxRepo.Delete(ctx, entity.Id{ID: msg.ID})
+			if err != nil {
+				return fmt.Errorf("usecase - ProcessMailOutbox - MailOutboxRepo.Delete: %w", err)
+			}
+			continue
+		}
+
+		failed++
+		lastErr = sendErr
+
+		status := "pending"
+		if msg.Attempts >= maxAttempts {
+			status = "failed"
+		}
+
+		_, err = u.MailOutboxRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+			Filter: []entity.Filter{{Column: "id", Type: "eq", Value: msg.ID}},
+			Items: []entity.UpdateFieldItem{
+				{Column: "status", Value: status},
+				{Column: "last_error", Value: sendErr.Error()},
+				{Column: "next_attempt_at", Value: time.Now().UTC().Add(MailRetryDelay(backoff, msg.Attempts))},
+				{Column: "updated_at", Value: time.Now().UTC()},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("usecase - ProcessMailOutbox - MailOutboxRepo.UpdateField: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("usecase - ProcessMailOutbox - %d deliveries failed, last: %w", failed, lastErr)
+	}
+
+	return ctx.Err()
+}
+
+// MailRetryDelay is the delay before the next attempt after attempts failed ones, it doubles every attempt.
+func MailRetryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < mailMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > mailMaxBackoff {
+		delay = mailMaxBackoff
+	}
+
+	return delay
+}
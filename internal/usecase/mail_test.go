@@ -0,0 +1,61 @@
+package usecase_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+// Every message has to render in every locale with the data its callers pass.
+func TestMailTemplates(t *testing.T) {
+	templates, err := usecase.NewMailTemplates("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string]map[string]interface{}{
+		usecase.MailVerifyEmail:       {"Code": "123456", "Minutes": 5},
+		usecase.MailLoginLockout:      {"Until": "2026-10-19T12:00:00Z"},
+		usecase.MailEmailChangeOtp:    {"Code": "123456", "Minutes": 15},
+		usecase.MailEmailChangeNotice: {"NewEmail": "new@example.com"},
+		usecase.MailReportOutcome:     {"Action": usecase.ReportHideTweet, "TargetType": "tweet", "Category": "spam", "ReportedAt": "2026-10-19T12:00:00Z"},
+	}
+
+	for _, locale := range []string{"en", "ru", "uz"} {
+		if got := templates.Match(locale); got != locale {
+			t.Fatalf("locale %s is missing, got %s", locale, got)
+		}
+
+		for name, data := range messages {
+			msg, err := templates.Render(name, locale, data)
+			if err != nil {
+				t.Errorf("%s/%s: %v", locale, name, err)
+				continue
+			}
+
+			if msg.Subject == "" || msg.Text == "" || !strings.Contains(msg.HTML, "<html>") {
+				t.Errorf("%s/%s rendered incompletely: %+v", locale, name, msg)
+			}
+		}
+	}
+}
+
+func TestMailRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{30, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := usecase.MailRetryDelay(30*time.Second, tt.attempts); got != tt.want {
+			t.Errorf("MailRetryDelay(30s, %d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
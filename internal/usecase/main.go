@@ -1,32 +1,74 @@
 package usecase
 
 import (
+	"fmt"
+
+	rediscache "github.com/golanguzb70/redis-cache"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/usecase/repo"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/mailer"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/storage"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 // UseCase -.
 type UseCase struct {
 	UserRepo             UserRepoI
 	SessionRepo          SessionRepoI
+	LoginAttemptRepo     LoginAttemptRepoI
+	IdentityRepo         IdentityRepoI
+	ApiTokenRepo         ApiTokenRepoI
+	CasbinRuleRepo       CasbinRuleRepoI
+	AuditLogRepo         AuditLogRepoI
+	DataExportRepo       DataExportRepoI
+	UserModerationRepo   UserModerationRepoI
+	ReportRepo           ReportRepoI
+	MailOutboxRepo       MailOutboxRepoI
 	TagRepo              TagRepoI
 	UserTagRepo          UserTagRepoI
 	FollowerRepo         FollowerRepoI
+	MediaRepo            MediaRepoI
 	TweetAttachmentsRepo TweetAttachentRepoI
 	TweetRepo            TweetI
+	TweetRevisionRepo    TweetRevisionRepoI
+	PollRepo             PollRepoI
+
+	MailTemplates *mailer.Templates
+	Storage       storage.Storage
 }
 
 // New -.
-func New(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *UseCase {
+func New(pg *postgres.Postgres, config *config.Config, logger *logger.Logger, redis rediscache.RedisCache, redisClient *goredis.Client,
+	store storage.Storage) *UseCase {
+	templates, err := NewMailTemplates(config.Mail.DefaultLocale)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("usecase - New - NewMailTemplates: %w", err))
+	}
+
 	return &UseCase{
 		UserRepo:             repo.NewUserRepo(pg, config, logger),
-		SessionRepo:          repo.NewSessionRepo(pg, config, logger),
+		SessionRepo:          repo.NewSessionCacheRepo(pg, config, logger, redis, redisClient),
+		LoginAttemptRepo:     repo.NewLoginAttemptRepo(pg, config, logger),
+		IdentityRepo:         repo.NewIdentityRepo(pg, config, logger),
+		ApiTokenRepo:         repo.NewApiTokenRepo(pg, config, logger),
+		CasbinRuleRepo:       repo.NewCasbinRuleRepo(pg, config, logger),
+		AuditLogRepo:         repo.NewAuditLogRepo(pg, config, logger),
+		DataExportRepo:       repo.NewDataExportRepo(pg, config, logger),
+		UserModerationRepo:   repo.NewUserModerationRepo(pg, config, logger),
+		ReportRepo:           repo.NewReportRepo(pg, config, logger),
+		MailOutboxRepo:       repo.NewMailOutboxRepo(pg, config, logger),
 		TagRepo:              repo.NewTagRepo(pg, config, logger),
 		UserTagRepo:          repo.NewUserTagRepo(pg, config, logger),
 		FollowerRepo:         repo.NewFollowerRepo(pg, config, logger),
+		MediaRepo:            repo.NewMediaRepo(pg, config, logger),
 		TweetAttachmentsRepo: repo.NewAttachmentRepo(pg, config, logger),
 		TweetRepo:            repo.NewTweetRepo(pg, config, logger),
+		TweetRevisionRepo:    repo.NewTweetRevisionRepo(pg, config, logger),
+		PollRepo:             repo.NewPollRepo(pg, config, logger),
+
+		MailTemplates: templates,
+		Storage:       store,
 	}
 }
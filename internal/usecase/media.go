@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// MediaTypes are the content types media can be uploaded as, mapped to the kind of attachment they make.
+var MediaTypes = map[string]string{
+	"image/jpeg": "photo",
+	"image/png":  "photo",
+	"image/gif":  "photo",
+	"image/webp": "photo",
+	"video/mp4":  "video",
+	"video/webm": "video",
+}
+
+const (
+	// MaxTweetPhotos is how many photos a tweet holds, a tweet with a video holds it alone.
+	MaxTweetPhotos = 4
+	// MaxAltTextLength is the longest alt text of an attachment, in characters.
+	MaxAltTextLength = 1000
+)
+
+var (
+	// ErrAttachmentLimit is returned for a tweet with too many attachments or photos and a video together.
+	ErrAttachmentLimit = errors.New("a tweet holds at most 4 photos or 1 video")
+	// ErrAttachmentDuplicate is returned for a tweet attaching the same media twice.
+	ErrAttachmentDuplicate = errors.New("the same media is attached twice")
+	// ErrAltTextLength is returned for an alt text longer than MaxAltTextLength.
+	ErrAltTextLength = errors.New("alt text is longer than 1000 characters")
+
+	// ErrMediaType is returned for a content type that can't be uploaded.
+	ErrMediaType = errors.New("unsupported media type")
+	// ErrMediaMismatch is returned when the content doesn't match the declared content type.
+	ErrMediaMismatch = errors.New("content doesn't match the declared content type")
+)
+
+// MediaSniffLength is how much of the content CheckMediaContent needs.
+const MediaSniffLength = 512
+
+// MediaContentType validates a declared content type and returns it without parameters.
+func MediaContentType(declared string) (string, error) {
+	contentType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return "", ErrMediaType
+	}
+
+	if _, ok := MediaTypes[contentType]; !ok {
+		return "", ErrMediaType
+	}
+
+	return contentType, nil
+}
+
+// MediaMaxSize returns the size limit of a content type, animated gifs get more room than other images.
+func MediaMaxSize(cfg config.Media, contentType string) int64 {
+	switch {
+	case contentType == "image/gif":
+		return cfg.MaxGifSize
+	case MediaTypes[contentType] == "video":
+		return cfg.MaxVideoSize
+	}
+
+	return cfg.MaxImageSize
+}
+
+// CheckMediaContent sniffs the first bytes of the content, so a file can't pass as another type than it is.
+func CheckMediaContent(contentType string, head []byte) error {
+	if http.DetectContentType(head) != contentType {
+		return ErrMediaMismatch
+	}
+
+	return nil
+}
+
+// CheckAttachments validates the attachments of a tweet, their content types have to be filled in from the media.
+func CheckAttachments(attachments []entity.Attachment) error {
+	var (
+		photos, videos int
+		media          = map[string]bool{}
+	)
+
+	for _, attachment := range attachments {
+		if utf8.RuneCountInString(attachment.AltText) > MaxAltTextLength {
+			return ErrAltTextLength
+		}
+
+		if attachment.MediaId != "" {
+			if media[attachment.MediaId] {
+				return ErrAttachmentDuplicate
+			}
+			media[attachment.MediaId] = true
+		}
+
+		if attachment.ContentType == "video" {
+			videos++
+		} else {
+			photos++
+		}
+	}
+
+	if photos > MaxTweetPhotos || videos > 1 || videos == 1 && photos > 0 {
+		return ErrAttachmentLimit
+	}
+
+	return nil
+}
+
+// MediaChunkKey is the storage key of the n-th chunk of a chunked upload.
+func MediaChunkKey(mediaID string, n int) string {
+	return fmt.Sprintf("uploads/%s/%d", mediaID, n)
+}
+
+// AssembleMedia joins the chunks of a chunked upload into the object of the media.
+// The chunks are kept, so a failed assembly can be retried, DeleteMediaChunks removes them.
+func (u *UseCase) AssembleMedia(ctx context.Context, media entity.Media) error {
+	body := &chunkReader{ctx: ctx, u: u, media: media}
+	defer body.Close()
+
+	err := u.Storage.Put(ctx, media.StorageKey, body, media.Size, media.ContentType)
+	if err != nil {
+		return fmt.Errorf("usecase - AssembleMedia - Storage.Put: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMediaChunks deletes the chunks of a chunked upload, one that fails to be deleted is left behind.
+func (u *UseCase) DeleteMediaChunks(ctx context.Context, media entity.Media) {
+	for n := 0; n < media.Chunks; n++ {
+		_ = u.Storage.Delete(ctx, MediaChunkKey(media.ID, n))
+	}
+}
+
+// chunkReader reads the chunks of an upload one after another, a chunk is opened only once the previous one is read.
+type chunkReader struct {
+	ctx     context.Context
+	u       *UseCase
+	media   entity.Media
+	next    int
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next == r.media.Chunks {
+				return 0, io.EOF
+			}
+
+			chunk, err := r.u.Storage.Get(r.ctx, MediaChunkKey(r.media.ID, r.next))
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = chunk
+			r.next++
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			err = nil
+
+			if n == 0 {
+				continue
+			}
+		}
+
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	mediaGCDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_gc_deleted_total",
+		Help: "Number of orphaned media deleted by the media gc, by mode. Nothing is deleted in dry_run mode.",
+	}, []string{"mode"})
+
+	mediaGCReclaimedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_gc_reclaimed_bytes_total",
+		Help: "Bytes of storage reclaimed from orphaned media by the media gc, by mode. Nothing is deleted in dry_run mode.",
+	}, []string{"mode"})
+)
+
+// mediaGCBatch is how many orphaned media the media gc lists at a time.
+const mediaGCBatch = 100
+
+// MediaGCResult is what a run of the media gc did, or would have done in a dry run.
+type MediaGCResult struct {
+	Orphaned int64 // media newly marked as orphaned
+	Deleted  int
+	Bytes    int64
+}
+
+// CollectMediaGarbage marks the media that no tweet attaches and no user has as avatar, and that were untouched
+// for the grace period, as orphaned. The media that stayed orphaned for another grace period are deleted with
+// their original, variants, renditions and upload chunks. A dry run only counts what would be deleted.
+func (u *UseCase) CollectMediaGarbage(ctx context.Context, grace time.Duration, dryRun bool) (MediaGCResult, error) {
+	var (
+		result  MediaGCResult
+		failed  int
+		lastErr error
+		err     error
+	)
+
+	mode := "delete"
+	if dryRun {
+		mode = "dry_run"
+	}
+
+	result.Orphaned, err = u.MediaRepo.MarkOrphaned(ctx, grace)
+	if err != nil {
+		return result, fmt.Errorf("usecase - CollectMediaGarbage - MediaRepo.MarkOrphaned: %w", err)
+	}
+
+	// deleted media leave the listing, the ones skipped stay in it and are stepped over
+	offset := 0
+	for ctx.Err() == nil {
+		orphans, err := u.MediaRepo.ListOrphaned(ctx, grace, offset, mediaGCBatch)
+		if err != nil {
+			return result, fmt.Errorf("usecase - CollectMediaGarbage - MediaRepo.ListOrphaned: %w", err)
+		}
+
+		for _, media := range orphans {
+			if dryRun {
+				offset++
+			} else {
+				deleted, err := u.deleteOrphanedMedia(ctx, media)
+				if err != nil {
+					offset++
+					failed++
+					lastErr = fmt.Errorf("media %s: %w", media.ID, err)
+					continue
+				}
+				if !deleted {
+					offset++
+					continue
+				}
+			}
+
+			bytes := MediaStoredBytes(media)
+			result.Deleted++
+			result.Bytes += bytes
+			mediaGCDeleted.WithLabelValues(mode).Inc()
+			mediaGCReclaimedBytes.WithLabelValues(mode).Add(float64(bytes))
+		}
+
+		if len(orphans) < mediaGCBatch {
+			break
+		}
+	}
+
+	if failed > 0 {
+		return result, fmt.Errorf("usecase - CollectMediaGarbage - %d media failed, last: %w", failed, lastErr)
+	}
+
+	return result, ctx.Err()
+}
+
+// MediaStoredBytes is how much storage a media takes: its original, or the chunks received of an unfinished
+// upload, and its variants and renditions.
+func MediaStoredBytes(media entity.Media) int64 {
+	if media.Status == "uploading" {
+		return media.Received
+	}
+
+	bytes := media.Size
+	for name, variant := range media.Variants {
+		// the original variant is the media itself
+		if name != "original" {
+			bytes += variant.Size
+		}
+	}
+
+	return bytes
+}
+
+// deleteOrphanedMedia claims an orphaned media and deletes its files and then its row, false when it was
+// referenced or is being processed meanwhile. A media whose files fail to be deleted stays claimed and is tried
+// again on the next run.
+func (u *UseCase) deleteOrphanedMedia(ctx context.Context, media entity.Media) (bool, error) {
+	if media.Status != "deleting" {
+		claimed, err := u.MediaRepo.ClaimOrphaned(ctx, media.ID)
+		if err != nil || !claimed {
+			return false, err
+		}
+	}
+
+	err := u.Storage.Delete(ctx, media.StorageKey)
+	if err != nil {
+		return false, err
+	}
+
+	dirs := []string{
+		path.Dir(MediaChunkKey(media.ID, 0)),
+		path.Dir(MediaVariantKey(media.ID, "original")),
+		path.Dir(MediaHLSKey(media.ID, "master.m3u8")),
+	}
+	for _, dir := range dirs {
+		err = u.Storage.DeleteDir(ctx, dir)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = u.MediaRepo.Delete(ctx, entity.Id{ID: media.ID})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,451 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/imaging"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/video"
+	"github.com/jackc/pgx/v4"
+)
+
+// MediaVariantSizes are the variants the image pipeline makes of a photo, by the square they fit in.
+var MediaVariantSizes = []struct {
+	Name string
+	Size int
+}{
+	{Name: "thumbnail", Size: 150},
+	{Name: "medium", Size: 600},
+	{Name: "large", Size: 1200},
+}
+
+// MediaPosterSize is the square the poster frame of a video fits in.
+const MediaPosterSize = 1200
+
+const (
+	// photoProcessStaleAfter is how long a photo can be processing before another replica takes it over.
+	photoProcessStaleAfter = 10 * time.Minute
+	// videoProcessStaleAfter is added to the transcode timeout before another replica takes a video over.
+	videoProcessStaleAfter = 10 * time.Minute
+)
+
+// hlsContentTypes are the content types of the files of an HLS rendition.
+var hlsContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+}
+
+// mediaRejected is returned by processing for media breaking the limits, it isn't tried again.
+type mediaRejected struct {
+	reason string
+}
+
+func (e mediaRejected) Error() string {
+	return e.reason
+}
+
+// MediaVariantsStatus is the variants status a media starts with once it's ready, photos and videos are processed.
+func MediaVariantsStatus(kind string) string {
+	if kind == "photo" || kind == "video" {
+		return "pending"
+	}
+
+	return "none"
+}
+
+// MediaVariantKey is the storage key of a variant of a photo or the poster of a video.
+func MediaVariantKey(mediaID, name string) string {
+	return fmt.Sprintf("variants/%s/%s.webp", mediaID, name)
+}
+
+// MediaHLSKey is the storage key of a file of the HLS renditions of a video, relative to the master playlist.
+func MediaHLSKey(mediaID, name string) string {
+	return fmt.Sprintf("hls/%s/%s", mediaID, name)
+}
+
+// ProcessMediaVariants makes the variants of the photos waiting for them until none is left.
+func (u *UseCase) ProcessMediaVariants(ctx context.Context, encoder *imaging.WebPEncoder, cfg config.Media) error {
+	return u.processMedia(ctx, "photo", photoProcessStaleAfter, cfg.ProcessAttempts, func(ctx context.Context, media entity.Media) (entity.Media, error) {
+		return u.makeMediaVariants(ctx, encoder, media, cfg.MaxImagePixels)
+	})
+}
+
+// ProcessVideos probes the videos waiting for processing until none is left, rejects the ones over the limits
+// and makes the poster frame and HLS renditions of the others.
+func (u *UseCase) ProcessVideos(ctx context.Context, transcoder *video.FFmpeg, encoder *imaging.WebPEncoder, mediaCfg config.Media, videoCfg config.Video) error {
+	return u.processMedia(ctx, "video", videoCfg.Timeout+videoProcessStaleAfter, mediaCfg.ProcessAttempts, func(ctx context.Context, media entity.Media) (entity.Media, error) {
+		ctx, cancel := context.WithTimeout(ctx, videoCfg.Timeout)
+		defer cancel()
+
+		return u.makeVideoVariants(ctx, transcoder, encoder, media, mediaCfg, videoCfg)
+	})
+}
+
+// processMedia runs process on the media of the kind waiting for it until none is left. Media that fails is
+// tried again on the next run, after maxAttempts or when it's rejected it's left failed or rejected, and a
+// video is detached from the tweets it was attached to meanwhile.
+func (u *UseCase) processMedia(ctx context.Context, kind string, staleAfter time.Duration, maxAttempts int,
+	process func(ctx context.Context, media entity.Media) (entity.Media, error)) error {
+	var (
+		failed  int
+		lastErr error
+	)
+
+	for ctx.Err() == nil {
+		media, err := u.MediaRepo.ClaimVariants(ctx, kind, staleAfter)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("usecase - processMedia - MediaRepo.ClaimVariants: %w", err)
+		}
+
+		processed, processErr := process(ctx, media)
+		if processErr == nil {
+			variants, err := entity.MarshalMediaVariants(processed.Variants)
+			if err != nil {
+				return fmt.Errorf("usecase - processMedia - entity.MarshalMediaVariants: %w", err)
+			}
+
+			items := []entity.UpdateFieldItem{
+				{Column: "size", Value: processed.Size},
+				{Column: "received", Value: processed.Size},
+				{Column: "width", Value: processed.Width},
+				{Column: "height", Value: processed.Height},
+				{Column: "blurhash", Value: processed.Blurhash},
+				{Column: "variants", Value: string(variants)},
+				{Column: "variants_status", Value: processed.VariantsStatus},
+				{Column: "variants_error", Value: ""},
+				{Column: "updated_at", Value: time.Now().UTC()},
+			}
+			if kind == "video" {
+				items = append(items, entity.UpdateFieldItem{Column: "duration", Value: processed.Duration})
+			}
+
+			_, err = u.MediaRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+				Filter: []entity.Filter{{Column: "id", Type: "eq", Value: media.ID}},
+				Items:  items,
+			})
+			if err != nil {
+				return fmt.Errorf("usecase - processMedia - MediaRepo.UpdateField: %w", err)
+			}
+			continue
+		}
+
+		status := "pending"
+		var rejected mediaRejected
+		switch {
+		case errors.As(processErr, &rejected):
+			status = "rejected"
+		case media.VariantsAttempts >= maxAttempts:
+			status = "failed"
+		}
+
+		if status != "rejected" {
+			failed++
+			lastErr = fmt.Errorf("media %s: %w", media.ID, processErr)
+		}
+
+		// a cancelled run isn't the media's fault, the next run picks it up as stale
+		if ctx.Err() != nil {
+			break
+		}
+
+		if status != "pending" {
+			err = u.MediaRepo.FailVariants(ctx, media.ID, status, processErr.Error())
+			if err != nil {
+				return fmt.Errorf("usecase - processMedia - MediaRepo.FailVariants: %w", err)
+			}
+			continue
+		}
+
+		_, err = u.MediaRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+			Filter: []entity.Filter{{Column: "id", Type: "eq", Value: media.ID}},
+			Items: []entity.UpdateFieldItem{
+				{Column: "variants_status", Value: status},
+				{Column: "variants_error", Value: processErr.Error()},
+				{Column: "updated_at", Value: time.Now().UTC()},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("usecase - processMedia - MediaRepo.UpdateField: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("usecase - processMedia - %d %ss failed, last: %w", failed, kind, lastErr)
+	}
+
+	return ctx.Err()
+}
+
+// makeMediaVariants strips the metadata of a photo, replacing its original, and stores its variants as WebP.
+// Variants are turned upright by the exif orientation and never scaled up, a size that would repeat the one
+// before it is left out. Variants of an animated gif are stills of its first frame. Animated WebP photos can't
+// be decoded, they are only stripped and their variants skipped.
+func (u *UseCase) makeMediaVariants(ctx context.Context, encoder *imaging.WebPEncoder, media entity.Media, maxPixels int) (entity.Media, error) {
+	object, err := u.Storage.Get(ctx, media.StorageKey)
+	if err != nil {
+		return entity.Media{}, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(io.LimitReader(object, media.Size+1))
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	stripped, err := imaging.StripMetadata(data, media.ContentType)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	if !bytes.Equal(stripped, data) {
+		err = u.Storage.Put(ctx, media.StorageKey, bytes.NewReader(stripped), int64(len(stripped)), media.ContentType)
+		if err != nil {
+			return entity.Media{}, err
+		}
+	}
+
+	media.Size = int64(len(stripped))
+	media.Variants = map[string]entity.MediaVariant{
+		"original": {FilePath: media.StorageKey, ContentType: media.ContentType, Size: media.Size},
+	}
+
+	if media.ContentType == "image/webp" && imaging.WebPAnimated(stripped) {
+		media.VariantsStatus = "skipped"
+		return media, nil
+	}
+
+	img, _, err := imaging.Decode(stripped, maxPixels)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return entity.Media{}, mediaRejected{reason: fmt.Sprintf("photo has more than %d pixels", maxPixels)}
+	}
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	if media.ContentType == "image/jpeg" {
+		img = imaging.Orient(img, imaging.JPEGOrientation(stripped))
+	}
+
+	media.Width, media.Height = img.Rect.Dx(), img.Rect.Dy()
+	media.Variants["original"] = entity.MediaVariant{
+		FilePath:    media.StorageKey,
+		ContentType: media.ContentType,
+		Width:       media.Width,
+		Height:      media.Height,
+		Size:        media.Size,
+	}
+
+	var smallest *image.RGBA
+	previous := image.Point{}
+
+	for _, size := range MediaVariantSizes {
+		width, height := imaging.Fit(media.Width, media.Height, size.Size)
+		if (image.Point{X: width, Y: height}) == previous {
+			continue
+		}
+		previous = image.Point{X: width, Y: height}
+
+		variant, resized, err := u.storeWebPVariant(ctx, encoder, media.ID, size.Name, img, width, height)
+		if err != nil {
+			return entity.Media{}, err
+		}
+
+		if smallest == nil {
+			smallest = resized
+		}
+		media.Variants[size.Name] = variant
+	}
+
+	media.Blurhash = imaging.Blurhash(smallest)
+	media.VariantsStatus = "ready"
+
+	return media, nil
+}
+
+// makeVideoVariants probes a video, rejects it when it's over the limits and stores its poster frame as WebP
+// and its HLS renditions next to each other under MediaHLSKey.
+func (u *UseCase) makeVideoVariants(ctx context.Context, transcoder *video.FFmpeg, encoder *imaging.WebPEncoder, media entity.Media,
+	mediaCfg config.Media, videoCfg config.Video) (entity.Media, error) {
+	dir, err := os.MkdirTemp("", "video-*")
+	if err != nil {
+		return entity.Media{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	original := filepath.Join(dir, "original")
+	err = u.download(ctx, media.StorageKey, original)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	info, err := transcoder.Probe(ctx, original)
+	if errors.Is(err, video.ErrNoVideo) {
+		return entity.Media{}, mediaRejected{reason: "file has no video stream"}
+	}
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	if info.Duration > videoCfg.MaxDuration {
+		return entity.Media{}, mediaRejected{reason: fmt.Sprintf("video is longer than %s", videoCfg.MaxDuration)}
+	}
+	if max(info.Width, info.Height) > videoCfg.MaxDimension {
+		return entity.Media{}, mediaRejected{reason: fmt.Sprintf("video is larger than %dx%d", videoCfg.MaxDimension, videoCfg.MaxDimension)}
+	}
+
+	media.Width, media.Height = info.Width, info.Height
+	media.Duration = info.Duration.Seconds()
+	media.Variants = map[string]entity.MediaVariant{
+		"original": {FilePath: media.StorageKey, ContentType: media.ContentType, Width: media.Width, Height: media.Height, Size: media.Size},
+	}
+
+	// the poster is a frame a second in, or halfway through a shorter video
+	poster := filepath.Join(dir, "poster.png")
+	err = transcoder.Poster(ctx, original, min(time.Second, info.Duration/2), poster)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	frame, err := os.ReadFile(poster)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	img, _, err := imaging.Decode(frame, mediaCfg.MaxImagePixels)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	width, height := imaging.Fit(img.Rect.Dx(), img.Rect.Dy(), MediaPosterSize)
+	variant, resized, err := u.storeWebPVariant(ctx, encoder, media.ID, "poster", img, width, height)
+	if err != nil {
+		return entity.Media{}, err
+	}
+	media.Variants["poster"] = variant
+	media.Blurhash = imaging.Blurhash(resized)
+
+	hls := filepath.Join(dir, "hls")
+	renditions := video.LadderFor(info.Width, info.Height)
+	err = transcoder.HLS(ctx, original, info, renditions, videoCfg.SegmentDuration, hls)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	size, err := u.uploadDir(ctx, hls, func(name string) string { return MediaHLSKey(media.ID, name) })
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	width, height = renditions[0].Size(info.Width, info.Height)
+	media.Variants["hls"] = entity.MediaVariant{
+		FilePath:    MediaHLSKey(media.ID, "master.m3u8"),
+		ContentType: hlsContentTypes[".m3u8"],
+		Width:       width,
+		Height:      height,
+		Size:        size,
+	}
+	media.VariantsStatus = "ready"
+
+	return media, nil
+}
+
+// storeWebPVariant resizes img and stores it as the named WebP variant of a media.
+func (u *UseCase) storeWebPVariant(ctx context.Context, encoder *imaging.WebPEncoder, mediaID, name string, img *image.RGBA,
+	width, height int) (entity.MediaVariant, *image.RGBA, error) {
+	resized := imaging.Resize(img, width, height)
+
+	var buf bytes.Buffer
+	err := encoder.Encode(ctx, &buf, resized)
+	if err != nil {
+		return entity.MediaVariant{}, nil, err
+	}
+
+	key := MediaVariantKey(mediaID, name)
+	err = u.Storage.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/webp")
+	if err != nil {
+		return entity.MediaVariant{}, nil, err
+	}
+
+	return entity.MediaVariant{
+		FilePath:    key,
+		ContentType: "image/webp",
+		Width:       width,
+		Height:      height,
+		Size:        int64(buf.Len()),
+	}, resized, nil
+}
+
+// download copies the object under key to a local file.
+func (u *UseCase) download(ctx context.Context, key, file string) error {
+	object, err := u.Storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, object)
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// uploadDir stores every file under dir, at the key keyOf returns for its slash separated path relative to dir,
+// and returns their total size.
+func (u *UseCase) uploadDir(ctx context.Context, dir string, keyOf func(name string) string) (int64, error) {
+	var total int64
+
+	err := filepath.WalkDir(dir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		err = u.Storage.Put(ctx, keyOf(name), f, info.Size(), hlsContentTypes[path.Ext(name)])
+		if err != nil {
+			return err
+		}
+
+		total += info.Size()
+
+		return nil
+	})
+
+	return total, err
+}
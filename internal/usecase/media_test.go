@@ -0,0 +1,200 @@
+package usecase_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+func TestMediaContentType(t *testing.T) {
+	tests := []struct {
+		declared string
+		want     string
+		wantErr  error
+	}{
+		{declared: "image/png", want: "image/png"},
+		{declared: "video/mp4; codecs=avc1", want: "video/mp4"},
+		{declared: "image/svg+xml", wantErr: usecase.ErrMediaType},
+		{declared: "text/html", wantErr: usecase.ErrMediaType},
+		{declared: "", wantErr: usecase.ErrMediaType},
+	}
+
+	for _, tt := range tests {
+		got, err := usecase.MediaContentType(tt.declared)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("MediaContentType(%q) = %q, %v, want %q, %v", tt.declared, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMediaMaxSize(t *testing.T) {
+	cfg := config.Media{MaxImageSize: 1, MaxGifSize: 2, MaxVideoSize: 3}
+
+	for contentType, want := range map[string]int64{"image/png": 1, "image/gif": 2, "video/webm": 3} {
+		if got := usecase.MediaMaxSize(cfg, contentType); got != want {
+			t.Errorf("MediaMaxSize(%q) = %d, want %d", contentType, got, want)
+		}
+	}
+}
+
+func TestCheckMediaContent(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	html := []byte("<html><script>alert(1)</script></html>")
+
+	if err := usecase.CheckMediaContent("image/png", png); err != nil {
+		t.Errorf("png as image/png: %v", err)
+	}
+	if err := usecase.CheckMediaContent("image/jpeg", png); !errors.Is(err, usecase.ErrMediaMismatch) {
+		t.Errorf("png as image/jpeg: %v, want ErrMediaMismatch", err)
+	}
+	if err := usecase.CheckMediaContent("image/png", html); !errors.Is(err, usecase.ErrMediaMismatch) {
+		t.Errorf("html as image/png: %v, want ErrMediaMismatch", err)
+	}
+}
+
+func TestMediaSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expires := usecase.MediaURLExpiry(time.Hour, now)
+
+	if expires.Before(now.Add(time.Hour)) {
+		t.Errorf("MediaURLExpiry = %v, want at least an hour from %v", expires, now)
+	}
+	if got := usecase.MediaURLExpiry(time.Hour, now.Add(time.Second)); !got.Equal(expires) {
+		t.Errorf("urls made a second apart expire at %v and %v, want the same", expires, got)
+	}
+
+	link, err := url.Parse(usecase.MediaURL("secret", "m1", "large", expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := link.Query()
+	if link.Path != "/v1/media/m1" || query.Get("variant") != "large" {
+		t.Errorf("MediaURL = %s", link)
+	}
+
+	tests := []struct {
+		name                     string
+		secret, mediaID, expires string
+		variant, file            string
+		unsigned                 bool
+		now                      time.Time
+		wantErr                  error
+	}{
+		{name: "valid", secret: "secret", mediaID: "m1", variant: "large", now: now},
+		{name: "other media", secret: "secret", mediaID: "m2", variant: "large", now: now, wantErr: usecase.ErrMediaURL},
+		{name: "other secret", secret: "other", mediaID: "m1", variant: "large", now: now, wantErr: usecase.ErrMediaURL},
+		{name: "other variant", secret: "secret", mediaID: "m1", variant: "thumbnail", now: now, wantErr: usecase.ErrMediaURL},
+		{name: "original", secret: "secret", mediaID: "m1", now: now, wantErr: usecase.ErrMediaURL},
+		{name: "hls file", secret: "secret", mediaID: "m1", variant: "large", file: "720p/000.ts", now: now, wantErr: usecase.ErrMediaURL},
+		{name: "expired", secret: "secret", mediaID: "m1", variant: "large", now: expires.Add(time.Second), wantErr: usecase.ErrMediaURL},
+		{name: "extended", secret: "secret", mediaID: "m1", variant: "large", expires: "9999999999", now: now, wantErr: usecase.ErrMediaURL},
+		{name: "unsigned", secret: "secret", mediaID: "m1", variant: "large", unsigned: true, now: now, wantErr: usecase.ErrMediaURL},
+	}
+
+	for _, tt := range tests {
+		expiresParam, sign := query.Get("expires"), query.Get("signature")
+		if tt.expires != "" {
+			expiresParam = tt.expires
+		}
+		if tt.unsigned {
+			sign = ""
+		}
+
+		err := usecase.CheckMediaSignature(tt.secret, tt.mediaID, tt.variant, tt.file, expiresParam, sign, tt.now)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CheckMediaSignature = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSignPlaylist(t *testing.T) {
+	expires := time.Unix(1700000000, 0)
+	playlist := "#EXTM3U\n#EXTINF:6.0,\n000.ts\n#EXT-X-ENDLIST\n"
+
+	signed := string(usecase.SignPlaylist([]byte(playlist), "secret", "m1", "720p", expires))
+
+	lines := strings.Split(signed, "\n")
+	if lines[0] != "#EXTM3U" || lines[1] != "#EXTINF:6.0," || lines[3] != "#EXT-X-ENDLIST" {
+		t.Fatalf("tags changed:\n%s", signed)
+	}
+
+	segment, err := url.Parse(lines[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := segment.Query()
+	if segment.Path != "m1" || query.Get("variant") != "hls" || query.Get("file") != "720p/000.ts" {
+		t.Errorf("segment uri = %s", lines[2])
+	}
+	if err := usecase.CheckMediaSignature("secret", "m1", "hls", "720p/000.ts", query.Get("expires"), query.Get("signature"), expires); err != nil {
+		t.Errorf("segment uri isn't signed: %v", err)
+	}
+	if err := usecase.CheckMediaSignature("secret", "m1", "hls", "720p/001.ts", query.Get("expires"), query.Get("signature"), expires); err == nil {
+		t.Error("segment uri opens another segment")
+	}
+}
+
+func TestMediaHLSFile(t *testing.T) {
+	for file, want := range map[string]string{"": "master.m3u8", "720p/index.m3u8": "720p/index.m3u8", "720p/001.ts": "720p/001.ts"} {
+		if got, ok := usecase.MediaHLSFile(file); !ok || got != want {
+			t.Errorf("MediaHLSFile(%q) = %q, %v, want %q", file, got, ok, want)
+		}
+	}
+
+	for _, file := range []string{"../m2/master.m3u8", "/etc/passwd", "720p/../../x.ts", "720p/secret.txt", "."} {
+		if _, ok := usecase.MediaHLSFile(file); ok {
+			t.Errorf("MediaHLSFile(%q) is valid, want invalid", file)
+		}
+	}
+}
+
+func TestMediaStoredBytes(t *testing.T) {
+	photo := entity.Media{Status: "ready", Size: 1000, Variants: map[string]entity.MediaVariant{
+		"original":  {Size: 1000},
+		"thumbnail": {Size: 10},
+		"large":     {Size: 300},
+	}}
+	if got := usecase.MediaStoredBytes(photo); got != 1310 {
+		t.Errorf("MediaStoredBytes(photo) = %d, want 1310", got)
+	}
+
+	upload := entity.Media{Status: "uploading", Size: 1000, Received: 400}
+	if got := usecase.MediaStoredBytes(upload); got != 400 {
+		t.Errorf("MediaStoredBytes(upload) = %d, want 400", got)
+	}
+}
+
+func TestCheckAttachments(t *testing.T) {
+	photo := func(mediaID string) entity.Attachment {
+		return entity.Attachment{MediaId: mediaID, ContentType: "photo"}
+	}
+	video := entity.Attachment{MediaId: "v1", ContentType: "video"}
+
+	tests := []struct {
+		name        string
+		attachments []entity.Attachment
+		wantErr     error
+	}{
+		{name: "none"},
+		{name: "four photos", attachments: []entity.Attachment{photo("p1"), photo("p2"), photo("p3"), photo("p4")}},
+		{name: "a video", attachments: []entity.Attachment{video}},
+		{name: "five photos", attachments: []entity.Attachment{photo("p1"), photo("p2"), photo("p3"), photo("p4"), photo("p5")}, wantErr: usecase.ErrAttachmentLimit},
+		{name: "two videos", attachments: []entity.Attachment{video, {MediaId: "v2", ContentType: "video"}}, wantErr: usecase.ErrAttachmentLimit},
+		{name: "photo and video", attachments: []entity.Attachment{photo("p1"), video}, wantErr: usecase.ErrAttachmentLimit},
+		{name: "same media twice", attachments: []entity.Attachment{photo("p1"), photo("p1")}, wantErr: usecase.ErrAttachmentDuplicate},
+		{name: "long alt text", attachments: []entity.Attachment{{MediaId: "p1", ContentType: "photo", AltText: strings.Repeat("ж", 1001)}}, wantErr: usecase.ErrAltTextLength},
+		{name: "alt text at the limit", attachments: []entity.Attachment{{MediaId: "p1", ContentType: "photo", AltText: strings.Repeat("ж", 1000)}}},
+	}
+
+	for _, tt := range tests {
+		if err := usecase.CheckAttachments(tt.attachments); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CheckAttachments = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMediaURL is returned for a media url with a wrong or expired signature.
+var ErrMediaURL = errors.New("invalid or expired media url")
+
+// MediaURLExpiry is when the media urls made now expire: at least ttl later, rounded up so the urls made within
+// a quarter of ttl are the same and stay in the browser's cache.
+func MediaURLExpiry(ttl time.Duration, now time.Time) time.Time {
+	window := max(ttl/4, time.Second)
+
+	return now.Add(ttl).Truncate(window).Add(window)
+}
+
+// MediaSignature signs access to a file of a variant of a media until expires, a unix time. The original is the
+// empty variant, file is only set for the playlists and segments of the hls variant.
+func MediaSignature(secret, mediaID, variant, file string, expires int64) string {
+	if variant == "original" {
+		variant = ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d", mediaID, variant, file, expires)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckMediaSignature checks the expires and signature query parameters of a media url against the variant and
+// file it is for.
+func CheckMediaSignature(secret, mediaID, variant, file, expires, signature string, now time.Time) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > unix {
+		return ErrMediaURL
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(MediaSignature(secret, mediaID, variant, file, unix))) {
+		return ErrMediaURL
+	}
+
+	return nil
+}
+
+// MediaURL is the signed url of a variant of a media, the original for an empty variant.
+func MediaURL(secret, mediaID, variant string, expires time.Time) string {
+	query := mediaURLQuery(secret, mediaID, variant, "", expires)
+	if variant != "" && variant != "original" {
+		query.Set("variant", variant)
+	}
+
+	return "/v1/media/" + mediaID + "?" + query.Encode()
+}
+
+// SignPlaylist rewrites the uris of an HLS playlist of a media, relative to dir, into signed urls of the
+// files of its hls variant. The urls are relative so they resolve against the url the playlist is served from.
+func SignPlaylist(playlist []byte, secret, mediaID, dir string, expires time.Time) []byte {
+	var out bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line != "" && !strings.HasPrefix(line, "#") {
+			file := path.Join(dir, line)
+			query := mediaURLQuery(secret, mediaID, "hls", file, expires)
+			query.Set("variant", "hls")
+			query.Set("file", file)
+			line = mediaID + "?" + query.Encode()
+		}
+
+		out.WriteString(line + "\n")
+	}
+
+	return out.Bytes()
+}
+
+// MediaHLSFile validates the file query parameter of the hls variant of a media, a playlist or segment under
+// the directory of its master playlist.
+func MediaHLSFile(file string) (string, bool) {
+	if file == "" {
+		return "master.m3u8", true
+	}
+
+	if path.Clean(file) != file || strings.HasPrefix(file, "/") || strings.HasPrefix(file, "../") || file == ".." {
+		return "", false
+	}
+
+	if _, ok := hlsContentTypes[path.Ext(file)]; !ok {
+		return "", false
+	}
+
+	return file, true
+}
+
+// MediaHLSContentType is the content type of a file of the hls variant.
+func MediaHLSContentType(file string) string {
+	return hlsContentTypes[path.Ext(file)]
+}
+
+func mediaURLQuery(secret, mediaID, variant, file string, expires time.Time) url.Values {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", MediaSignature(secret, mediaID, variant, file, expires.Unix()))
+
+	return query
+}
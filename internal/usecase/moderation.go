@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// Moderation actions of the user_moderation history.
+const (
+	ModerationSuspend = "suspend"
+	ModerationBan     = "ban"
+	ModerationRestore = "restore"
+)
+
+// AuditSuspensionExpired is written when a suspended user is restored because the suspension is over.
+const AuditSuspensionExpired = "user.suspension_expired"
+
+// moderationPageSize is how many expired suspensions are restored per run.
+const moderationPageSize = 100
+
+// RestoreExpiredSuspensions restores the suspended users whose suspension is over and returns how many were restored.
+func (u *UseCase) RestoreExpiredSuspensions(ctx context.Context) (int, error) {
+	// suspended_until holds UTC, like every timestamp column
+	now := time.Now().UTC()
+
+	users, err := u.UserRepo.GetList(ctx, entity.GetListFilter{
+		Limit: moderationPageSize,
+		Filters: []entity.Filter{
+			{Column: "status", Type: "eq", Value: "suspended"},
+			{Column: "suspended_until", Type: "lte", Value: now.Format(time.RFC3339)},
+		},
+		OrderBy: []entity.OrderBy{{Column: "suspended_until", Order: "asc"}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("usecase - RestoreExpiredSuspensions - UserRepo.GetList: %w", err)
+	}
+
+	restored := 0
+	for _, user := range users.Items {
+		// a moderator may have changed the suspension meanwhile, and replicas run the job concurrently
+		rows, err := u.UserRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+			Filter: []entity.Filter{
+				{Column: "id", Type: "eq", Value: user.ID},
+				{Column: "status", Type: "eq", Value: "suspended"},
+				{Column: "suspended_until", Type: "lte", Value: now.Format(time.RFC3339)},
+			},
+			Items: []entity.UpdateFieldItem{
+				{Column: "status", Value: "active"},
+				{Column: "suspended_until", Value: nil},
+				{Column: "updated_at", Value: now},
+			},
+		})
+		if err != nil {
+			return restored, fmt.Errorf("usecase - RestoreExpiredSuspensions - UserRepo.UpdateField: %w", err)
+		}
+
+		if rows.RowsEffected == 0 {
+			continue
+		}
+		restored++
+
+		_, err = u.UserModerationRepo.Create(ctx, entity.UserModeration{
+			UserID: user.ID,
+			Action: ModerationRestore,
+			Reason: "suspension expired",
+		})
+		if err != nil {
+			return restored, fmt.Errorf("usecase - RestoreExpiredSuspensions - UserModerationRepo.Create: %w", err)
+		}
+
+		err = u.Audit(ctx, entity.SystemPrincipal, entity.AuditLog{
+			Action:     AuditSuspensionExpired,
+			TargetType: "user",
+			TargetID:   user.ID,
+			Before:     map[string]interface{}{"status": "suspended", "suspended_until": user.SuspendedUntil},
+			After:      map[string]interface{}{"status": "active"},
+		})
+		if err != nil {
+			return restored, err
+		}
+	}
+
+	return restored, nil
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/jackc/pgx/v4"
+)
+
+// Limits of the poll of a tweet.
+const (
+	MinPollOptions      = 2
+	MaxPollOptions      = 4
+	MaxPollOptionLength = 25
+	MinPollDuration     = 5           // minutes
+	MaxPollDuration     = 7 * 24 * 60 // minutes
+)
+
+var (
+	// ErrPollOptions is returned for a poll with too few or too many options.
+	ErrPollOptions = fmt.Errorf("poll needs %d to %d options", MinPollOptions, MaxPollOptions)
+	// ErrPollOption is returned for an empty, too long or repeated poll option.
+	ErrPollOption = fmt.Errorf("poll options must be distinct and 1 to %d characters", MaxPollOptionLength)
+	// ErrPollDuration is returned for a poll open for too short or too long.
+	ErrPollDuration = fmt.Errorf("poll duration must be %d to %d minutes", MinPollDuration, MaxPollDuration)
+)
+
+// CheckPoll checks the poll given with a new tweet and trims its options.
+func CheckPoll(poll *entity.Poll) error {
+	if len(poll.Options) < MinPollOptions || len(poll.Options) > MaxPollOptions {
+		return ErrPollOptions
+	}
+
+	seen := map[string]bool{}
+	for i := range poll.Options {
+		text := strings.TrimSpace(poll.Options[i].Text)
+		if text == "" || utf8.RuneCountInString(text) > MaxPollOptionLength || seen[strings.ToLower(text)] {
+			return ErrPollOption
+		}
+
+		seen[strings.ToLower(text)] = true
+		poll.Options[i] = entity.PollOption{Text: text}
+	}
+
+	if poll.DurationMinutes < MinPollDuration || poll.DurationMinutes > MaxPollDuration {
+		return ErrPollDuration
+	}
+
+	return nil
+}
+
+// HidePollResults drops the counts of a poll until the caller has voted or the poll has ended.
+func HidePollResults(poll *entity.Poll) {
+	if poll.Vote != "" || poll.Ended {
+		return
+	}
+
+	poll.TotalVotes = nil
+	for i := range poll.Options {
+		poll.Options[i].Votes = nil
+	}
+}
+
+// ClosePolls closes the polls that ended and freezes their results, it returns how many were closed.
+func (u *UseCase) ClosePolls(ctx context.Context) (int, error) {
+	closed := 0
+
+	for ctx.Err() == nil {
+		_, err := u.PollRepo.CloseDue(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return closed, fmt.Errorf("usecase - ClosePolls - PollRepo.CloseDue: %w", err)
+		}
+
+		closed++
+	}
+
+	return closed, ctx.Err()
+}
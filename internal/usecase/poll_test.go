@@ -0,0 +1,79 @@
+package usecase_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+)
+
+func TestCheckPoll(t *testing.T) {
+	options := func(texts ...string) []entity.PollOption {
+		out := []entity.PollOption{}
+		for _, text := range texts {
+			out = append(out, entity.PollOption{Id: "set-by-client", Text: text})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		poll    entity.Poll
+		wantErr error
+	}{
+		{name: "two options", poll: entity.Poll{Options: options("yes", "no"), DurationMinutes: 60}},
+		{name: "four options", poll: entity.Poll{Options: options("a", "b", "c", "d"), DurationMinutes: usecase.MaxPollDuration}},
+		{name: "one option", poll: entity.Poll{Options: options("yes"), DurationMinutes: 60}, wantErr: usecase.ErrPollOptions},
+		{name: "five options", poll: entity.Poll{Options: options("a", "b", "c", "d", "e"), DurationMinutes: 60}, wantErr: usecase.ErrPollOptions},
+		{name: "blank option", poll: entity.Poll{Options: options("yes", "  "), DurationMinutes: 60}, wantErr: usecase.ErrPollOption},
+		{name: "long option", poll: entity.Poll{Options: options("yes", strings.Repeat("a", 26)), DurationMinutes: 60}, wantErr: usecase.ErrPollOption},
+		{name: "repeated option", poll: entity.Poll{Options: options("Yes", " yes"), DurationMinutes: 60}, wantErr: usecase.ErrPollOption},
+		{name: "too short", poll: entity.Poll{Options: options("yes", "no"), DurationMinutes: 4}, wantErr: usecase.ErrPollDuration},
+		{name: "too long", poll: entity.Poll{Options: options("yes", "no"), DurationMinutes: usecase.MaxPollDuration + 1}, wantErr: usecase.ErrPollDuration},
+	}
+
+	for _, tt := range tests {
+		err := usecase.CheckPoll(&tt.poll)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CheckPoll() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	poll := entity.Poll{Options: options(" yes ", "no"), DurationMinutes: 60}
+	if err := usecase.CheckPoll(&poll); err != nil || poll.Options[0].Text != "yes" || poll.Options[0].Id != "" {
+		t.Errorf("CheckPoll() = %v, options %+v, want trimmed options without ids", err, poll.Options)
+	}
+}
+
+func TestHidePollResults(t *testing.T) {
+	poll := func(vote string, ended bool) entity.Poll {
+		total, votes := int64(3), int64(3)
+		return entity.Poll{
+			Options:    []entity.PollOption{{Id: "a", Votes: &votes}},
+			Ended:      ended,
+			TotalVotes: &total,
+			Vote:       vote,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		poll   entity.Poll
+		hidden bool
+	}{
+		{name: "not voted", poll: poll("", false), hidden: true},
+		{name: "voted", poll: poll("a", false)},
+		{name: "ended", poll: poll("", true)},
+	}
+
+	for _, tt := range tests {
+		usecase.HidePollResults(&tt.poll)
+		hidden := tt.poll.TotalVotes == nil && tt.poll.Options[0].Votes == nil
+		shown := tt.poll.TotalVotes != nil && tt.poll.Options[0].Votes != nil
+		if tt.hidden && !hidden || !tt.hidden && !shown {
+			t.Errorf("%s: HidePollResults() left total %v, option votes %v", tt.name, tt.poll.TotalVotes, tt.poll.Options[0].Votes)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type ApiTokenRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewApiTokenRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *ApiTokenRepo {
+	return &ApiTokenRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *ApiTokenRepo) Create(ctx context.Context, req entity.ApiToken) (entity.ApiToken, error) {
+	req.ID = uuid.NewString()
+	expireDate := sql.NullTime{}
+	expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+	if err == nil {
+		expireDate.Time = expiresAt
+		expireDate.Valid = true
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("api_token").
+		Columns(`id, user_id, name, lookup, token_hash, scopes, expires_at`).
+		Values(req.ID, req.UserID, req.Name, req.Lookup, req.TokenHash, req.Scopes, expireDate).ToSql()
+	if err != nil {
+		return entity.ApiToken{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.ApiToken{}, err
+	}
+
+	return req, nil
+}
+
+// GetSingle finds a token by id or, when Slug is set, by its lookup part.
+func (r *ApiTokenRepo) GetSingle(ctx context.Context, req entity.Id) (entity.ApiToken, error) {
+	response := entity.ApiToken{}
+	var (
+		createdAt, updatedAt  time.Time
+		lastUsedAt, expiresAt sql.NullTime
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, user_id, name, lookup, token_hash, scopes, last_used_at, expires_at, created_at, updated_at`).
+		From("api_token")
+
+	switch {
+	case req.ID != "":
+		qeuryBuilder = qeuryBuilder.Where("id = ?", req.ID)
+	case req.Slug != "":
+		qeuryBuilder = qeuryBuilder.Where("lookup = ?", req.Slug)
+	default:
+		return entity.ApiToken{}, fmt.Errorf("GetSingle - invalid request")
+	}
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return entity.ApiToken{}, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.ID, &response.UserID, &response.Name, &response.Lookup, &response.TokenHash, &response.Scopes,
+			&lastUsedAt, &expiresAt, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.ApiToken{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+	if lastUsedAt.Valid {
+		response.LastUsedAt = lastUsedAt.Time.Format(time.RFC3339)
+	}
+
+	if expiresAt.Valid {
+		response.ExpiresAt = expiresAt.Time.Format(time.RFC3339)
+	}
+
+	return response, nil
+}
+
+func (r *ApiTokenRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.ApiTokenList, error) {
+	var (
+		response = entity.ApiTokenList{}
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, user_id, name, lookup, scopes, last_used_at, expires_at, created_at, updated_at`).
+		From("api_token")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			createdAt, updatedAt  time.Time
+			lastUsedAt, expiresAt sql.NullTime
+			item                  entity.ApiToken
+		)
+		err = rows.Scan(&item.ID, &item.UserID, &item.Name, &item.Lookup, &item.Scopes,
+			&lastUsedAt, &expiresAt, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+		if lastUsedAt.Valid {
+			item.LastUsedAt = lastUsedAt.Time.Format(time.RFC3339)
+		}
+
+		if expiresAt.Valid {
+			item.ExpiresAt = expiresAt.Time.Format(time.RFC3339)
+		}
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("api_token").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (r *ApiTokenRepo) Delete(ctx context.Context, req entity.Id) error {
+	qeury, args, err := r.pg.Builder.Delete("api_token").Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ApiTokenRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	mp := map[string]interface{}{}
+	response := entity.RowsEffected{}
+
+	for _, item := range req.Items {
+		mp[item.Column] = item.Value
+	}
+
+	qeury, args, err := r.pg.Builder.Update("api_token").SetMap(mp).Where(PrepareFilter(req.Filter)).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+
+	response.RowsEffected = int(n.RowsAffected())
+
+	return response, nil
+}
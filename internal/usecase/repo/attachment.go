@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -11,8 +12,22 @@ import (
 	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 )
 
+// attachmentMediaColumns are the blurhash, variants and status of the media of an attachment.
+var attachmentMediaColumns = `COALESCE((SELECT blurhash FROM media WHERE media.id = tweet_attachment.media_id), ''),
+	COALESCE((SELECT variants FROM media WHERE media.id = tweet_attachment.media_id), '{}'),
+	COALESCE((SELECT ` + attachmentStatus("media") + ` FROM media WHERE media.id = tweet_attachment.media_id), 'ready')`
+
+// attachmentStatus is the status of an attachment by its media under the alias: a video is processing until
+// its renditions are made and failed when they can't be, everything else is ready as soon as it's attached.
+func attachmentStatus(alias string) string {
+	return fmt.Sprintf(`CASE WHEN %[1]s.kind = 'video' AND %[1]s.variants_status IN ('pending', 'processing') THEN 'processing'
+		WHEN %[1]s.kind = 'video' AND %[1]s.variants_status IN ('failed', 'rejected') THEN 'failed'
+		ELSE 'ready' END`, alias)
+}
+
 type AttachmentRepo struct {
 	pg     *postgres.Postgres
 	config *config.Config
@@ -32,8 +47,8 @@ func (r *AttachmentRepo) Create(ctx context.Context, req entity.Attachment) (ent
 	req.Id = uuid.NewString()
 
 	qeury, args, err := r.pg.Builder.Insert("tweet_attachment").
-		Columns(`id, tweet_id, filepath, content_type`).
-		Values(req.Id, req.TweetId, req.FilePath, req.ContentType).ToSql()
+		Columns(`id, tweet_id, media_id, filepath, content_type, position, alt_text`).
+		Values(req.Id, req.TweetId, sql.NullString{String: req.MediaId, Valid: req.MediaId != ""}, req.FilePath, req.ContentType, req.Position, req.AltText).ToSql()
 	if err != nil {
 		return entity.Attachment{}, err
 	}
@@ -46,87 +61,22 @@ func (r *AttachmentRepo) Create(ctx context.Context, req entity.Attachment) (ent
 	return req, nil
 }
 
+// MultipleUpsert makes req.Attachments the attachments of the tweet in the order they are listed, in one
+// transaction: listed attachments with an id are moved to their position and get their alt text updated, the
+// ones without are inserted and the ones not listed are deleted. pgx.ErrNoRows is returned for an id that isn't
+// an attachment of the tweet.
 func (r *AttachmentRepo) MultipleUpsert(ctx context.Context, req entity.AttachmentMultipleInsertRequest) ([]entity.Attachment, error) {
-	hasNewAttachment := false
-
 	tx, err := r.pg.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	insertQuery := r.pg.Builder.Insert("tweet_attachment").
-		Columns(`id, tweet_id, filepath, content_type`)
-
-	for i, attachment := range req.Attachments {
-		if attachment.Id == "" {
-			hasNewAttachment = true
-
-			attachment.Id = uuid.NewString()
-			req.Attachments[i].Id = attachment.Id
-			insertQuery = insertQuery.Values(attachment.Id, req.TweetId, attachment.FilePath, attachment.ContentType)
-		}
-	}
-
-	existingAttachments := make(map[string]bool)
-	for _, attachment := range req.Attachments {
-		if attachment.Id != "" {
-			existingAttachments[attachment.Id] = true
-		}
-	}
-
-	if hasNewAttachment {
-		query, args, err := insertQuery.ToSql()
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = tx.Exec(ctx, query, args...)
-		if err != nil {
-			r.logger.Error("error while inserting tweet_attachment", err)
-			return nil, err
-		}
-	}
-
-	query, args, err := r.pg.Builder.Select("id").From("tweet_attachment").
-		Where(squirrel.Eq{"tweet_id": req.TweetId}).ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := tx.Query(ctx, query, args...)
+	err = upsertAttachments(ctx, tx, r.pg.Builder, req.TweetId, req.Attachments)
 	if err != nil {
-		r.logger.Error("error while getting ids of tweet_attachment", err)
+		r.logger.Error("error while upserting tweet_attachment", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	deletedAttachmentIds := []string{}
-
-	for rows.Next() {
-		var id string
-		err = rows.Scan(&id)
-		if err != nil {
-			return nil, err
-		}
-
-		if !existingAttachments[id] {
-			deletedAttachmentIds = append(deletedAttachmentIds, id)
-		}
-	}
-
-	for _, e := range deletedAttachmentIds {
-		query, args, err := r.pg.Builder.Delete("tweet_attachment").Where("id = ?", e).ToSql()
-		if err != nil {
-			return nil, err
-		}
-
-		_, err = tx.Exec(ctx, query, args...)
-		if err != nil {
-			r.logger.Error("error while deleting tweet_attachment", err)
-			return nil, err
-		}
-	}
 
 	err = tx.Commit(ctx)
 	if err != nil {
@@ -136,7 +86,7 @@ func (r *AttachmentRepo) MultipleUpsert(ctx context.Context, req entity.Attachme
 
 	attachments, err := r.GetList(ctx, entity.GetListFilter{
 		Page:  1,
-		Limit: 10,
+		Limit: len(req.Attachments),
 		Filters: []entity.Filter{
 			{
 				Column: "tweet_id",
@@ -156,11 +106,12 @@ func (r *AttachmentRepo) MultipleUpsert(ctx context.Context, req entity.Attachme
 func (r *AttachmentRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Attachment, error) {
 	response := entity.Attachment{}
 	var (
+		variants             []byte
 		createdAt, updatedAt time.Time
 	)
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, tweet_id, filepath, content_type, created_at, updated_at`).
+		Select(`id, tweet_id, COALESCE(media_id::text, ''), filepath, content_type, position, alt_text, ` + attachmentMediaColumns + `, created_at, updated_at`).
 		From("tweet_attachment")
 
 	switch {
@@ -176,7 +127,12 @@ func (r *AttachmentRepo) GetSingle(ctx context.Context, req entity.Id) (entity.A
 	}
 
 	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
-		Scan(&response.Id, &response.TweetId, &response.FilePath, &response.ContentType, &createdAt, &updatedAt)
+		Scan(&response.Id, &response.TweetId, &response.MediaId, &response.FilePath, &response.ContentType, &response.Position, &response.AltText, &response.Blurhash, &variants, &response.Status, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Attachment{}, err
+	}
+
+	response.Variants, err = entity.UnmarshalMediaVariants(variants)
 	if err != nil {
 		return entity.Attachment{}, err
 	}
@@ -194,10 +150,11 @@ func (r *AttachmentRepo) GetList(ctx context.Context, req entity.GetListFilter)
 	)
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, tweet_id, filepath, content_type, created_at, updated_at`).
+		Select(`id, tweet_id, COALESCE(media_id::text, ''), filepath, content_type, position, alt_text, ` + attachmentMediaColumns + `, created_at, updated_at`).
 		From("tweet_attachment")
 
 	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+	qeuryBuilder = qeuryBuilder.OrderBy("position", "created_at")
 
 	qeury, args, err := qeuryBuilder.ToSql()
 	if err != nil {
@@ -211,8 +168,16 @@ func (r *AttachmentRepo) GetList(ctx context.Context, req entity.GetListFilter)
 	defer rows.Close()
 
 	for rows.Next() {
-		var item entity.Attachment
-		err = rows.Scan(&item.Id, &item.TweetId, &item.FilePath, &item.ContentType, &createdAt, &updatedAt)
+		var (
+			item     entity.Attachment
+			variants []byte
+		)
+		err = rows.Scan(&item.Id, &item.TweetId, &item.MediaId, &item.FilePath, &item.ContentType, &item.Position, &item.AltText, &item.Blurhash, &variants, &item.Status, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.Variants, err = entity.UnmarshalMediaVariants(variants)
 		if err != nil {
 			return response, err
 		}
@@ -249,3 +214,85 @@ func (r *AttachmentRepo) Delete(ctx context.Context, req entity.Id) error {
 
 	return nil
 }
+
+// upsertAttachments makes attachments the attachments of the tweet within tx, see MultipleUpsert.
+func upsertAttachments(ctx context.Context, tx pgx.Tx, builder squirrel.StatementBuilderType, tweetID string, attachments []entity.Attachment) error {
+	kept := []string{}
+	for _, attachment := range attachments {
+		if attachment.Id != "" {
+			kept = append(kept, attachment.Id)
+		}
+	}
+
+	query, args, err := builder.Delete("tweet_attachment").
+		Where(squirrel.Eq{"tweet_id": tweetID}).
+		Where(squirrel.NotEq{"id": kept}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	// positions are unique per tweet once the transaction commits, so attachments can swap them
+	for i, attachment := range attachments {
+		if attachment.Id == "" {
+			err = lockAttachedMedia(ctx, tx, attachment.MediaId)
+			if err != nil {
+				return err
+			}
+
+			query, args, err = builder.Insert("tweet_attachment").
+				Columns(`id, tweet_id, media_id, filepath, content_type, position, alt_text`).
+				Values(uuid.NewString(), tweetID, sql.NullString{String: attachment.MediaId, Valid: attachment.MediaId != ""},
+					attachment.FilePath, attachment.ContentType, i, attachment.AltText).ToSql()
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		query, args, err = builder.Update("tweet_attachment").
+			SetMap(map[string]interface{}{"position": i, "alt_text": attachment.AltText, "updated_at": time.Now().UTC()}).
+			Where(squirrel.Eq{"id": attachment.Id, "tweet_id": tweetID}).ToSql()
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+	}
+
+	return nil
+}
+
+// lockAttachedMedia locks a media being attached within tx and unmarks it orphaned, pgx.ErrNoRows is returned
+// when it isn't ready, e.g. claimed by the garbage collector. Updating the row rather than only locking it makes a
+// MediaRepo.ClaimOrphaned waiting for tx check the media again and leave it.
+func lockAttachedMedia(ctx context.Context, tx pgx.Tx, mediaID string) error {
+	if mediaID == "" {
+		return nil
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE media SET orphaned_at = NULL WHERE id = $1 AND status = 'ready'`, mediaID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
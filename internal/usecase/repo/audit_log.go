@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type AuditLogRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewAuditLogRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *AuditLogRepo {
+	return &AuditLogRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *AuditLogRepo) Create(ctx context.Context, req entity.AuditLog) (entity.AuditLog, error) {
+	req.ID = uuid.NewString()
+	actorID := sql.NullString{String: req.ActorID, Valid: req.ActorID != ""}
+
+	before, err := auditJSON(req.Before)
+	if err != nil {
+		return entity.AuditLog{}, err
+	}
+
+	after, err := auditJSON(req.After)
+	if err != nil {
+		return entity.AuditLog{}, err
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("audit_log").
+		Columns(`id, actor_id, actor_role, action, method, path, target_type, target_id, before, after, status, reason, ip_address, user_agent`).
+		Values(req.ID, actorID, req.ActorRole, req.Action, req.Method, req.Path, req.TargetType, req.TargetID,
+			before, after, req.Status, req.Reason, req.IPAddress, req.UserAgent).ToSql()
+	if err != nil {
+		return entity.AuditLog{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.AuditLog{}, err
+	}
+
+	return req, nil
+}
+
+func (r *AuditLogRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.AuditLogList, error) {
+	var (
+		response  = entity.AuditLogList{Items: []entity.AuditLog{}}
+		createdAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, actor_id, actor_role, action, method, path, target_type, target_id, before, after, status, reason, ip_address, user_agent, created_at`).
+		From("audit_log")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item          entity.AuditLog
+			actorID       sql.NullString
+			before, after []byte
+		)
+		err = rows.Scan(&item.ID, &actorID, &item.ActorRole, &item.Action, &item.Method, &item.Path,
+			&item.TargetType, &item.TargetID, &before, &after, &item.Status, &item.Reason,
+			&item.IPAddress, &item.UserAgent, &createdAt)
+		if err != nil {
+			return response, err
+		}
+
+		if len(before) > 0 {
+			if err = json.Unmarshal(before, &item.Before); err != nil {
+				return response, err
+			}
+		}
+		if len(after) > 0 {
+			if err = json.Unmarshal(after, &item.After); err != nil {
+				return response, err
+			}
+		}
+
+		item.ActorID = actorID.String
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("audit_log").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// DeleteBefore purges the entries older than the retention period.
+func (r *AuditLogRepo) DeleteBefore(ctx context.Context, before time.Time) (entity.RowsEffected, error) {
+	qeury, args, err := r.pg.Builder.Delete("audit_log").Where("created_at < ?", before).ToSql()
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	result, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	return entity.RowsEffected{RowsEffected: int(result.RowsAffected())}, nil
+}
+
+// auditJSON keeps an empty side of a change NULL instead of storing an empty object.
+func auditJSON(fields map[string]interface{}) ([]byte, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(fields)
+}
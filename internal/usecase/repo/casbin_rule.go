@@ -0,0 +1,273 @@
+package repo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/casbin/casbin/model"
+	"github.com/casbin/casbin/persist"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+)
+
+// casbinRuleColumns are the value columns of a rule, casbin rules have at most 6 fields.
+var casbinRuleColumns = []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+
+// CasbinRuleRepo stores casbin policies in postgres, it is also the casbin adapter.
+type CasbinRuleRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+var _ persist.Adapter = (*CasbinRuleRepo)(nil)
+
+// New -.
+func NewCasbinRuleRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *CasbinRuleRepo {
+	return &CasbinRuleRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *CasbinRuleRepo) Create(ctx context.Context, ptype string, rule []string) error {
+	qeury, args, err := r.insertQuery(ptype, rule).Suffix("ON CONFLICT DO NOTHING").ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CasbinRuleRepo) Delete(ctx context.Context, ptype string, rule []string) (entity.RowsEffected, error) {
+	where := squirrel.Eq{"ptype": ptype}
+	for i, column := range casbinRuleColumns {
+		where[column] = ""
+		if i < len(rule) {
+			where[column] = rule[i]
+		}
+	}
+
+	qeury, args, err := r.pg.Builder.Delete("casbin_rule").Where(where).ToSql()
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	result, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	return entity.RowsEffected{RowsEffected: int(result.RowsAffected())}, nil
+}
+
+// Seed fills an empty casbin_rule table with the rules of a casbin csv policy file.
+// Seeding is idempotent, replicas starting at the same time insert the same rules.
+// A table that has rules is left alone so rules removed by admins stay removed, rules added
+// to the policy file later reach seeded tables through a migration.
+func (r *CasbinRuleRepo) Seed(ctx context.Context, path string) error {
+	var count int
+
+	err := r.pg.Pool.QueryRow(ctx, "SELECT COUNT(1) FROM casbin_rule").Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tokens := strings.Split(line, ",")
+		for i := range tokens {
+			tokens[i] = strings.TrimSpace(tokens[i])
+		}
+
+		if len(tokens) < 2 || len(tokens)-1 > len(casbinRuleColumns) {
+			return fmt.Errorf("invalid policy line %q", line)
+		}
+
+		qeury, args, err := r.insertQuery(tokens[0], tokens[1:]).Suffix("ON CONFLICT DO NOTHING").ToSql()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, qeury, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LoadPolicy loads all rules into the casbin model.
+func (r *CasbinRuleRepo) LoadPolicy(model model.Model) error {
+	ctx := context.Background()
+
+	qeury, args, err := r.pg.Builder.
+		Select("ptype, v0, v1, v2, v3, v4, v5").
+		From("casbin_rule").OrderBy("id").ToSql()
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rules := map[string][][]string{}
+	for rows.Next() {
+		var (
+			ptype  string
+			values = make([]string, len(casbinRuleColumns))
+		)
+
+		err = rows.Scan(&ptype, &values[0], &values[1], &values[2], &values[3], &values[4], &values[5])
+		if err != nil {
+			return err
+		}
+
+		// trailing empty values are not part of the rule
+		for len(values) > 0 && values[len(values)-1] == "" {
+			values = values[:len(values)-1]
+		}
+
+		rules[ptype] = append(rules[ptype], values)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// the model is touched only after every row was read successfully
+	for ptype, items := range rules {
+		if ptype == "" {
+			continue
+		}
+
+		assertion, ok := model[ptype[:1]][ptype]
+		if !ok {
+			r.logger.Warn(fmt.Sprintf("casbin_rule has rules of unknown type %q", ptype))
+			continue
+		}
+
+		assertion.Policy = append(assertion.Policy, items...)
+	}
+
+	return nil
+}
+
+// SavePolicy replaces all stored rules with the rules of the model.
+func (r *CasbinRuleRepo) SavePolicy(model model.Model) error {
+	ctx := context.Background()
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM casbin_rule")
+	if err != nil {
+		return err
+	}
+
+	for _, sec := range []string{"p", "g"} {
+		for ptype, assertion := range model[sec] {
+			for _, rule := range assertion.Policy {
+				qeury, args, err := r.insertQuery(ptype, rule).ToSql()
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(ctx, qeury, args...)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// AddPolicy -.
+func (r *CasbinRuleRepo) AddPolicy(sec string, ptype string, rule []string) error {
+	return r.Create(context.Background(), ptype, rule)
+}
+
+// RemovePolicy -.
+func (r *CasbinRuleRepo) RemovePolicy(sec string, ptype string, rule []string) error {
+	_, err := r.Delete(context.Background(), ptype, rule)
+	return err
+}
+
+// RemoveFilteredPolicy removes the rules whose fields starting at fieldIndex match fieldValues, empty values match anything.
+func (r *CasbinRuleRepo) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	where := squirrel.Eq{"ptype": ptype}
+	for i, value := range fieldValues {
+		if value != "" && fieldIndex+i < len(casbinRuleColumns) {
+			where[casbinRuleColumns[fieldIndex+i]] = value
+		}
+	}
+
+	qeury, args, err := r.pg.Builder.Delete("casbin_rule").Where(where).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(context.Background(), qeury, args...)
+	return err
+}
+
+func (r *CasbinRuleRepo) insertQuery(ptype string, rule []string) squirrel.InsertBuilder {
+	values := []interface{}{ptype}
+	for i := range casbinRuleColumns {
+		value := ""
+		if i < len(rule) {
+			value = rule[i]
+		}
+		values = append(values, value)
+	}
+
+	return r.pg.Builder.Insert("casbin_rule").
+		Columns("ptype, v0, v1, v2, v3, v4, v5").
+		Values(values...)
+}
@@ -0,0 +1,189 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+// DataExportRepo stores the archives of user data exports, the archive is only read by GetArchive.
+type DataExportRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewDataExportRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *DataExportRepo {
+	return &DataExportRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *DataExportRepo) Create(ctx context.Context, req entity.DataExport) (entity.DataExport, error) {
+	req.ID = uuid.NewString()
+	req.Status = "pending"
+
+	qeury, args, err := r.pg.Builder.Insert("data_export").
+		Columns(`id, user_id, status`).
+		Values(req.ID, req.UserID, req.Status).ToSql()
+	if err != nil {
+		return entity.DataExport{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.DataExport{}, err
+	}
+
+	return req, nil
+}
+
+func (r *DataExportRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.DataExportList, error) {
+	var (
+		response             = entity.DataExportList{}
+		createdAt, updatedAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, user_id, status, error, expires_at, created_at, updated_at`).
+		From("data_export")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item      entity.DataExport
+			expiresAt sql.NullTime
+		)
+		err = rows.Scan(&item.ID, &item.UserID, &item.Status, &item.Error, &expiresAt, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		if expiresAt.Valid {
+			item.ExpiresAt = expiresAt.Time.Format(time.RFC3339)
+		}
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("data_export").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (r *DataExportRepo) GetArchive(ctx context.Context, req entity.Id) ([]byte, error) {
+	var archive []byte
+
+	qeury, args, err := r.pg.Builder.Select("archive").From("data_export").
+		Where("id = ? AND status = 'ready'", req.ID).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).Scan(&archive)
+	if err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// Claim marks the oldest pending export as processing and returns it, pgx.ErrNoRows when there is none.
+// Exports left processing for longer than staleAfter by a replica that died are claimed again.
+// Replicas never claim the same export, the row is locked with SKIP LOCKED.
+func (r *DataExportRepo) Claim(ctx context.Context, staleAfter time.Duration) (entity.DataExport, error) {
+	var (
+		response             entity.DataExport
+		createdAt, updatedAt time.Time
+	)
+
+	qeury := `UPDATE data_export SET status = 'processing', updated_at = now()
+		WHERE id = (
+			SELECT id FROM data_export
+			WHERE status = 'pending' OR (status = 'processing' AND updated_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, status, created_at, updated_at`
+
+	err := r.pg.Pool.QueryRow(ctx, qeury, time.Now().UTC().Add(-staleAfter)).
+		Scan(&response.ID, &response.UserID, &response.Status, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.DataExport{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *DataExportRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	mp := map[string]interface{}{}
+	response := entity.RowsEffected{}
+
+	for _, item := range req.Items {
+		mp[item.Column] = item.Value
+	}
+
+	qeury, args, err := r.pg.Builder.Update("data_export").SetMap(mp).Where(PrepareFilter(req.Filter)).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+
+	response.RowsEffected = int(n.RowsAffected())
+
+	return response, nil
+}
+
+// DeleteExpired removes exports whose archive is past its expiry.
+func (r *DataExportRepo) DeleteExpired(ctx context.Context) (entity.RowsEffected, error) {
+	qeury, args, err := r.pg.Builder.Delete("data_export").Where("expires_at < now()").ToSql()
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	result, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	return entity.RowsEffected{RowsEffected: int(result.RowsAffected())}, nil
+}
@@ -75,29 +75,44 @@ func (r *FollowerRepo) GetList(ctx context.Context, req entity.GetListFilter) (e
 		createdAt, updatedAt time.Time
 	)
 
-	followingId := ""
+	followingId, followerId := "", ""
 
 	for i := 0; i < len(req.Filters); i++ {
-		if req.Filters[i].Column == "following_id" {
+		switch req.Filters[i].Column {
+		case "following_id":
 			followingId = req.Filters[i].Value
-			req.Filters = append(req.Filters[:i], req.Filters[i+1:]...)
-			i--
+		case "follower_id":
+			followerId = req.Filters[i].Value
+		default:
+			continue
 		}
+		req.Filters = append(req.Filters[:i], req.Filters[i+1:]...)
+		i--
 	}
 
-	if followingId == "" {
-		return response, fmt.Errorf("%sfollowing_id is required", "BAD_REQUEST")
-	} else {
+	// following_id lists the followers of a user, follower_id the users a user follows
+	join := "users as u ON u.id=f.follower_id"
+	switch {
+	case followingId != "":
 		req.Filters = append(req.Filters, entity.Filter{
 			Column: "f.following_id",
 			Type:   "eq",
 			Value:  followingId,
 		})
+	case followerId != "":
+		join = "users as u ON u.id=f.following_id"
+		req.Filters = append(req.Filters, entity.Filter{
+			Column: "f.follower_id",
+			Type:   "eq",
+			Value:  followerId,
+		})
+	default:
+		return response, fmt.Errorf("%sfollowing_id or follower_id is required", "BAD_REQUEST")
 	}
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, full_name, email, username, user_type, user_role, status, avatar_id, gender, created_at, updated_at`).
-		From("follower f").Join("users as u ON u.id=f.follower_id")
+		Select(`u.id, u.full_name, u.email, u.username, u.user_type, u.user_role, u.status, u.avatar_id, u.gender, u.created_at, u.updated_at`).
+		From("follower f").Join(join)
 
 	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
 
@@ -126,7 +141,7 @@ func (r *FollowerRepo) GetList(ctx context.Context, req entity.GetListFilter) (e
 		response.Items = append(response.Items, item)
 	}
 
-	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("follower").Where(where).ToSql()
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("follower f").Join(join).Where(where).ToSql()
 	if err != nil {
 		return response, err
 	}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type IdentityRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewIdentityRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *IdentityRepo {
+	return &IdentityRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *IdentityRepo) Create(ctx context.Context, req entity.Identity) (entity.Identity, error) {
+	req.ID = uuid.NewString()
+
+	qeury, args, err := r.pg.Builder.Insert("identity").
+		Columns(`id, user_id, provider, subject, email`).
+		Values(req.ID, req.UserID, req.Provider, req.Subject, req.Email).ToSql()
+	if err != nil {
+		return entity.Identity{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Identity{}, err
+	}
+
+	return req, nil
+}
+
+func (r *IdentityRepo) GetSingle(ctx context.Context, req entity.IdentitySingleRequest) (entity.Identity, error) {
+	response := entity.Identity{}
+	var (
+		createdAt, updatedAt time.Time
+	)
+
+	qeury, args, err := r.pg.Builder.
+		Select(`id, user_id, provider, subject, email, created_at, updated_at`).
+		From("identity").Where("provider = ? AND subject = ?", req.Provider, req.Subject).ToSql()
+	if err != nil {
+		return entity.Identity{}, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
+		Scan(&response.ID, &response.UserID, &response.Provider, &response.Subject, &response.Email, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Identity{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *IdentityRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.IdentityList, error) {
+	var (
+		response             = entity.IdentityList{}
+		createdAt, updatedAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, user_id, provider, subject, email, created_at, updated_at`).
+		From("identity")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.Identity
+		err = rows.Scan(&item.ID, &item.UserID, &item.Provider, &item.Subject, &item.Email, &createdAt, &updatedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("identity").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (r *IdentityRepo) Delete(ctx context.Context, req entity.Id) error {
+	qeury, args, err := r.pg.Builder.Delete("identity").Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
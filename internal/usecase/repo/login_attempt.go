@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+type LoginAttemptRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewLoginAttemptRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *LoginAttemptRepo {
+	return &LoginAttemptRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *LoginAttemptRepo) Create(ctx context.Context, req entity.LoginAttempt) (entity.LoginAttempt, error) {
+	req.ID = uuid.NewString()
+	userID := sql.NullString{String: req.UserID, Valid: req.UserID != ""}
+
+	qeury, args, err := r.pg.Builder.Insert("login_attempt").
+		Columns(`id, user_id, identifier, ip_address, user_agent, success, reason`).
+		Values(req.ID, userID, req.Identifier, req.IPAddress, req.UserAgent, req.Success, req.Reason).ToSql()
+	if err != nil {
+		return entity.LoginAttempt{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.LoginAttempt{}, err
+	}
+
+	return req, nil
+}
+
+func (r *LoginAttemptRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.LoginAttemptList, error) {
+	var (
+		response  = entity.LoginAttemptList{}
+		createdAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, user_id, identifier, ip_address, user_agent, success, reason, created_at`).
+		From("login_attempt")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item   entity.LoginAttempt
+			userID sql.NullString
+		)
+		err = rows.Scan(&item.ID, &userID, &item.Identifier, &item.IPAddress, &item.UserAgent,
+			&item.Success, &item.Reason, &createdAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.UserID = userID.String
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("login_attempt").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+)
+
+// MailOutboxRepo is the queue of rendered emails waiting for delivery.
+type MailOutboxRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewMailOutboxRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *MailOutboxRepo {
+	return &MailOutboxRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *MailOutboxRepo) Create(ctx context.Context, req entity.MailOutbox) (entity.MailOutbox, error) {
+	req.ID = uuid.NewString()
+	req.Status = "pending"
+
+	qeury, args, err := r.pg.Builder.Insert("mail_outbox").
+		Columns(`id, recipient, template, subject, text_body, html_body, status`).
+		Values(req.ID, req.Recipient, req.Template, req.Subject, req.TextBody, req.HTMLBody, req.Status).ToSql()
+	if err != nil {
+		return entity.MailOutbox{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.MailOutbox{}, err
+	}
+
+	return req, nil
+}
+
+// Claim marks the pending message that is due the longest as sending, counts the attempt and returns it,
+// pgx.ErrNoRows when there is none. Messages left sending for longer than staleAfter are claimed again.
+// Replicas never claim the same message, the row is locked with SKIP LOCKED.
+func (r *MailOutboxRepo) Claim(ctx context.Context, staleAfter time.Duration) (entity.MailOutbox, error) {
+	var (
+		response                            entity.MailOutbox
+		nextAttemptAt, createdAt, updatedAt time.Time
+	)
+
+	qeury := `UPDATE mail_outbox SET status = 'sending', attempts = attempts + 1, updated_at = now()
+		WHERE id = (
+			SELECT id FROM mail_outbox
+			WHERE (status = 'pending' AND next_attempt_at <= now()) OR (status = 'sending' AND updated_at < $1)
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, template, subject, text_body, html_body, status, attempts, last_error,
+			next_attempt_at, created_at, updated_at`
+
+	err := r.pg.Pool.QueryRow(ctx, qeury, time.Now().UTC().Add(-staleAfter)).Scan(
+		&response.ID, &response.Recipient, &response.Template, &response.Subject, &response.TextBody, &response.HTMLBody,
+		&response.Status, &response.Attempts, &response.LastError, &nextAttemptAt, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return entity.MailOutbox{}, err
+	}
+
+	response.NextAttemptAt = nextAttemptAt.Format(time.RFC3339)
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *MailOutboxRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	mp := map[string]interface{}{}
+	response := entity.RowsEffected{}
+
+	for _, item := range req.Items {
+		mp[item.Column] = item.Value
+	}
+
+	qeury, args, err := r.pg.Builder.Update("mail_outbox").SetMap(mp).Where(PrepareFilter(req.Filter)).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+
+	response.RowsEffected = int(n.RowsAffected())
+
+	return response, nil
+}
+
+func (r *MailOutboxRepo) Delete(ctx context.Context, req entity.Id) error {
+	qeury, args, err := r.pg.Builder.Delete("mail_outbox").Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
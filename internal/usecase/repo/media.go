@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// MediaRepo holds the uploaded media, the bytes themselves are in the media storage under storage_key.
+type MediaRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewMediaRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *MediaRepo {
+	return &MediaRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *MediaRepo) Create(ctx context.Context, req entity.Media) (entity.Media, error) {
+	if req.ID == "" {
+		req.ID = uuid.NewString()
+	}
+	if req.StorageKey == "" {
+		req.StorageKey = "media/" + req.ID
+	}
+	req.CreatedAt = time.Now().Format(time.RFC3339)
+	req.UpdatedAt = req.CreatedAt
+
+	if req.VariantsStatus == "" {
+		req.VariantsStatus = "none"
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("media").
+		Columns(`id, owner_id, kind, content_type, size, received, chunks, status, storage_key, variants_status`).
+		Values(req.ID, req.OwnerID, req.Kind, req.ContentType, req.Size, req.Received, req.Chunks, req.Status, req.StorageKey, req.VariantsStatus).ToSql()
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	return req, nil
+}
+
+// mediaColumns are the columns scanMedia reads.
+const mediaColumns = `id, COALESCE(owner_id::text, ''), kind, content_type, size, received, chunks, status, storage_key,
+	COALESCE(width, 0), COALESCE(height, 0), COALESCE(duration, 0), blurhash, variants, variants_status, variants_error,
+	variants_attempts, created_at, updated_at`
+
+func (r *MediaRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Media, error) {
+	qeuryBuilder := r.pg.Builder.
+		Select(mediaColumns).
+		From("media")
+
+	switch {
+	case req.ID != "":
+		qeuryBuilder = qeuryBuilder.Where("id = ?", req.ID)
+	default:
+		return entity.Media{}, fmt.Errorf("GetSingle - invalid request")
+	}
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	return scanMedia(r.pg.Pool.QueryRow(ctx, qeury, args...))
+}
+
+// ClaimVariants marks the media of the kind that waits for its variants the longest as processing, counts the
+// attempt and returns it, pgx.ErrNoRows when there is none. Media left processing for longer than staleAfter
+// are claimed again. Replicas never claim the same media, the row is locked with SKIP LOCKED.
+func (r *MediaRepo) ClaimVariants(ctx context.Context, kind string, staleAfter time.Duration) (entity.Media, error) {
+	qeury := `UPDATE media SET variants_status = 'processing', variants_attempts = variants_attempts + 1, updated_at = now()
+		WHERE id = (
+			SELECT id FROM media
+			WHERE kind = $1 AND status = 'ready' AND (variants_status = 'pending' OR (variants_status = 'processing' AND updated_at < $2))
+			ORDER BY updated_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + mediaColumns
+
+	return scanMedia(r.pg.Pool.QueryRow(ctx, qeury, kind, time.Now().UTC().Add(-staleAfter)))
+}
+
+// FailVariants leaves the variants of a media failed or rejected, as status says, for the reason. A video
+// that can't be played is detached from the tweets it was attached to in the same transaction, so they don't
+// keep showing it.
+func (r *MediaRepo) FailVariants(ctx context.Context, id, status, reason string) error {
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var kind string
+	err = tx.QueryRow(ctx, `UPDATE media SET variants_status = $2, variants_error = $3, updated_at = now()
+		WHERE id = $1 RETURNING kind`, id, status, reason).Scan(&kind)
+	if err != nil {
+		return err
+	}
+
+	if kind == "video" {
+		_, err = tx.Exec(ctx, `DELETE FROM tweet_attachment WHERE media_id = $1`, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// mediaUnreferenced holds for media no tweet attaches and no user has as avatar.
+const mediaUnreferenced = `NOT EXISTS (SELECT 1 FROM tweet_attachment WHERE tweet_attachment.media_id = media.id)
+	AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_id = media.id::text)`
+
+// MarkOrphaned marks the unreferenced media untouched for longer than unusedFor as orphaned and unmarks the
+// orphaned media that were referenced again. It returns how many were marked.
+func (r *MediaRepo) MarkOrphaned(ctx context.Context, unusedFor time.Duration) (int64, error) {
+	_, err := r.pg.Pool.Exec(ctx, `UPDATE media SET orphaned_at = NULL
+		WHERE orphaned_at IS NOT NULL AND status <> 'deleting' AND NOT (`+mediaUnreferenced+`)`)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := r.pg.Pool.Exec(ctx, `UPDATE media SET orphaned_at = now()
+		WHERE orphaned_at IS NULL AND updated_at < $1 AND `+mediaUnreferenced, time.Now().UTC().Add(-unusedFor))
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ListOrphaned returns limit of the media orphaned for longer than orphanedFor after skipping offset of them,
+// the longest orphaned first. Media whose deletion failed half way are listed again.
+func (r *MediaRepo) ListOrphaned(ctx context.Context, orphanedFor time.Duration, offset, limit int) ([]entity.Media, error) {
+	qeury := `SELECT ` + mediaColumns + ` FROM media
+		WHERE orphaned_at < $1 AND ` + mediaUnreferenced + `
+		ORDER BY orphaned_at, id
+		OFFSET $2 LIMIT $3`
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, time.Now().UTC().Add(-orphanedFor), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := []entity.Media{}
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		response = append(response, media)
+	}
+
+	return response, rows.Err()
+}
+
+// ClaimOrphaned marks an orphaned media as deleting, so it can't be attached or made an avatar anymore. It
+// returns false when the media was referenced meanwhile or its variants are being made.
+func (r *MediaRepo) ClaimOrphaned(ctx context.Context, id string) (bool, error) {
+	tag, err := r.pg.Pool.Exec(ctx, `UPDATE media SET status = 'deleting', updated_at = now()
+		WHERE id = $1 AND orphaned_at IS NOT NULL AND variants_status <> 'processing' AND `+mediaUnreferenced, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func scanMedia(row pgx.Row) (entity.Media, error) {
+	var (
+		response             entity.Media
+		variants             []byte
+		createdAt, updatedAt time.Time
+	)
+
+	err := row.Scan(&response.ID, &response.OwnerID, &response.Kind, &response.ContentType, &response.Size, &response.Received,
+		&response.Chunks, &response.Status, &response.StorageKey, &response.Width, &response.Height, &response.Duration,
+		&response.Blurhash, &variants, &response.VariantsStatus, &response.VariantsError, &response.VariantsAttempts,
+		&createdAt, &updatedAt)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	response.Variants, err = entity.UnmarshalMediaVariants(variants)
+	if err != nil {
+		return entity.Media{}, err
+	}
+
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+func (r *MediaRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	mp := map[string]interface{}{}
+	response := entity.RowsEffected{}
+
+	for _, item := range req.Items {
+		mp[item.Column] = item.Value
+	}
+
+	qeury, args, err := r.pg.Builder.Update("media").SetMap(mp).Where(PrepareFilter(req.Filter)).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+
+	response.RowsEffected = int(n.RowsAffected())
+
+	return response, nil
+}
+
+func (r *MediaRepo) Delete(ctx context.Context, req entity.Id) error {
+	qeury, args, err := r.pg.Builder.Delete("media").Where("id = ?", req.ID).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
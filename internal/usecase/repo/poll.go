@@ -0,0 +1,257 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// PollRepo is the polls of tweets and their votes.
+type PollRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewPollRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *PollRepo {
+	return &PollRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+// pollEndsAt is when voting on a poll ends, null until its tweet is published.
+const pollEndsAt = `t.published_at + p.duration_minutes * interval '1 minute'`
+
+// GetByTweets returns the polls of tweets by tweet id, with the counts of their options and the option userID
+// voted for. The counts of a closed poll are the ones frozen when it was closed.
+func (r *PollRepo) GetByTweets(ctx context.Context, tweetIDs []string, userID string) (map[string]entity.Poll, error) {
+	response := map[string]entity.Poll{}
+	if len(tweetIDs) == 0 {
+		return response, nil
+	}
+
+	voter := sql.NullString{String: userID, Valid: userID != ""}
+
+	qeury, args, err := r.pg.Builder.
+		Select(`p.tweet_id, p.duration_minutes, `+pollEndsAt).
+		Column(`p.closed_at IS NOT NULL OR COALESCE(`+pollEndsAt+` <= ?, false)`, time.Now().UTC()).
+		Column(`(SELECT v.option_id::text FROM poll_vote v WHERE v.tweet_id = p.tweet_id AND v.user_id = ?)`, voter).
+		From("poll p").
+		Join("tweet t ON t.id = p.tweet_id").
+		Where(squirrel.Eq{"p.tweet_id": tweetIDs}).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item    entity.Poll
+			tweetID string
+			endsAt  sql.NullTime
+			vote    sql.NullString
+			total   int64
+		)
+
+		err = rows.Scan(&tweetID, &item.DurationMinutes, &endsAt, &item.Ended, &vote)
+		if err != nil {
+			return response, err
+		}
+
+		if endsAt.Valid {
+			item.EndsAt = endsAt.Time.Format(time.RFC3339)
+		}
+		item.Vote = vote.String
+		item.Options = []entity.PollOption{}
+		item.TotalVotes = &total
+
+		response[tweetID] = item
+	}
+
+	if err = rows.Err(); err != nil {
+		return response, err
+	}
+
+	qeury, args, err = r.pg.Builder.
+		Select(`o.tweet_id, o.id, o.text,
+			CASE WHEN p.closed_at IS NULL THEN (SELECT COUNT(1) FROM poll_vote v WHERE v.option_id = o.id) ELSE o.votes END`).
+		From("poll_option o").
+		Join("poll p ON p.tweet_id = o.tweet_id").
+		Where(squirrel.Eq{"o.tweet_id": tweetIDs}).
+		OrderBy("o.tweet_id", "o.position").ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err = r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item    entity.PollOption
+			tweetID string
+			votes   int64
+		)
+
+		err = rows.Scan(&tweetID, &item.Id, &item.Text, &votes)
+		if err != nil {
+			return response, err
+		}
+		item.Votes = &votes
+
+		poll := response[tweetID]
+		poll.Options = append(poll.Options, item)
+		*poll.TotalVotes += votes
+		response[tweetID] = poll
+	}
+
+	return response, rows.Err()
+}
+
+// Vote records the vote of a user for an option of the poll of a tweet, false when voting on the poll isn't
+// open. pgx.ErrNoRows is returned for a tweet without a poll or an option of another poll, a unique violation
+// for a user who voted already. The poll is locked against being closed meanwhile.
+func (r *PollRepo) Vote(ctx context.Context, req entity.PollVote) (bool, error) {
+	var open bool
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	qeury, args, err := r.pg.Builder.
+		Select().Column(`t.status = 'published' AND p.closed_at IS NULL AND `+pollEndsAt+` > ?`, time.Now().UTC()).
+		From("poll p").
+		Join("tweet t ON t.id = p.tweet_id").
+		Where("p.tweet_id = ?", req.TweetId).
+		Suffix("FOR SHARE OF p").ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&open)
+	if err != nil {
+		return false, err
+	}
+
+	if !open {
+		return false, nil
+	}
+
+	option := r.pg.Builder.Select().Column("tweet_id, ?::uuid, id", req.UserId).
+		From("poll_option").Where(squirrel.Eq{"tweet_id": req.TweetId, "id": req.OptionId})
+
+	qeury, args, err = r.pg.Builder.Insert("poll_vote").
+		Columns(`tweet_id, user_id, option_id`).Select(option).ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, pgx.ErrNoRows
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CloseDue closes the poll that ended the longest ago and freezes the counts of its options, in one
+// transaction. It returns the id of the tweet of the poll, pgx.ErrNoRows when no poll is due. Replicas never
+// close the same poll, the row is locked with SKIP LOCKED.
+func (r *PollRepo) CloseDue(ctx context.Context) (string, error) {
+	var tweetID string
+
+	now := time.Now().UTC()
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `SELECT p.tweet_id FROM poll p
+		JOIN tweet t ON t.id = p.tweet_id
+		WHERE p.closed_at IS NULL AND `+pollEndsAt+` <= $1
+		ORDER BY `+pollEndsAt+`
+		LIMIT 1
+		FOR UPDATE OF p SKIP LOCKED`, now).Scan(&tweetID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE poll_option SET votes = (SELECT COUNT(1) FROM poll_vote v WHERE v.option_id = poll_option.id)
+		WHERE tweet_id = $1`, tweetID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE poll SET closed_at = $2 WHERE tweet_id = $1`, tweetID, now)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return tweetID, nil
+}
+
+// insertPoll adds a poll with its options to a tweet within tx, the options get their ids.
+func insertPoll(ctx context.Context, tx pgx.Tx, builder squirrel.StatementBuilderType, tweetID string, req *entity.Poll) error {
+	qeury, args, err := builder.Insert("poll").
+		Columns(`tweet_id, duration_minutes`).
+		Values(tweetID, req.DurationMinutes).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return err
+	}
+
+	insert := builder.Insert("poll_option").Columns(`id, tweet_id, position, text`)
+	for i := range req.Options {
+		req.Options[i].Id = uuid.NewString()
+		insert = insert.Values(req.Options[i].Id, tweetID, i, req.Options[i].Text)
+	}
+
+	qeury, args, err = insert.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, qeury, args...)
+
+	return err
+}
@@ -0,0 +1,199 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// ReportRepo holds the reports of tweets and users, open ones make up the moderation queue.
+type ReportRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewReportRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *ReportRepo {
+	return &ReportRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *ReportRepo) Create(ctx context.Context, req entity.Report) (entity.Report, error) {
+	req.ID = uuid.NewString()
+	req.Status = "open"
+	req.CreatedAt = time.Now().Format(time.RFC3339)
+
+	qeury, args, err := r.pg.Builder.Insert("report").
+		Columns(`id, reporter_id, target_type, target_id, category, severity, details, locale, status`).
+		Values(req.ID, req.ReporterID, req.TargetType, req.TargetID, req.Category, req.Severity, req.Details, req.Locale, req.Status).ToSql()
+	if err != nil {
+		return entity.Report{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Report{}, err
+	}
+
+	return req, nil
+}
+
+// GetQueue merges the reports matching req by target, req.OrderBy may use reports, severity,
+// first_reported_at and last_reported_at.
+func (r *ReportRepo) GetQueue(ctx context.Context, req entity.GetListFilter) (entity.ReportQueue, error) {
+	var (
+		response                        = entity.ReportQueue{Items: []entity.ReportQueueItem{}}
+		firstReportedAt, lastReportedAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`target_type, target_id, COUNT(1) AS reports, MAX(severity) AS severity,
+				array_agg(DISTINCT category) AS categories,
+				MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at`).
+		From("report").
+		GroupBy("target_type", "target_id")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.ReportQueueItem
+
+		err = rows.Scan(&item.TargetType, &item.TargetID, &item.Reports, &item.Severity, &item.Categories, &firstReportedAt, &lastReportedAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.FirstReportedAt = firstReportedAt.Format(time.RFC3339)
+		item.LastReportedAt = lastReportedAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(DISTINCT (target_type, target_id))").From("report").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// Resolve resolves the open reports of the target and applies the action, in one transaction. The reports are
+// claimed first, so of two moderators resolving the same target only one applies an action, the other gets no
+// reports. pgx.ErrNoRows is returned when the tweet or user is no longer in the status the action expects. The
+// ids of the sessions revoked by suspending the author are returned for their cached copies to be dropped.
+func (r *ReportRepo) Resolve(ctx context.Context, req entity.ReportResolve) ([]entity.Report, []string, error) {
+	var (
+		response   = []entity.Report{}
+		revoked    = []string{}
+		resolvedBy = sql.NullString{String: req.Report.ResolvedBy, Valid: req.Report.ResolvedBy != ""}
+	)
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return response, revoked, err
+	}
+	defer tx.Rollback(ctx)
+
+	qeury, args, err := r.pg.Builder.Update("report").
+		SetMap(map[string]interface{}{
+			"status":      "resolved",
+			"action":      req.Report.Action,
+			"resolved_by": resolvedBy,
+			"resolved_at": squirrel.Expr("now()"),
+		}).
+		Where("target_type = ? AND target_id = ? AND status = 'open'", req.Report.TargetType, req.Report.TargetID).
+		Suffix("RETURNING id, reporter_id, category, severity, details, locale, resolved_at, created_at").ToSql()
+	if err != nil {
+		return response, revoked, err
+	}
+
+	rows, err := tx.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, revoked, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item                  entity.Report
+			resolvedAt, createdAt time.Time
+		)
+
+		err = rows.Scan(&item.ID, &item.ReporterID, &item.Category, &item.Severity, &item.Details, &item.Locale, &resolvedAt, &createdAt)
+		if err != nil {
+			return response, revoked, err
+		}
+
+		item.TargetType = req.Report.TargetType
+		item.TargetID = req.Report.TargetID
+		item.Status = "resolved"
+		item.Action = req.Report.Action
+		item.ResolvedBy = req.Report.ResolvedBy
+		item.ResolvedAt = resolvedAt.Format(time.RFC3339)
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+
+		response = append(response, item)
+	}
+
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return response, revoked, err
+	}
+
+	if len(response) == 0 {
+		return response, revoked, nil
+	}
+
+	if req.Tweet != nil {
+		tag, err := tx.Exec(ctx, `UPDATE tweet SET status = $1, updated_at = now() WHERE id = $2 AND status = $3`,
+			req.Tweet.Status, req.Tweet.TweetID, req.Tweet.From)
+		if err != nil {
+			return response, revoked, err
+		}
+		if tag.RowsAffected() == 0 {
+			return response, revoked, pgx.ErrNoRows
+		}
+	}
+
+	if req.Moderation != nil {
+		_, revoked, err = NewUserModerationRepo(r.pg, r.config, r.logger).moderate(ctx, tx, *req.Moderation)
+		if err != nil {
+			return response, revoked, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return response, revoked, err
+	}
+
+	return response, revoked, nil
+}
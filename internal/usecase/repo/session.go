@@ -182,24 +182,44 @@ func (r *SessionRepo) Delete(ctx context.Context, req entity.Id) error {
 }
 
 func (r *SessionRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	ids, err := r.updateField(ctx, req)
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	return entity.RowsEffected{RowsEffected: len(ids)}, nil
+}
+
+// updateField applies UpdateField and returns ids of the updated sessions.
+func (r *SessionRepo) updateField(ctx context.Context, req entity.UpdateFieldRequest) ([]string, error) {
 	mp := map[string]interface{}{}
-	response := entity.RowsEffected{}
+	ids := []string{}
 
 	for _, item := range req.Items {
 		mp[item.Column] = item.Value
 	}
 
-	qeury, args, err := r.pg.Builder.Update("session").SetMap(mp).Where(PrepareFilter(req.Filter)).ToSql()
+	qeury, args, err := r.pg.Builder.Update("session").SetMap(mp).Where(PrepareFilter(req.Filter)).
+		Suffix("RETURNING id").ToSql()
 	if err != nil {
-		return response, err
+		return nil, err
 	}
 
-	n, err := r.pg.Pool.Exec(ctx, qeury, args...)
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
 	if err != nil {
-		return response, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	response.RowsEffected = int(n.RowsAffected())
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
 
-	return response, nil
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
 }
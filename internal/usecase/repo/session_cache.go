@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	rediscache "github.com/golanguzb70/redis-cache"
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/lru"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	sessionCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "session_cache_hits_total",
+		Help: "Number of session lookups served from cache, by cache layer.",
+	}, []string{"layer"})
+
+	sessionCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "session_cache_misses_total",
+		Help: "Number of session lookups that fell through to postgres.",
+	})
+)
+
+// sessionInvalidationRetry is how long the invalidation listener waits before receiving again after an error.
+const sessionInvalidationRetry = time.Second
+
+// SessionCacheRepo wraps SessionRepo with an in-process LRU in front of redis.
+// GetSingle is served from cache, writes that can deactivate a session invalidate it. Invalidations are
+// published on redis pub/sub so every replica drops the session from its LRU.
+type SessionCacheRepo struct {
+	*SessionRepo
+	redis  rediscache.RedisCache
+	client *goredis.Client
+	pubsub *goredis.PubSub
+	local  *lru.Cache[entity.Session]
+}
+
+// New -.
+func NewSessionCacheRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger, redis rediscache.RedisCache,
+	client *goredis.Client) *SessionCacheRepo {
+	r := &SessionCacheRepo{
+		SessionRepo: NewSessionRepo(pg, config, logger),
+		redis:       redis,
+		client:      client,
+		local:       lru.New[entity.Session](config.Session.LocalCacheSize, config.Session.LocalCacheTTL),
+	}
+
+	r.pubsub = client.Subscribe(context.Background(), config.Session.InvalidationChannel)
+	go r.listen()
+
+	return r
+}
+
+func (r *SessionCacheRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Session, error) {
+	if session, ok := r.local.Get(req.ID); ok {
+		sessionCacheHits.WithLabelValues("local").Inc()
+		return session, nil
+	}
+
+	key := sessionCacheKey(req.ID)
+
+	cached, err := r.redis.Get(ctx, key)
+	if err == nil {
+		var session entity.Session
+		if err = json.Unmarshal([]byte(cached), &session); err == nil {
+			sessionCacheHits.WithLabelValues("redis").Inc()
+			r.local.Set(req.ID, session)
+			return session, nil
+		}
+	}
+
+	sessionCacheMisses.Inc()
+
+	session, err := r.SessionRepo.GetSingle(ctx, req)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	body, err := json.Marshal(session)
+	if err == nil {
+		err = r.redis.Set(ctx, key, string(body), int(r.config.Session.CacheTTL.Seconds()))
+	}
+	if err != nil {
+		r.logger.Error(err, "error while caching session")
+	}
+
+	r.local.Set(req.ID, session)
+
+	return session, nil
+}
+
+func (r *SessionCacheRepo) Update(ctx context.Context, req entity.Session) (entity.Session, error) {
+	session, err := r.SessionRepo.Update(ctx, req)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	r.Invalidate(ctx, req.ID)
+
+	return session, nil
+}
+
+func (r *SessionCacheRepo) Delete(ctx context.Context, req entity.Id) error {
+	err := r.SessionRepo.Delete(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	r.Invalidate(ctx, req.ID)
+
+	return nil
+}
+
+func (r *SessionCacheRepo) UpdateField(ctx context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	ids, err := r.SessionRepo.updateField(ctx, req)
+	if err != nil {
+		return entity.RowsEffected{}, err
+	}
+
+	// last_active_at touches are frequent and harmless to serve stale for a TTL,
+	// only changes to is_active or expires_at have to be visible immediately.
+	for _, item := range req.Items {
+		if item.Column == "is_active" || item.Column == "expires_at" {
+			r.Invalidate(ctx, ids...)
+			break
+		}
+	}
+
+	return entity.RowsEffected{RowsEffected: len(ids)}, nil
+}
+
+// Invalidate drops the cached copies of sessions, also used for sessions changed by other repos.
+func (r *SessionCacheRepo) Invalidate(ctx context.Context, ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	for _, id := range ids {
+		r.local.Delete(id)
+
+		err := r.redis.Del(ctx, sessionCacheKey(id))
+		if err != nil {
+			r.logger.Error(err, "error while invalidating session cache")
+		}
+	}
+
+	err := r.client.Publish(ctx, r.config.Session.InvalidationChannel, strings.Join(ids, ",")).Err()
+	if err != nil {
+		r.logger.Error(err, "error while publishing session invalidation")
+	}
+}
+
+// listen drops the sessions other replicas invalidate from the LRU until the redis client is closed.
+// Invalidations published while the subscription is down are lost, so the LRU is cleared every time the
+// subscription is made again.
+func (r *SessionCacheRepo) listen() {
+	for {
+		msg, err := r.pubsub.Receive(context.Background())
+		if errors.Is(err, goredis.ErrClosed) {
+			return
+		}
+		if err != nil {
+			r.logger.Error(err, "error while receiving session invalidations")
+			time.Sleep(sessionInvalidationRetry)
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *goredis.Subscription:
+			r.local.Purge()
+		case *goredis.Message:
+			for _, id := range strings.Split(msg.Payload, ",") {
+				r.local.Delete(id)
+			}
+		}
+	}
+}
+
+func sessionCacheKey(id string) string {
+	return fmt.Sprintf("session-cache-%s", id)
+}
@@ -2,10 +2,12 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/golanguzb70/udevslabs-twitter/config"
 	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
 	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
@@ -13,6 +15,11 @@ import (
 	"github.com/google/uuid"
 )
 
+// revisionAttachments are the attachments of a tweet as the json of a revision, in their order.
+var revisionAttachments = `(SELECT COALESCE(json_agg(json_build_object(
+		'media_id', media_id, 'content_type', content_type, 'alt_text', alt_text) ORDER BY position, created_at), '[]')
+	FROM tweet_attachment WHERE tweet_attachment.tweet_id = tweet.id)`
+
 type TweetRepo struct {
 	pg     *postgres.Postgres
 	config *config.Config
@@ -28,17 +35,55 @@ func NewTweetRepo(pg *postgres.Postgres, config *config.Config, logger *logger.L
 	}
 }
 
+// Create adds a tweet with its attachments and its poll in one transaction, a failed insert leaves nothing behind.
 func (r *TweetRepo) Create(ctx context.Context, req entity.Tweet) (entity.Tweet, error) {
 	req.Id = uuid.NewString()
 
+	var publishedAt interface{}
+	if req.Status == "published" {
+		now := time.Now().UTC()
+		publishedAt = now
+		req.PublishedAt = now.Format(time.RFC3339)
+	}
+
+	publishAt, err := tweetPublishAt(req)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+	defer tx.Rollback(ctx)
+
 	qeury, args, err := r.pg.Builder.Insert("tweet").
-		Columns(`id, owner_id, content, tags, status`).
-		Values(req.Id, req.Owner.ID, req.Content, req.Tags, req.Status).ToSql()
+		Columns(`id, owner_id, content, tags, status, publish_at, published_at`).
+		Values(req.Id, req.Owner.ID, req.Content, req.Tags, req.Status, publishAt, publishedAt).ToSql()
 	if err != nil {
 		return entity.Tweet{}, err
 	}
 
-	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	_, err = tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	err = upsertAttachments(ctx, tx, r.pg.Builder, req.Id, req.Attachments)
+	if err != nil {
+		r.logger.Error("error while inserting tweet_attachment", err)
+		return entity.Tweet{}, err
+	}
+
+	if req.Poll != nil {
+		err = insertPoll(ctx, tx, r.pg.Builder, req.Id, req.Poll)
+		if err != nil {
+			r.logger.Error("error while inserting poll", err)
+			return entity.Tweet{}, err
+		}
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return entity.Tweet{}, err
 	}
@@ -49,11 +94,12 @@ func (r *TweetRepo) Create(ctx context.Context, req entity.Tweet) (entity.Tweet,
 func (r *TweetRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Tweet, error) {
 	response := entity.Tweet{}
 	var (
-		createdAt, updatedAt time.Time
+		createdAt, updatedAt             time.Time
+		publishAt, publishedAt, editedAt sql.NullTime
 	)
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, owner_id, content, tags, status, created_at, updated_at`).
+		Select(`id, owner_id, content, tags, status, publish_at, published_at, edited_at, edit_count, created_at, updated_at`).
 		From("tweet")
 
 	switch {
@@ -71,7 +117,8 @@ func (r *TweetRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Tweet,
 	tags := []byte{}
 
 	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
-		Scan(&response.Id, &response.Owner.ID, &response.Content, &tags, &response.Status, &createdAt, &updatedAt)
+		Scan(&response.Id, &response.Owner.ID, &response.Content, &tags, &response.Status, &publishAt, &publishedAt,
+			&editedAt, &response.EditCount, &createdAt, &updatedAt)
 	if err != nil {
 		return entity.Tweet{}, err
 	}
@@ -81,6 +128,15 @@ func (r *TweetRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Tweet,
 		return entity.Tweet{}, err
 	}
 
+	if publishAt.Valid {
+		response.PublishAt = publishAt.Time.Format(time.RFC3339)
+	}
+	if publishedAt.Valid {
+		response.PublishedAt = publishedAt.Time.Format(time.RFC3339)
+	}
+	if editedAt.Valid {
+		response.EditedAt = editedAt.Time.Format(time.RFC3339)
+	}
 	response.CreatedAt = createdAt.Format(time.RFC3339)
 	response.UpdatedAt = updatedAt.Format(time.RFC3339)
 
@@ -89,14 +145,16 @@ func (r *TweetRepo) GetSingle(ctx context.Context, req entity.Id) (entity.Tweet,
 
 func (r *TweetRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.TweetList, error) {
 	var (
-		response             = entity.TweetList{}
-		createdAt, updatedAt time.Time
+		response                         = entity.TweetList{}
+		createdAt, updatedAt             time.Time
+		publishAt, publishedAt, editedAt sql.NullTime
 	)
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, owner_id, content, status, created_at, updated_at, 
-				(SELECT COALESCE(json_agg(row_to_json(ta)), '[]'::json) 
+		Select(`id, owner_id, content, status, publish_at, published_at, edited_at, edit_count, created_at, updated_at, 
+				(SELECT COALESCE(json_agg(to_jsonb(ta) || jsonb_build_object('blurhash', COALESCE(m.blurhash, ''), 'variants', COALESCE(m.variants, '{}'), 'status', ` + attachmentStatus("m") + `) ORDER BY ta.position, ta.created_at), '[]'::json) 
 				 FROM tweet_attachment ta 
+				 LEFT JOIN media m ON m.id = ta.media_id 
 				 WHERE ta.tweet_id = tweet.id) AS attachments, 
 				 (
 					SELECT row_to_json(u)
@@ -108,6 +166,12 @@ func (r *TweetRepo) GetList(ctx context.Context, req entity.GetListFilter) (enti
 
 	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
 
+	// tweets of suspended and banned users are hidden until the user is restored,
+	// tweets hidden or removed by a moderator until the moderator shows them again
+	hidden := squirrel.Expr(`status NOT IN ('hidden', 'removed') AND owner_id NOT IN (SELECT id FROM users WHERE status IN ('suspended', 'blocked'))`)
+	qeuryBuilder = qeuryBuilder.Where(hidden)
+	where = append(where, hidden)
+
 	qeury, args, err := qeuryBuilder.ToSql()
 	if err != nil {
 		return response, err
@@ -123,11 +187,21 @@ func (r *TweetRepo) GetList(ctx context.Context, req entity.GetListFilter) (enti
 		var item entity.Tweet
 		var attachmentsJSON []byte
 		var userJson []byte
-		err = rows.Scan(&item.Id, &item.Owner.ID, &item.Content, &item.Status, &createdAt, &updatedAt, &attachmentsJSON, &userJson)
+		err = rows.Scan(&item.Id, &item.Owner.ID, &item.Content, &item.Status, &publishAt, &publishedAt, &editedAt,
+			&item.EditCount, &createdAt, &updatedAt, &attachmentsJSON, &userJson)
 		if err != nil {
 			return response, err
 		}
 
+		if publishAt.Valid {
+			item.PublishAt = publishAt.Time.Format(time.RFC3339)
+		}
+		if publishedAt.Valid {
+			item.PublishedAt = publishedAt.Time.Format(time.RFC3339)
+		}
+		if editedAt.Valid {
+			item.EditedAt = editedAt.Time.Format(time.RFC3339)
+		}
 		item.CreatedAt = createdAt.Format(time.RFC3339)
 		item.UpdatedAt = updatedAt.Format(time.RFC3339)
 
@@ -157,26 +231,213 @@ func (r *TweetRepo) GetList(ctx context.Context, req entity.GetListFilter) (enti
 	return response, nil
 }
 
-func (r *TweetRepo) Update(ctx context.Context, req entity.Tweet) (entity.Tweet, error) {
+// Update saves the content, tags, status and, unless nil, the attachments of a tweet in one transaction, see
+// AttachmentRepo.MultipleUpsert for the attachments. A tweet is published the first time its status is published.
+// check is called with the stored tweet and its attachments, locked, before anything is written and its error is
+// returned. An edit of a published tweet keeps the content and attachments it replaces as a revision and counts
+// the edit.
+func (r *TweetRepo) Update(ctx context.Context, req entity.Tweet, check func(stored entity.Tweet) error) (entity.Tweet, error) {
+	var (
+		stored                entity.Tweet
+		publishedAt, editedAt sql.NullTime
+		createdAt, updatedAt  time.Time
+	)
+
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qeury, args, err := r.pg.Builder.Select("id, owner_id, content, status, published_at").From("tweet").
+		Where("id = ?", req.Id).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&stored.Id, &stored.Owner.ID, &stored.Content, &stored.Status, &publishedAt)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	if publishedAt.Valid {
+		stored.PublishedAt = publishedAt.Time.Format(time.RFC3339)
+	}
+
+	qeury, args, err = r.pg.Builder.Select("id, alt_text").From("tweet_attachment").
+		Where("tweet_id = ?", req.Id).OrderBy("position", "created_at").ToSql()
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	rows, err := tx.Query(ctx, qeury, args...)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	for rows.Next() {
+		var attachment entity.Attachment
+		err = rows.Scan(&attachment.Id, &attachment.AltText)
+		if err != nil {
+			rows.Close()
+			return entity.Tweet{}, err
+		}
+
+		stored.Attachments = append(stored.Attachments, attachment)
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return entity.Tweet{}, rows.Err()
+	}
+
+	if check != nil {
+		err = check(stored)
+		if err != nil {
+			return entity.Tweet{}, err
+		}
+	}
+
+	edit := req
+	if edit.Attachments == nil {
+		edit.Attachments = stored.Attachments
+	}
+
+	publishAt, err := tweetPublishAt(req)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	now := time.Now().UTC()
+
 	mp := map[string]interface{}{
 		"content":    req.Content,
+		"tags":       req.Tags,
 		"status":     req.Status,
-		"updated_at": "now()",
+		"publish_at": publishAt,
+		"updated_at": now,
+	}
+
+	if req.Status == "published" {
+		mp["published_at"] = squirrel.Expr("COALESCE(published_at, ?)", now)
+	}
+
+	if publishedAt.Valid && stored.EditedBy(edit) {
+		revision := r.pg.Builder.Select().Column("?::uuid", uuid.NewString()).
+			Columns("id, content, tags", revisionAttachments, "COALESCE(edited_at, published_at)").
+			From("tweet").Where("id = ?", req.Id)
+
+		qeury, args, err = r.pg.Builder.Insert("tweet_revision").
+			Columns(`id, tweet_id, content, tags, attachments, created_at`).Select(revision).ToSql()
+		if err != nil {
+			return entity.Tweet{}, err
+		}
+
+		_, err = tx.Exec(ctx, qeury, args...)
+		if err != nil {
+			r.logger.Error("error while inserting tweet_revision", err)
+			return entity.Tweet{}, err
+		}
+
+		mp["edited_at"] = now
+		mp["edit_count"] = squirrel.Expr("edit_count + 1")
 	}
 
-	qeury, args, err := r.pg.Builder.Update("tweet").SetMap(mp).Where("id = ?", req.Id).ToSql()
+	qeury, args, err = r.pg.Builder.Update("tweet").SetMap(mp).Where("id = ?", req.Id).
+		Suffix("RETURNING published_at, edited_at, edit_count, created_at, updated_at").ToSql()
 	if err != nil {
 		return entity.Tweet{}, err
 	}
 
-	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	err = tx.QueryRow(ctx, qeury, args...).Scan(&publishedAt, &editedAt, &req.EditCount, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	if req.Attachments != nil {
+		err = upsertAttachments(ctx, tx, r.pg.Builder, req.Id, req.Attachments)
+		if err != nil {
+			r.logger.Error("error while upserting tweet_attachment", err)
+			return entity.Tweet{}, err
+		}
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return entity.Tweet{}, err
 	}
 
+	if publishedAt.Valid {
+		req.PublishedAt = publishedAt.Time.Format(time.RFC3339)
+	}
+	if editedAt.Valid {
+		req.EditedAt = editedAt.Time.Format(time.RFC3339)
+	}
+	req.CreatedAt = createdAt.Format(time.RFC3339)
+	req.UpdatedAt = updatedAt.Format(time.RFC3339)
+
 	return req, nil
 }
 
+// ClaimScheduled publishes the scheduled tweet due the longest, pgx.ErrNoRows when none is due. The tweet is left
+// to be tagged, see ClaimUntagged. Replicas never claim the same tweet, the row is locked with SKIP LOCKED.
+func (r *TweetRepo) ClaimScheduled(ctx context.Context) (entity.Tweet, error) {
+	var (
+		response             entity.Tweet
+		publishedAt          time.Time
+		createdAt, updatedAt time.Time
+	)
+
+	qeury := `UPDATE tweet SET status = 'published', publish_at = NULL, published_at = $1, tag_attempt_at = $1,
+		updated_at = $1
+		WHERE id = (
+			SELECT id FROM tweet
+			WHERE status = 'scheduled' AND publish_at <= $1
+			ORDER BY publish_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, owner_id, content, status, published_at, created_at, updated_at`
+
+	err := r.pg.Pool.QueryRow(ctx, qeury, time.Now().UTC()).
+		Scan(&response.Id, &response.Owner.ID, &response.Content, &response.Status, &publishedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	response.PublishedAt = publishedAt.Format(time.RFC3339)
+	response.CreatedAt = createdAt.Format(time.RFC3339)
+	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+
+	return response, nil
+}
+
+// ClaimUntagged returns the published tweet waiting the longest for its tags and puts its next attempt off by
+// retryAfter, pgx.ErrNoRows when none is waiting. Tagging it clears tag_attempt_at. Replicas never claim the same
+// tweet, the row is locked with SKIP LOCKED.
+func (r *TweetRepo) ClaimUntagged(ctx context.Context, retryAfter time.Duration) (entity.Tweet, error) {
+	var response entity.Tweet
+
+	qeury := `UPDATE tweet SET tag_attempt_at = $2
+		WHERE id = (
+			SELECT id FROM tweet
+			WHERE tag_attempt_at <= $1
+			ORDER BY tag_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, owner_id, content, status`
+
+	now := time.Now().UTC()
+
+	err := r.pg.Pool.QueryRow(ctx, qeury, now, now.Add(retryAfter)).
+		Scan(&response.Id, &response.Owner.ID, &response.Content, &response.Status)
+	if err != nil {
+		return entity.Tweet{}, err
+	}
+
+	return response, nil
+}
+
 func (r *TweetRepo) Delete(ctx context.Context, req entity.Id) error {
 	qeury, args, err := r.pg.Builder.Delete("tweet").Where("id = ?", req.ID).ToSql()
 	if err != nil {
@@ -213,3 +474,17 @@ func (r *TweetRepo) UpdateField(ctx context.Context, req entity.UpdateFieldReque
 
 	return response, nil
 }
+
+// tweetPublishAt is the publish_at column of a tweet, null unless the tweet is scheduled. It is kept in UTC.
+func tweetPublishAt(req entity.Tweet) (sql.NullTime, error) {
+	if req.Status != "scheduled" || req.PublishAt == "" {
+		return sql.NullTime{}, nil
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, req.PublishAt)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+
+	return sql.NullTime{Time: publishAt.UTC(), Valid: true}, nil
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+)
+
+// TweetRevisionRepo is the edit history of tweets, revisions are written by TweetRepo.Update.
+type TweetRevisionRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewTweetRevisionRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *TweetRevisionRepo {
+	return &TweetRevisionRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *TweetRevisionRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.TweetRevisionList, error) {
+	var (
+		response  = entity.TweetRevisionList{Items: []entity.TweetRevision{}}
+		createdAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, tweet_id, content, tags, attachments, created_at`).
+		From("tweet_revision")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item        entity.TweetRevision
+			tags        []byte
+			attachments []byte
+		)
+
+		err = rows.Scan(&item.Id, &item.TweetId, &item.Content, &tags, &attachments, &createdAt)
+		if err != nil {
+			return response, err
+		}
+
+		err = json.Unmarshal(attachments, &item.Attachments)
+		if err != nil {
+			return response, err
+		}
+
+		if len(tags) > 0 {
+			err = json.Unmarshal(tags, &item.Tags)
+			if err != nil {
+				return response, err
+			}
+		}
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("tweet_revision").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -12,6 +13,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// avatarVariantsColumn is the variants of the media avatar_id refers to, avatar ids that aren't media have none.
+const avatarVariantsColumn = `COALESCE((SELECT variants FROM media WHERE media.id::text = users.avatar_id), '{}')`
+
 type UserRepo struct {
 	pg     *postgres.Postgres
 	config *config.Config
@@ -49,10 +53,14 @@ func (r *UserRepo) GetSingle(ctx context.Context, req entity.UserSingleRequest)
 	response := entity.User{}
 	var (
 		createdAt, updatedAt time.Time
+		deleteScheduledAt    sql.NullTime
+		pendingEmail         sql.NullString
+		suspendedUntil       sql.NullTime
+		avatarVariants       []byte
 	)
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, full_name, email, username, password, user_type, user_role, status, avatar_id, gender, created_at, updated_at`).
+		Select(`id, full_name, email, username, password, user_type, user_role, status, avatar_id, gender, created_at, updated_at, delete_scheduled_at, pending_email, suspended_until, ` + avatarVariantsColumn).
 		From("users")
 
 	switch {
@@ -73,13 +81,25 @@ func (r *UserRepo) GetSingle(ctx context.Context, req entity.UserSingleRequest)
 
 	err = r.pg.Pool.QueryRow(ctx, qeury, args...).
 		Scan(&response.ID, &response.FullName, &response.Email, &response.Username, &response.Password,
-			&response.UserType, &response.UserRole, &response.Status, &response.AvatarId, &response.Gender, &createdAt, &updatedAt)
+			&response.UserType, &response.UserRole, &response.Status, &response.AvatarId, &response.Gender, &createdAt, &updatedAt, &deleteScheduledAt, &pendingEmail, &suspendedUntil, &avatarVariants)
 	if err != nil {
 		return entity.User{}, err
 	}
 
+	response.AvatarVariants, err = entity.UnmarshalMediaVariants(avatarVariants)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	response.PendingEmail = pendingEmail.String
 	response.CreatedAt = createdAt.Format(time.RFC3339)
 	response.UpdatedAt = updatedAt.Format(time.RFC3339)
+	if deleteScheduledAt.Valid {
+		response.DeleteScheduledAt = deleteScheduledAt.Time.Format(time.RFC3339)
+	}
+	if suspendedUntil.Valid {
+		response.SuspendedUntil = suspendedUntil.Time.Format(time.RFC3339)
+	}
 
 	return response, nil
 }
@@ -91,7 +111,7 @@ func (r *UserRepo) GetList(ctx context.Context, req entity.GetListFilter) (entit
 	)
 
 	qeuryBuilder := r.pg.Builder.
-		Select(`id, full_name, email, username, password, user_type, user_role, status, avatar_id, gender, created_at, updated_at`).
+		Select(`id, full_name, email, username, password, user_type, user_role, status, avatar_id, gender, created_at, updated_at, delete_scheduled_at, pending_email, suspended_until, ` + avatarVariantsColumn).
 		From("users")
 
 	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
@@ -108,15 +128,33 @@ func (r *UserRepo) GetList(ctx context.Context, req entity.GetListFilter) (entit
 	defer rows.Close()
 
 	for rows.Next() {
-		var item entity.User
+		var (
+			item              entity.User
+			deleteScheduledAt sql.NullTime
+			pendingEmail      sql.NullString
+			suspendedUntil    sql.NullTime
+			avatarVariants    []byte
+		)
 		err = rows.Scan(&item.ID, &item.FullName, &item.Email, &item.Username, &item.Password,
-			&item.UserType, &item.UserRole, &item.Status, &item.AvatarId, &item.Gender, &createdAt, &updatedAt)
+			&item.UserType, &item.UserRole, &item.Status, &item.AvatarId, &item.Gender, &createdAt, &updatedAt, &deleteScheduledAt, &pendingEmail, &suspendedUntil, &avatarVariants)
 		if err != nil {
 			return response, err
 		}
 
+		item.AvatarVariants, err = entity.UnmarshalMediaVariants(avatarVariants)
+		if err != nil {
+			return response, err
+		}
+
+		item.PendingEmail = pendingEmail.String
 		item.CreatedAt = createdAt.Format(time.RFC3339)
 		item.UpdatedAt = updatedAt.Format(time.RFC3339)
+		if deleteScheduledAt.Valid {
+			item.DeleteScheduledAt = deleteScheduledAt.Time.Format(time.RFC3339)
+		}
+		if suspendedUntil.Valid {
+			item.SuspendedUntil = suspendedUntil.Time.Format(time.RFC3339)
+		}
 
 		response.Items = append(response.Items, item)
 	}
@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/config"
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/logger"
+	"github.com/golanguzb70/udevslabs-twitter/pkg/postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// UserModerationRepo is the history of suspensions, bans and restores of users.
+type UserModerationRepo struct {
+	pg     *postgres.Postgres
+	config *config.Config
+	logger *logger.Logger
+}
+
+// New -.
+func NewUserModerationRepo(pg *postgres.Postgres, config *config.Config, logger *logger.Logger) *UserModerationRepo {
+	return &UserModerationRepo{
+		pg:     pg,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (r *UserModerationRepo) Create(ctx context.Context, req entity.UserModeration) (entity.UserModeration, error) {
+	qeury, args, req, err := r.insertQuery(req)
+	if err != nil {
+		return entity.UserModeration{}, err
+	}
+
+	_, err = r.pg.Pool.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.UserModeration{}, err
+	}
+
+	return req, nil
+}
+
+// Moderate moves the user from req.From to req.Status, revokes the sessions of the user unless restored and
+// records the moderation in the history, in one transaction. pgx.ErrNoRows is returned when the user is no longer
+// in req.From. The ids of the revoked sessions are returned for their cached copies to be dropped.
+func (r *UserModerationRepo) Moderate(ctx context.Context, req entity.UserModerationChange) (entity.UserModeration, []string, error) {
+	tx, err := r.pg.Pool.Begin(ctx)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	moderation, revoked, err := r.moderate(ctx, tx, req)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+
+	return moderation, revoked, nil
+}
+
+// moderate applies Moderate within tx, resolving reports suspends authors in its own transaction.
+func (r *UserModerationRepo) moderate(ctx context.Context, tx pgx.Tx, req entity.UserModerationChange) (entity.UserModeration, []string, error) {
+	revoked := []string{}
+
+	qeury, args, moderation, err := r.insertQuery(req.Moderation)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+
+	suspendedUntil, err := parseSuspendedUntil(moderation.SuspendedUntil)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET status = $1, suspended_until = $2, updated_at = now()
+		WHERE id = $3 AND status = $4`, req.Status, suspendedUntil, moderation.UserID, req.From)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.UserModeration{}, nil, pgx.ErrNoRows
+	}
+
+	if req.Status != "active" {
+		rows, err := tx.Query(ctx, `UPDATE session SET is_active = false, updated_at = now()
+			WHERE user_id = $1 AND is_active RETURNING id`, moderation.UserID)
+		if err != nil {
+			return entity.UserModeration{}, nil, err
+		}
+
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				rows.Close()
+				return entity.UserModeration{}, nil, err
+			}
+
+			revoked = append(revoked, id)
+		}
+
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return entity.UserModeration{}, nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, qeury, args...)
+	if err != nil {
+		return entity.UserModeration{}, nil, err
+	}
+
+	return moderation, revoked, nil
+}
+
+// insertQuery builds the insert of a history entry, the entry is returned with its id and creation time.
+func (r *UserModerationRepo) insertQuery(req entity.UserModeration) (string, []interface{}, entity.UserModeration, error) {
+	req.ID = uuid.NewString()
+	req.CreatedAt = time.Now().Format(time.RFC3339)
+
+	moderatorID := sql.NullString{String: req.ModeratorID, Valid: req.ModeratorID != ""}
+
+	suspendedUntil, err := parseSuspendedUntil(req.SuspendedUntil)
+	if err != nil {
+		return "", nil, entity.UserModeration{}, err
+	}
+	if suspendedUntil.Valid {
+		req.SuspendedUntil = suspendedUntil.Time.Format(time.RFC3339)
+	}
+
+	qeury, args, err := r.pg.Builder.Insert("user_moderation").
+		Columns(`id, user_id, moderator_id, action, reason, suspended_until`).
+		Values(req.ID, req.UserID, moderatorID, req.Action, req.Reason, suspendedUntil).ToSql()
+	if err != nil {
+		return "", nil, entity.UserModeration{}, err
+	}
+
+	return qeury, args, req, nil
+}
+
+func (r *UserModerationRepo) GetList(ctx context.Context, req entity.GetListFilter) (entity.UserModerationList, error) {
+	var (
+		response  = entity.UserModerationList{Items: []entity.UserModeration{}}
+		createdAt time.Time
+	)
+
+	qeuryBuilder := r.pg.Builder.
+		Select(`id, user_id, moderator_id, action, reason, suspended_until, created_at`).
+		From("user_moderation")
+
+	qeuryBuilder, where := PrepareGetListQuery(qeuryBuilder, req)
+
+	qeury, args, err := qeuryBuilder.ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	rows, err := r.pg.Pool.Query(ctx, qeury, args...)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item           entity.UserModeration
+			moderatorID    sql.NullString
+			suspendedUntil sql.NullTime
+		)
+
+		err = rows.Scan(&item.ID, &item.UserID, &moderatorID, &item.Action, &item.Reason, &suspendedUntil, &createdAt)
+		if err != nil {
+			return response, err
+		}
+
+		item.ModeratorID = moderatorID.String
+		if suspendedUntil.Valid {
+			item.SuspendedUntil = suspendedUntil.Time.Format(time.RFC3339)
+		}
+		item.CreatedAt = createdAt.Format(time.RFC3339)
+
+		response.Items = append(response.Items, item)
+	}
+
+	countQuery, args, err := r.pg.Builder.Select("COUNT(1)").From("user_moderation").Where(where).ToSql()
+	if err != nil {
+		return response, err
+	}
+
+	err = r.pg.Pool.QueryRow(ctx, countQuery, args...).Scan(&response.Count)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// parseSuspendedUntil parses an RFC3339 end of a suspension into UTC, a timestamp column keeps the wall clock
+// of whatever zone it is given. An empty value is NULL.
+func parseSuspendedUntil(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+
+	return sql.NullTime{Time: until.UTC(), Valid: true}, nil
+}
@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+)
+
+// ReportCategories maps the categories a report can be filed under to their severity, the queue puts severe reports first.
+var ReportCategories = map[string]int{
+	"spam":           1,
+	"other":          1,
+	"misinformation": 2,
+	"impersonation":  2,
+	"harassment":     3,
+	"sexual":         3,
+	"hate":           4,
+	"violence":       4,
+	"self_harm":      4,
+}
+
+// Actions a report is resolved with.
+const (
+	ReportDismiss       = "dismiss"
+	ReportHideTweet     = "hide_tweet"
+	ReportDeleteTweet   = "delete_tweet"
+	ReportSuspendAuthor = "suspend_author"
+)
+
+// NotifyReporters queues the outcome of the resolved reports to their reporters, in the language they reported in.
+func (u *UseCase) NotifyReporters(ctx context.Context, reports []entity.Report) error {
+	for _, report := range reports {
+		reporter, err := u.UserRepo.GetSingle(ctx, entity.UserSingleRequest{ID: report.ReporterID})
+		if err != nil {
+			return fmt.Errorf("usecase - NotifyReporters - UserRepo.GetSingle: %w", err)
+		}
+
+		err = u.SendMail(ctx, reporter.Email, MailReportOutcome, report.Locale, map[string]interface{}{
+			"Action":     report.Action,
+			"TargetType": report.TargetType,
+			"Category":   report.Category,
+			"ReportedAt": report.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrTweetEditWindow is returned for an edit of the content or the attachments of a tweet published longer than the
+// edit window ago.
+var ErrTweetEditWindow = errors.New("tweet can no longer be edited")
+
+// ErrTweetSchedule is returned for a scheduled tweet without a publish_at in the future.
+var ErrTweetSchedule = errors.New("scheduled tweet needs a publish_at in the future")
+
+// ErrTweetTransition is returned for a status a tweet can't be moved to from its current status.
+var ErrTweetTransition = errors.New("tweet can't be moved to this status")
+
+// tweetTransitions are the statuses a tweet can be moved to from each status. A published tweet stays published
+// until a moderator hides or removes it, hidden and removed are set by moderators only.
+var tweetTransitions = map[string][]string{
+	"draft":     {"draft", "scheduled", "published"},
+	"scheduled": {"scheduled", "draft", "published"},
+	"published": {"published", "hidden", "removed"},
+	"hidden":    {"hidden", "published", "removed"},
+	"removed":   {"removed", "published"},
+}
+
+// CheckTweetTransition checks that a tweet can be moved from one status to another, a new tweet is created as
+// moved from draft.
+func CheckTweetTransition(from, to string) error {
+	if !slices.Contains(tweetTransitions[from], to) {
+		return ErrTweetTransition
+	}
+
+	return nil
+}
+
+// CheckTweetEdit checks that tweet can be edited into edit at now, see entity.Tweet.EditedBy. A tweet that isn't
+// published yet can always be edited, a published one within window of publishing.
+func CheckTweetEdit(tweet, edit entity.Tweet, window time.Duration, now time.Time) error {
+	if tweet.PublishedAt == "" || !tweet.EditedBy(edit) {
+		return nil
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339, tweet.PublishedAt)
+	if err != nil {
+		return err
+	}
+
+	if now.Sub(publishedAt) > window {
+		return ErrTweetEditWindow
+	}
+
+	return nil
+}
+
+// CheckTweetSchedule checks the publish_at of a scheduled tweet at now and returns it in UTC. The publish_at of
+// a tweet that isn't scheduled is dropped.
+func CheckTweetSchedule(tweet entity.Tweet, now time.Time) (string, error) {
+	if tweet.Status != "scheduled" {
+		return "", nil
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, tweet.PublishAt)
+	if err != nil || !publishAt.After(now) {
+		return "", ErrTweetSchedule
+	}
+
+	return publishAt.UTC().Format(time.RFC3339), nil
+}
+
+// PublishScheduledTweets publishes the scheduled tweets that are due and tags them like a tweet created
+// published, it returns how many were published. A tweet is published at its time even when tagging it fails,
+// tagging is then retried after retryAfter until it succeeds.
+func (u *UseCase) PublishScheduledTweets(ctx context.Context, retryAfter time.Duration) (int, error) {
+	var (
+		published int
+		failed    int
+		lastErr   error
+	)
+
+	for ctx.Err() == nil {
+		_, err := u.TweetRepo.ClaimScheduled(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return published, fmt.Errorf("usecase - PublishScheduledTweets - TweetRepo.ClaimScheduled: %w", err)
+		}
+		published++
+	}
+
+	// the tweets just published and the ones whose tagging is due to be retried
+	for ctx.Err() == nil {
+		tweet, err := u.TweetRepo.ClaimUntagged(ctx, retryAfter)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return published, fmt.Errorf("usecase - PublishScheduledTweets - TweetRepo.ClaimUntagged: %w", err)
+		}
+
+		err = u.tagTweet(ctx, tweet)
+		if err != nil {
+			failed++
+			lastErr = fmt.Errorf("tweet %s: %w", tweet.Id, err)
+		}
+	}
+
+	if failed > 0 {
+		return published, fmt.Errorf("usecase - PublishScheduledTweets - %d tweets not tagged, last: %w", failed, lastErr)
+	}
+
+	return published, ctx.Err()
+}
+
+func (u *UseCase) tagTweet(ctx context.Context, tweet entity.Tweet) error {
+	tagged, err := u.TagRepo.TagTweetByContent(ctx, tweet)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.TweetRepo.UpdateField(ctx, entity.UpdateFieldRequest{
+		Filter: []entity.Filter{{Column: "id", Type: "eq", Value: tweet.Id}},
+		Items: []entity.UpdateFieldItem{
+			{Column: "tags", Value: tagged.Tags},
+			{Column: "tag_attempt_at", Value: nil},
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,208 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/internal/entity"
+	"github.com/golanguzb70/udevslabs-twitter/internal/usecase"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestCheckTweetEdit(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	photo := entity.Attachment{Id: "a1", MediaId: "m1", AltText: "a cat"}
+	published := entity.Tweet{
+		Content:     "hello",
+		Attachments: []entity.Attachment{photo},
+		PublishedAt: now.Add(-30 * time.Minute).Format(time.RFC3339),
+	}
+	unchanged := entity.Tweet{Content: "hello", Attachments: []entity.Attachment{{Id: "a1", AltText: "a cat"}}}
+
+	withAttachments := func(attachments ...entity.Attachment) entity.Tweet {
+		return entity.Tweet{Content: "hello", Attachments: attachments}
+	}
+
+	tests := []struct {
+		name    string
+		tweet   entity.Tweet
+		edit    entity.Tweet
+		window  time.Duration
+		wantErr error
+	}{
+		{name: "draft", tweet: entity.Tweet{Content: "hello"}, edit: entity.Tweet{Content: "hi"}, window: 0},
+		{name: "within window", tweet: published, edit: entity.Tweet{Content: "hi", Attachments: unchanged.Attachments}, window: time.Hour},
+		{name: "after window", tweet: published, edit: entity.Tweet{Content: "hi", Attachments: unchanged.Attachments}, window: 10 * time.Minute, wantErr: usecase.ErrTweetEditWindow},
+		{name: "unchanged", tweet: published, edit: unchanged, window: 10 * time.Minute},
+		{name: "attachment added", tweet: published, edit: withAttachments(unchanged.Attachments[0], entity.Attachment{MediaId: "m2"}), window: 10 * time.Minute, wantErr: usecase.ErrTweetEditWindow},
+		{name: "attachment removed", tweet: published, edit: withAttachments(), window: 10 * time.Minute, wantErr: usecase.ErrTweetEditWindow},
+		{name: "alt text changed", tweet: published, edit: withAttachments(entity.Attachment{Id: "a1", AltText: "a dog"}), window: 10 * time.Minute, wantErr: usecase.ErrTweetEditWindow},
+		{name: "attachments reordered", tweet: entity.Tweet{Content: "hello", Attachments: []entity.Attachment{photo, {Id: "a2"}}, PublishedAt: published.PublishedAt},
+			edit: withAttachments(entity.Attachment{Id: "a2"}, entity.Attachment{Id: "a1", AltText: "a cat"}), window: 10 * time.Minute, wantErr: usecase.ErrTweetEditWindow},
+	}
+
+	for _, tt := range tests {
+		err := usecase.CheckTweetEdit(tt.tweet, tt.edit, tt.window, now)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: CheckTweetEdit() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckTweetSchedule(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tweet   entity.Tweet
+		want    string
+		wantErr error
+	}{
+		{name: "not scheduled", tweet: entity.Tweet{Status: "published", PublishAt: "2026-10-20T12:00:00Z"}},
+		{name: "future", tweet: entity.Tweet{Status: "scheduled", PublishAt: "2026-10-20T14:00:00+02:00"}, want: "2026-10-20T12:00:00Z"},
+		{name: "past", tweet: entity.Tweet{Status: "scheduled", PublishAt: "2026-10-19T11:00:00Z"}, wantErr: usecase.ErrTweetSchedule},
+		{name: "now", tweet: entity.Tweet{Status: "scheduled", PublishAt: "2026-10-19T12:00:00Z"}, wantErr: usecase.ErrTweetSchedule},
+		{name: "missing", tweet: entity.Tweet{Status: "scheduled"}, wantErr: usecase.ErrTweetSchedule},
+	}
+
+	for _, tt := range tests {
+		got, err := usecase.CheckTweetSchedule(tt.tweet, now)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%s: CheckTweetSchedule() = %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckTweetTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  error
+	}{
+		{from: "draft", to: "published"},
+		{from: "draft", to: "scheduled"},
+		{from: "scheduled", to: "draft"},
+		{from: "scheduled", to: "published"},
+		{from: "published", to: "published"},
+		{from: "published", to: "draft", wantErr: usecase.ErrTweetTransition},
+		{from: "published", to: "scheduled", wantErr: usecase.ErrTweetTransition},
+		{from: "draft", to: "hidden", wantErr: usecase.ErrTweetTransition},
+		{from: "hidden", to: "published"},
+		{from: "draft", to: "", wantErr: usecase.ErrTweetTransition},
+	}
+
+	for _, tt := range tests {
+		err := usecase.CheckTweetTransition(tt.from, tt.to)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckTweetTransition(%q, %q) = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
+
+// scheduledTweetRepo publishes its scheduled tweets and keeps the ones waiting for tags by their next attempt.
+type scheduledTweetRepo struct {
+	usecase.TweetI
+	now       time.Time
+	scheduled []entity.Tweet
+	untagged  map[string]time.Time
+	contents  map[string]string
+	tags      map[string]map[string][]string
+}
+
+func (r *scheduledTweetRepo) ClaimScheduled(_ context.Context) (entity.Tweet, error) {
+	if len(r.scheduled) == 0 {
+		return entity.Tweet{}, pgx.ErrNoRows
+	}
+
+	tweet := r.scheduled[0]
+	r.scheduled = r.scheduled[1:]
+	r.untagged[tweet.Id] = r.now
+	r.contents[tweet.Id] = tweet.Content
+
+	return tweet, nil
+}
+
+func (r *scheduledTweetRepo) ClaimUntagged(_ context.Context, retryAfter time.Duration) (entity.Tweet, error) {
+	for id, attemptAt := range r.untagged {
+		if !attemptAt.After(r.now) {
+			r.untagged[id] = r.now.Add(retryAfter)
+
+			return entity.Tweet{Id: id, Content: r.contents[id]}, nil
+		}
+	}
+
+	return entity.Tweet{}, pgx.ErrNoRows
+}
+
+func (r *scheduledTweetRepo) UpdateField(_ context.Context, req entity.UpdateFieldRequest) (entity.RowsEffected, error) {
+	id := req.Filter[0].Value
+
+	for _, item := range req.Items {
+		switch item.Column {
+		case "tags":
+			r.tags[id] = item.Value.(map[string][]string)
+		case "tag_attempt_at":
+			if item.Value == nil {
+				delete(r.untagged, id)
+			}
+		}
+	}
+
+	return entity.RowsEffected{RowsEffected: 1}, nil
+}
+
+// failingTagRepo fails to tag the contents in fail.
+type failingTagRepo struct {
+	usecase.TagRepoI
+	fail map[string]bool
+}
+
+func (r *failingTagRepo) TagTweetByContent(_ context.Context, req entity.Tweet) (entity.Tweet, error) {
+	if r.fail[req.Content] {
+		return entity.Tweet{}, errors.New("tags unavailable")
+	}
+
+	req.Tags = map[string][]string{"level3": {req.Content}}
+
+	return req, nil
+}
+
+func TestPublishScheduledTweetsRetriesTagging(t *testing.T) {
+	tweets := &scheduledTweetRepo{
+		now:       time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC),
+		scheduled: []entity.Tweet{{Id: "t1", Content: "#go"}, {Id: "t2", Content: "#rust"}},
+		untagged:  map[string]time.Time{},
+		contents:  map[string]string{},
+		tags:      map[string]map[string][]string{},
+	}
+	tagger := &failingTagRepo{fail: map[string]bool{"#rust": true}}
+	uc := &usecase.UseCase{TweetRepo: tweets, TagRepo: tagger}
+
+	published, err := uc.PublishScheduledTweets(context.Background(), time.Minute)
+	if published != 2 {
+		t.Errorf("published = %d, want 2", published)
+	}
+	if err == nil {
+		t.Error("a failed tagging wasn't reported")
+	}
+	if tweets.tags["t1"] == nil || tweets.tags["t2"] != nil {
+		t.Fatalf("tags = %v, want only t1 tagged", tweets.tags)
+	}
+
+	// not retried before retryAfter
+	tagger.fail = nil
+	_, err = uc.PublishScheduledTweets(context.Background(), time.Minute)
+	if err != nil || tweets.tags["t2"] != nil {
+		t.Fatalf("t2 retried early: %v, %v", tweets.tags["t2"], err)
+	}
+
+	tweets.now = tweets.now.Add(time.Minute)
+	_, err = uc.PublishScheduledTweets(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tweets.tags["t2"] == nil || len(tweets.untagged) != 0 {
+		t.Errorf("t2 not tagged on retry: tags %v, untagged %v", tweets.tags, tweets.untagged)
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE login_attempt (
+  id uuid PRIMARY KEY,
+  user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  identifier varchar(100) NOT NULL,
+  ip_address varchar(64) NOT NULL,
+  user_agent text NOT NULL,
+  success bool NOT NULL,
+  reason varchar(50) NOT NULL DEFAULT '',
+  created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "login_attempt" ("identifier", "created_at");
+CREATE INDEX ON "login_attempt" ("ip_address", "created_at");
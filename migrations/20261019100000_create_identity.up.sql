@@ -0,0 +1,14 @@
+ALTER TYPE gender ADD VALUE IF NOT EXISTS 'unspecified';
+
+CREATE TABLE identity (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider varchar(50) NOT NULL,
+  subject varchar(255) NOT NULL,
+  email varchar(50) NOT NULL DEFAULT '',
+  created_at timestamp NOT NULL DEFAULT now(),
+  updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX ON "identity" ("provider", "subject");
+CREATE INDEX ON "identity" ("user_id");
@@ -0,0 +1,14 @@
+CREATE TABLE api_token (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name varchar(100) NOT NULL,
+  lookup varchar(16) UNIQUE NOT NULL,
+  token_hash varchar(64) NOT NULL,
+  scopes text[] NOT NULL DEFAULT '{}',
+  last_used_at timestamp,
+  expires_at timestamp,
+  created_at timestamp NOT NULL DEFAULT now(),
+  updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "api_token" ("user_id");
@@ -0,0 +1,32 @@
+CREATE TABLE audit_log (
+  id uuid PRIMARY KEY,
+  -- no foreign keys, entries have to outlive the users and resources they mention
+  actor_id uuid,
+  actor_role varchar(50) NOT NULL DEFAULT '',
+  action varchar(100) NOT NULL,
+  method varchar(10) NOT NULL DEFAULT '',
+  path varchar(255) NOT NULL DEFAULT '',
+  target_type varchar(50) NOT NULL DEFAULT '',
+  target_id varchar(255) NOT NULL DEFAULT '',
+  before jsonb,
+  after jsonb,
+  status int NOT NULL DEFAULT 0,
+  reason varchar(100) NOT NULL DEFAULT '',
+  ip_address varchar(64) NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "audit_log" ("created_at");
+CREATE INDEX ON "audit_log" ("actor_id", "created_at");
+CREATE INDEX ON "audit_log" ("target_type", "target_id", "created_at");
+
+-- audit_log is append-only, rows are only ever removed by the retention purge
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
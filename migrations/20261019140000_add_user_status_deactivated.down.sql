@@ -0,0 +1,2 @@
+-- postgres can't drop an enum value, deactivated accounts are restored instead
+UPDATE users SET status = 'active' WHERE status = 'deactivated';
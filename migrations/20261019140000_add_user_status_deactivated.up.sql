@@ -0,0 +1,2 @@
+-- on its own, a new enum value can't be used in the transaction that adds it
+ALTER TYPE user_status ADD VALUE IF NOT EXISTS 'deactivated';
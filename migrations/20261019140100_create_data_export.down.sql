@@ -0,0 +1,2 @@
+DROP TABLE data_export;
+ALTER TABLE users DROP COLUMN delete_scheduled_at;
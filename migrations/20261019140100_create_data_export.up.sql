@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN delete_scheduled_at timestamp;
+
+CREATE INDEX ON "users" ("status", "delete_scheduled_at");
+
+CREATE TABLE data_export (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status varchar(20) NOT NULL DEFAULT 'pending',
+  archive bytea,
+  error text NOT NULL DEFAULT '',
+  expires_at timestamp,
+  created_at timestamp NOT NULL DEFAULT now(),
+  updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "data_export" ("user_id", "created_at");
+CREATE INDEX ON "data_export" ("status", "updated_at");
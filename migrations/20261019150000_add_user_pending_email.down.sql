@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN pending_email_expires_at;
+ALTER TABLE users DROP COLUMN pending_email;
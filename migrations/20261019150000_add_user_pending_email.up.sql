@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN pending_email varchar(50);
+ALTER TABLE users ADD COLUMN pending_email_expires_at timestamp;
+
+-- an address can be claimed by one account at a time
+CREATE UNIQUE INDEX ON "users" ("pending_email");
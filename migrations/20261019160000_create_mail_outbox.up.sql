@@ -0,0 +1,16 @@
+CREATE TABLE mail_outbox (
+  id uuid PRIMARY KEY,
+  recipient varchar(255) NOT NULL,
+  template varchar(50) NOT NULL,
+  subject text NOT NULL,
+  text_body text NOT NULL,
+  html_body text NOT NULL DEFAULT '',
+  status varchar(20) NOT NULL DEFAULT 'pending',
+  attempts int NOT NULL DEFAULT 0,
+  last_error text NOT NULL DEFAULT '',
+  next_attempt_at timestamp NOT NULL DEFAULT now(),
+  created_at timestamp NOT NULL DEFAULT now(),
+  updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "mail_outbox" ("status", "next_attempt_at");
@@ -0,0 +1,2 @@
+DROP TABLE user_moderation;
+ALTER TABLE users DROP COLUMN suspended_until;
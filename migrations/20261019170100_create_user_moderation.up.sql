@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN suspended_until timestamp;
+
+CREATE INDEX ON "users" ("status", "suspended_until");
+
+-- moderator_id has no foreign key, the history outlives the moderator's account
+CREATE TABLE user_moderation (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  moderator_id uuid,
+  action varchar(20) NOT NULL,
+  reason text NOT NULL DEFAULT '',
+  suspended_until timestamp,
+  created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "user_moderation" ("user_id", "created_at");
@@ -0,0 +1,2 @@
+-- postgres can't drop an enum value, moderated tweets go back to their owners as drafts instead
+UPDATE tweet SET status = 'draft' WHERE status IN ('hidden', 'removed');
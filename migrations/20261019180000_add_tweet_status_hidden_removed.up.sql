@@ -0,0 +1,3 @@
+-- on its own, a new enum value can't be used in the transaction that adds it
+ALTER TYPE tweet_status ADD VALUE IF NOT EXISTS 'hidden';
+ALTER TYPE tweet_status ADD VALUE IF NOT EXISTS 'removed';
@@ -0,0 +1,21 @@
+-- target_id has no foreign key, it is a tweet or a user depending on target_type
+CREATE TABLE report (
+  id uuid PRIMARY KEY,
+  reporter_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  target_type varchar(10) NOT NULL,
+  target_id uuid NOT NULL,
+  category varchar(30) NOT NULL,
+  severity smallint NOT NULL,
+  details text NOT NULL DEFAULT '',
+  locale varchar(10) NOT NULL DEFAULT '',
+  status varchar(20) NOT NULL DEFAULT 'open',
+  action varchar(20),
+  resolved_by uuid,
+  resolved_at timestamp,
+  created_at timestamp NOT NULL DEFAULT now()
+);
+
+-- a reporter has one open report per target, reporting again after it is resolved is allowed
+CREATE UNIQUE INDEX ON "report" ("reporter_id", "target_type", "target_id") WHERE status = 'open';
+CREATE INDEX ON "report" ("target_type", "target_id", "status");
+CREATE INDEX ON "report" ("status", "created_at");
@@ -0,0 +1,2 @@
+ALTER TABLE tweet_attachment DROP COLUMN media_id;
+DROP TABLE media;
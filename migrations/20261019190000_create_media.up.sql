@@ -0,0 +1,22 @@
+-- an upload is ready once all of it is stored under storage_key, a chunked upload keeps its chunks apart until then
+CREATE TABLE media (
+  id uuid PRIMARY KEY,
+  owner_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind attachment_type NOT NULL,
+  content_type varchar(100) NOT NULL,
+  size bigint NOT NULL,
+  received bigint NOT NULL DEFAULT 0,
+  chunks int NOT NULL DEFAULT 0,
+  status varchar(20) NOT NULL DEFAULT 'uploading',
+  storage_key varchar NOT NULL,
+  created_at timestamp NOT NULL DEFAULT now(),
+  updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "media" ("owner_id");
+CREATE INDEX ON "media" ("status", "updated_at");
+
+-- attachments made before media uploads keep their client provided filepath and no media
+ALTER TABLE tweet_attachment ADD COLUMN media_id uuid REFERENCES media(id) ON DELETE CASCADE;
+
+CREATE INDEX ON "tweet_attachment" ("media_id");
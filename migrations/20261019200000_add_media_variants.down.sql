@@ -0,0 +1,7 @@
+ALTER TABLE media
+  DROP COLUMN width,
+  DROP COLUMN height,
+  DROP COLUMN blurhash,
+  DROP COLUMN variants,
+  DROP COLUMN variants_status,
+  DROP COLUMN variants_attempts;
@@ -0,0 +1,13 @@
+-- filled in by the image pipeline once a photo is ready, variants_status tells how far it got
+ALTER TABLE media
+  ADD COLUMN width int,
+  ADD COLUMN height int,
+  ADD COLUMN blurhash varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN variants jsonb NOT NULL DEFAULT '{}',
+  ADD COLUMN variants_status varchar(20) NOT NULL DEFAULT 'none',
+  ADD COLUMN variants_attempts int NOT NULL DEFAULT 0;
+
+CREATE INDEX ON "media" ("variants_status", "updated_at");
+
+-- photos uploaded before the pipeline get their variants too
+UPDATE media SET variants_status = 'pending' WHERE kind = 'photo' AND status = 'ready';
@@ -0,0 +1,5 @@
+UPDATE media SET variants_status = 'none' WHERE kind = 'video';
+
+ALTER TABLE media
+  DROP COLUMN duration,
+  DROP COLUMN variants_error;
@@ -0,0 +1,7 @@
+-- duration of a video, and why its processing failed or it was rejected
+ALTER TABLE media
+  ADD COLUMN duration double precision,
+  ADD COLUMN variants_error text NOT NULL DEFAULT '';
+
+-- videos uploaded before transcoding get their renditions too
+UPDATE media SET variants_status = 'pending' WHERE kind = 'video' AND status = 'ready';
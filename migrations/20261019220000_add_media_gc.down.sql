@@ -0,0 +1,10 @@
+DELETE FROM media WHERE owner_id IS NULL;
+
+ALTER TABLE media
+  DROP CONSTRAINT media_owner_id_fkey,
+  ADD CONSTRAINT media_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+  ALTER COLUMN owner_id SET NOT NULL;
+
+DROP INDEX users_avatar_id_idx;
+
+ALTER TABLE media DROP COLUMN orphaned_at;
@@ -0,0 +1,12 @@
+-- media no tweet attaches and no user has as avatar is marked orphaned by the gc job and deleted with its files
+-- once it has stayed orphaned for the grace period
+ALTER TABLE media ADD COLUMN orphaned_at timestamp;
+
+CREATE INDEX ON "media" ("orphaned_at");
+CREATE INDEX ON "users" ("avatar_id");
+
+-- media of a deleted user is left to the gc job, deleting the row would lose track of its files
+ALTER TABLE media
+  ALTER COLUMN owner_id DROP NOT NULL,
+  DROP CONSTRAINT media_owner_id_fkey,
+  ADD CONSTRAINT media_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE tweet_attachment
+  DROP CONSTRAINT tweet_attachment_position_key,
+  DROP COLUMN position,
+  DROP COLUMN alt_text;
@@ -0,0 +1,15 @@
+-- attachments are shown in position order, existing ones keep the order they were added in
+ALTER TABLE tweet_attachment
+  ADD COLUMN position int NOT NULL DEFAULT 0,
+  ADD COLUMN alt_text varchar(1000) NOT NULL DEFAULT '';
+
+UPDATE tweet_attachment SET position = ordered.position
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY tweet_id ORDER BY created_at, id) - 1 AS position
+  FROM tweet_attachment
+) ordered
+WHERE tweet_attachment.id = ordered.id;
+
+-- checked at commit, so attachments can swap positions within a transaction
+ALTER TABLE tweet_attachment
+  ADD CONSTRAINT tweet_attachment_position_key UNIQUE (tweet_id, position) DEFERRABLE INITIALLY DEFERRED;
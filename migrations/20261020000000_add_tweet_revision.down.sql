@@ -0,0 +1,6 @@
+DROP TABLE tweet_revision;
+
+ALTER TABLE tweet
+  DROP COLUMN published_at,
+  DROP COLUMN edited_at,
+  DROP COLUMN edit_count;
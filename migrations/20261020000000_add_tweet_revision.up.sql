@@ -0,0 +1,19 @@
+-- the content of a published tweet can be edited for a while after it is published, the edit window starts at
+-- published_at and every edit keeps the content it replaced as a revision
+ALTER TABLE tweet
+  ADD COLUMN published_at timestamp,
+  ADD COLUMN edited_at timestamp,
+  ADD COLUMN edit_count int NOT NULL DEFAULT 0;
+
+UPDATE tweet SET published_at = created_at WHERE status <> 'draft';
+
+CREATE TABLE tweet_revision (
+  id uuid PRIMARY KEY,
+  tweet_id uuid NOT NULL REFERENCES tweet(id) ON DELETE CASCADE,
+  content text NOT NULL,
+  tags json,
+  -- when this content was published or edited in, not when it was replaced
+  created_at timestamp NOT NULL DEFAULT 'now()'
+);
+
+CREATE INDEX tweet_revision_tweet_id_idx ON tweet_revision (tweet_id, created_at);
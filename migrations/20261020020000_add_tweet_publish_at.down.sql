@@ -0,0 +1,3 @@
+DROP INDEX tweet_publish_at_idx;
+
+ALTER TABLE tweet DROP COLUMN publish_at;
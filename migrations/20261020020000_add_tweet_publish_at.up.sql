@@ -0,0 +1,4 @@
+-- scheduled tweets are published at publish_at by the scheduler, which clears it
+ALTER TABLE tweet ADD COLUMN publish_at timestamp;
+
+CREATE INDEX tweet_publish_at_idx ON tweet (publish_at) WHERE status = 'scheduled';
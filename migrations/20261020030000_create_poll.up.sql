@@ -0,0 +1,32 @@
+-- voting is open for duration_minutes after the tweet is published, the counts of the options are frozen
+-- into poll_option.votes when the poll is closed
+CREATE TABLE poll (
+  tweet_id uuid PRIMARY KEY REFERENCES tweet(id) ON DELETE CASCADE,
+  duration_minutes int NOT NULL,
+  closed_at timestamp,
+  created_at timestamp NOT NULL DEFAULT 'now()'
+);
+
+CREATE INDEX poll_open_idx ON poll (tweet_id) WHERE closed_at IS NULL;
+
+CREATE TABLE poll_option (
+  id uuid PRIMARY KEY,
+  tweet_id uuid NOT NULL REFERENCES poll(tweet_id) ON DELETE CASCADE,
+  position int NOT NULL,
+  text varchar(25) NOT NULL,
+  votes int NOT NULL DEFAULT 0,
+  UNIQUE (tweet_id, position),
+  UNIQUE (tweet_id, id)
+);
+
+-- one vote per user and poll, for an option of that poll
+CREATE TABLE poll_vote (
+  tweet_id uuid NOT NULL REFERENCES poll(tweet_id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  option_id uuid NOT NULL,
+  created_at timestamp NOT NULL DEFAULT 'now()',
+  PRIMARY KEY (tweet_id, user_id),
+  FOREIGN KEY (tweet_id, option_id) REFERENCES poll_option(tweet_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX poll_vote_option_id_idx ON poll_vote (option_id);
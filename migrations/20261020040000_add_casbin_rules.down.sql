@@ -0,0 +1,12 @@
+DELETE FROM casbin_rule
+WHERE (ptype, v0, v1, v2) IN (
+  ('p', 'user', '/v1/user/:id/report', 'POST'),
+  ('p', 'user', '/v1/media', 'POST'),
+  ('p', 'user', '/v1/media/*', 'GET|POST|PUT'),
+  ('p', 'unauthorized', '/v1/media/:id', 'GET'),
+  ('p', 'admin', '/v1/admin/audit', 'GET'),
+  ('p', 'admin', '/v1/admin/users/*', 'GET|POST'),
+  ('p', 'admin', '/v1/admin/reports', 'GET'),
+  ('p', 'admin', '/v1/admin/reports/*', 'POST'),
+  ('g', 'superadmin', 'admin', '')
+);
@@ -0,0 +1,16 @@
+-- rules added to config/policy.csv after the table was seeded, a fresh table gets them from the seed
+INSERT INTO casbin_rule (ptype, v0, v1, v2)
+SELECT rule.ptype, rule.v0, rule.v1, rule.v2
+FROM (VALUES
+  ('p', 'user', '/v1/user/:id/report', 'POST'),
+  ('p', 'user', '/v1/media', 'POST'),
+  ('p', 'user', '/v1/media/*', 'GET|POST|PUT'),
+  ('p', 'unauthorized', '/v1/media/:id', 'GET'),
+  ('p', 'admin', '/v1/admin/audit', 'GET'),
+  ('p', 'admin', '/v1/admin/users/*', 'GET|POST'),
+  ('p', 'admin', '/v1/admin/reports', 'GET'),
+  ('p', 'admin', '/v1/admin/reports/*', 'POST'),
+  ('g', 'superadmin', 'admin', '')
+) AS rule (ptype, v0, v1, v2)
+WHERE EXISTS (SELECT 1 FROM casbin_rule)
+ON CONFLICT DO NOTHING;
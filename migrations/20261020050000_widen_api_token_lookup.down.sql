@@ -0,0 +1,3 @@
+DELETE FROM api_token WHERE length(lookup) > 16;
+
+ALTER TABLE api_token ALTER COLUMN lookup TYPE varchar(16);
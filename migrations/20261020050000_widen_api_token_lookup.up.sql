@@ -0,0 +1,2 @@
+-- lookups of new tokens are 32 hex characters, existing tokens keep their shorter ones
+ALTER TABLE api_token ALTER COLUMN lookup TYPE varchar(64);
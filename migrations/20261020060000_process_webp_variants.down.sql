@@ -0,0 +1,2 @@
+UPDATE media SET variants_status = 'skipped'
+WHERE content_type = 'image/webp' AND variants_status IN ('pending', 'processing', 'failed');
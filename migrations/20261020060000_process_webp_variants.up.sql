@@ -0,0 +1,3 @@
+-- webp photos were skipped when they couldn't be decoded, the still ones get their variants now
+UPDATE media SET variants_status = 'pending', variants_attempts = 0, variants_error = ''
+WHERE content_type = 'image/webp' AND variants_status = 'skipped';
@@ -0,0 +1 @@
+-- detached attachments can't be told apart anymore, they stay detached
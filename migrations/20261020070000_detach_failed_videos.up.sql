@@ -0,0 +1,4 @@
+-- videos that failed or were rejected are detached from their tweets, as the video pipeline does now
+DELETE FROM tweet_attachment WHERE media_id IN (
+  SELECT id FROM media WHERE kind = 'video' AND variants_status IN ('failed', 'rejected')
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS tweet_tag_attempt_at_idx;
+
+ALTER TABLE tweet DROP COLUMN IF EXISTS tag_attempt_at;
@@ -0,0 +1,7 @@
+-- published tweets that still need their tags, retried by the scheduler at tag_attempt_at until tagging succeeds
+ALTER TABLE tweet ADD COLUMN tag_attempt_at timestamp;
+
+CREATE INDEX tweet_tag_attempt_at_idx ON tweet (tag_attempt_at) WHERE tag_attempt_at IS NOT NULL;
+
+-- scheduled tweets published while tagging failed
+UPDATE tweet SET tag_attempt_at = now() AT TIME ZONE 'UTC' WHERE status = 'published' AND (tags IS NULL OR tags::text = 'null');
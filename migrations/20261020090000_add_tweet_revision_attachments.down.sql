@@ -0,0 +1 @@
+ALTER TABLE tweet_revision DROP COLUMN IF EXISTS attachments;
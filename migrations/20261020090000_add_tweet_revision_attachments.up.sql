@@ -0,0 +1,2 @@
+-- edits of the attachments of a published tweet are revisions too, a revision keeps the attachments it replaced
+ALTER TABLE tweet_revision ADD COLUMN attachments json NOT NULL DEFAULT '[]';
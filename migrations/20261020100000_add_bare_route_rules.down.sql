@@ -0,0 +1,11 @@
+DELETE FROM casbin_rule
+WHERE (ptype, v0, v1, v2) IN (
+  ('p', 'user', '/v1/user', 'PUT'),
+  ('p', 'admin', '/v1/user', 'POST'),
+  ('p', 'user', '/v1/session', 'PUT'),
+  ('p', 'admin', '/v1/tag', 'POST|PUT'),
+  ('p', 'user', '/v1/tag/*', 'GET'),
+  ('p', 'user', '/v1/follower/*', 'GET'),
+  ('p', 'user', '/v1/tweet', 'POST|PUT'),
+  ('p', 'superadmin', '/v1/admin/rbac/*', 'GET|POST|DELETE')
+);
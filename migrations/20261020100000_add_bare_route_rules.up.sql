@@ -0,0 +1,16 @@
+-- keyMatch doesn't match /v1/tweet against /v1/tweet/*, so routes without a trailing segment need rules of their own.
+-- the rbac admin routes lost their only grant with the superadmin matcher bypass.
+INSERT INTO casbin_rule (ptype, v0, v1, v2)
+SELECT rule.ptype, rule.v0, rule.v1, rule.v2
+FROM (VALUES
+  ('p', 'user', '/v1/user', 'PUT'),
+  ('p', 'admin', '/v1/user', 'POST'),
+  ('p', 'user', '/v1/session', 'PUT'),
+  ('p', 'admin', '/v1/tag', 'POST|PUT'),
+  ('p', 'user', '/v1/tag/*', 'GET'),
+  ('p', 'user', '/v1/follower/*', 'GET'),
+  ('p', 'user', '/v1/tweet', 'POST|PUT'),
+  ('p', 'superadmin', '/v1/admin/rbac/*', 'GET|POST|DELETE')
+) AS rule (ptype, v0, v1, v2)
+WHERE EXISTS (SELECT 1 FROM casbin_rule)
+ON CONFLICT DO NOTHING;
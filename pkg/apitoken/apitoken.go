@@ -0,0 +1,57 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix marks personal access tokens so they can be told apart from JWTs.
+const Prefix = "mtw_"
+
+// Generate returns a new token in the form mtw_<lookup>_<secret>, its lookup part and the hash of its secret.
+// Only the lookup and the hash are meant to be stored.
+func Generate() (token, lookup, secretHash string, err error) {
+	l := make([]byte, 16)
+	if _, err = rand.Read(l); err != nil {
+		return "", "", "", err
+	}
+
+	s := make([]byte, 32)
+	if _, err = rand.Read(s); err != nil {
+		return "", "", "", err
+	}
+
+	lookup = hex.EncodeToString(l)
+	secret := base64.RawURLEncoding.EncodeToString(s)
+
+	return Prefix + lookup + "_" + secret, lookup, Hash(secret), nil
+}
+
+// Parse splits a token into its lookup and secret parts.
+func Parse(token string) (lookup, secret string, ok bool) {
+	if !strings.HasPrefix(token, Prefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(token, Prefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// Hash returns the hex encoded sha256 of the secret.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify compares secret with a stored hash in constant time.
+func Verify(secret, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(secret)), []byte(secretHash)) == 1
+}
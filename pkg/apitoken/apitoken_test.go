@@ -0,0 +1,86 @@
+package apitoken_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/apitoken"
+)
+
+func TestGenerate(t *testing.T) {
+	token, lookup, secretHash, err := apitoken.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(token, apitoken.Prefix+lookup+"_") {
+		t.Errorf("token %q doesn't start with its lookup %q", token, lookup)
+	}
+	if len(lookup) != 32 {
+		t.Errorf("lookup %q has %d characters, want 32", lookup, len(lookup))
+	}
+	if strings.Contains(token, secretHash) {
+		t.Error("token contains the hash of its secret")
+	}
+
+	parsedLookup, secret, ok := apitoken.Parse(token)
+	if !ok || parsedLookup != lookup {
+		t.Fatalf("Parse(%q) = %q, %v, want lookup %q", token, parsedLookup, ok, lookup)
+	}
+	if !apitoken.Verify(secret, secretHash) {
+		t.Error("secret of a generated token doesn't verify against its hash")
+	}
+}
+
+func TestGenerateIsUnique(t *testing.T) {
+	lookups := map[string]bool{}
+
+	for i := 0; i < 1000; i++ {
+		_, lookup, _, err := apitoken.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lookups[lookup] {
+			t.Fatalf("lookup %q was generated twice", lookup)
+		}
+		lookups[lookup] = true
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		token          string
+		lookup, secret string
+		ok             bool
+	}{
+		{"mtw_abc_secret", "abc", "secret", true},
+		{"mtw_abc_sec_ret", "abc", "sec_ret", true},
+		{"abc_secret", "", "", false},
+		{"mtw_abc", "", "", false},
+		{"mtw__secret", "", "", false},
+		{"mtw_abc_", "", "", false},
+		{"eyJhbGciOiJIUzI1NiJ9.e30.sig", "", "", false},
+	}
+
+	for _, tt := range tests {
+		lookup, secret, ok := apitoken.Parse(tt.token)
+		if lookup != tt.lookup || secret != tt.secret || ok != tt.ok {
+			t.Errorf("Parse(%q) = %q, %q, %v, want %q, %q, %v", tt.token, lookup, secret, ok, tt.lookup, tt.secret, tt.ok)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secretHash := apitoken.Hash("secret")
+
+	if !apitoken.Verify("secret", secretHash) {
+		t.Error("Verify rejects the right secret")
+	}
+	if apitoken.Verify("Secret", secretHash) {
+		t.Error("Verify accepts a wrong secret")
+	}
+	if apitoken.Verify("", "") {
+		t.Error("Verify accepts an empty hash")
+	}
+}
@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// blurhashSample is the size img is scaled down to before the blurhash is computed, a placeholder needs no detail.
+const blurhashSample = 32
+
+// Blurhash returns the blurhash (https://blurha.sh) of img, with 4x3 components for a landscape image and
+// 3x4 for a portrait one.
+func Blurhash(img *image.RGBA) string {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	xComponents, yComponents := 4, 3
+	if h > w {
+		xComponents, yComponents = 3, 4
+	}
+
+	w, h = Fit(w, h, blurhashSample)
+	img = Resize(img, w, h)
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var r, g, b float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := normalisation * math.Cos(math.Pi*float64(i*x)/float64(w)) * math.Cos(math.Pi*float64(j*y)/float64(h))
+					p := img.Pix[img.PixOffset(x, y):]
+					r += basis * srgbToLinear(p[0])
+					g += basis * srgbToLinear(p[1])
+					b += basis * srgbToLinear(p[2])
+				}
+			}
+
+			scale := 1 / float64(w*h)
+			factors = append(factors, [3]float64{r * scale, g * scale, b * scale})
+		}
+	}
+
+	var hash strings.Builder
+	encodeBase83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+
+	maximum := 0.0
+	for _, factor := range factors[1:] {
+		for _, c := range factor {
+			maximum = math.Max(maximum, math.Abs(c))
+		}
+	}
+
+	quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(maximum*166-0.5))))
+	maximum = float64(quantisedMaximum+1) / 166
+	encodeBase83(&hash, quantisedMaximum, 1)
+
+	dc := factors[0]
+	encodeBase83(&hash, linearToSrgb(dc[0])<<16|linearToSrgb(dc[1])<<8|linearToSrgb(dc[2]), 4)
+
+	for _, factor := range factors[1:] {
+		var q [3]int
+		for c := range factor {
+			q[c] = int(math.Max(0, math.Min(18, math.Floor(signPow(factor[c]/maximum, 0.5)*9+9.5))))
+		}
+		encodeBase83(&hash, q[0]*19*19+q[1]*19+q[2], 2)
+	}
+
+	return hash.String()
+}
+
+func encodeBase83(b *strings.Builder, value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		digit := value
+		for j := 0; j < i; j++ {
+			digit /= 83
+		}
+		b.WriteByte(base83[digit%83])
+	}
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSrgb(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
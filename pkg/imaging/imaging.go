@@ -0,0 +1,150 @@
+// Package imaging makes the derived variants of uploaded photos: decoding with a pixel limit, orientation,
+// resizing, metadata stripping, blurhash placeholders and WebP output, the WebP encoding being left to ffmpeg.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	// decoders of the formats Decode reads
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ErrTooLarge is returned by Decode for an image of more pixels than allowed.
+var ErrTooLarge = errors.New("imaging: image has too many pixels")
+
+// Decode decodes a jpeg, png, gif (its first frame) or still webp into RGBA. The dimensions are checked against maxPixels
+// before any pixel is decoded, so a small file can't claim a huge image.
+func Decode(data []byte, maxPixels int) (*image.RGBA, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("imaging: %w", err)
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, format, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, fmt.Errorf("imaging: %w", err)
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+
+	return rgba, format, nil
+}
+
+// Orient turns img the way an EXIF orientation (1-8) says it is meant to be shown.
+func Orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+// Fit returns the size of a width x height image scaled down to fit in a maxSize square, it's never scaled up.
+func Fit(width, height, maxSize int) (int, int) {
+	if width <= maxSize && height <= maxSize {
+		return width, height
+	}
+
+	if width >= height {
+		return maxSize, max(1, (height*maxSize+width/2)/width)
+	}
+
+	return max(1, (width*maxSize+height/2)/height), maxSize
+}
+
+// Resize scales img down to width x height by averaging the source pixels each target pixel covers.
+// Averaging premultiplied colors keeps transparent pixels from darkening the edges.
+func Resize(img *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := img.Rect.Dx(), img.Rect.Dy()
+	if width == sw && height == sh {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sums := make([]uint64, width*4)
+	counts := make([]uint64, width)
+
+	for y := 0; y < height; y++ {
+		y0 := y * sh / height
+		y1 := max(y0+1, (y+1)*sh/height)
+
+		for i := range sums {
+			sums[i] = 0
+		}
+		for i := range counts {
+			counts[i] = 0
+		}
+
+		for sy := y0; sy < y1; sy++ {
+			row := img.Pix[img.PixOffset(0, sy):]
+			for x := 0; x < width; x++ {
+				x0 := x * sw / width
+				x1 := max(x0+1, (x+1)*sw/width)
+
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					sums[x*4] += uint64(p[0])
+					sums[x*4+1] += uint64(p[1])
+					sums[x*4+2] += uint64(p[2])
+					sums[x*4+3] += uint64(p[3])
+				}
+				counts[x] += uint64(x1 - x0)
+			}
+		}
+
+		out := dst.Pix[dst.PixOffset(0, y):]
+		for x := 0; x < width; x++ {
+			n := counts[x]
+			for c := 0; c < 4; c++ {
+				out[x*4+c] = uint8((sums[x*4+c] + n/2) / n)
+			}
+		}
+	}
+
+	return dst
+}
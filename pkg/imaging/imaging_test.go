@@ -0,0 +1,273 @@
+package imaging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os/exec"
+	"testing"
+
+	"golang.org/x/image/webp"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/imaging"
+)
+
+func solid(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+
+	return img
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, max    int
+		wantW, wantH int
+	}{
+		{4000, 3000, 1200, 1200, 900},
+		{3000, 4000, 600, 450, 600},
+		{100, 50, 150, 100, 50},
+		{5000, 1, 150, 150, 1},
+	}
+
+	for _, tt := range tests {
+		w, h := imaging.Fit(tt.w, tt.h, tt.max)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("Fit(%d, %d, %d) = %d, %d, want %d, %d", tt.w, tt.h, tt.max, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestResizeAverages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+	img.SetRGBA(1, 0, color.RGBA{200, 100, 50, 255})
+
+	got := imaging.Resize(img, 1, 1).RGBAAt(0, 0)
+	if want := (color.RGBA{100, 50, 25, 255}); got != want {
+		t.Errorf("Resize = %v, want %v", got, want)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// a 2x1 image with a red pixel on the left, orientation 6 turns it clockwise into a 1x2 with red on top
+	img := solid(2, 1, color.RGBA{0, 0, 255, 255})
+	img.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+
+	got := imaging.Orient(img, 6)
+	if got.Rect.Dx() != 1 || got.Rect.Dy() != 2 {
+		t.Fatalf("Orient size = %v", got.Rect)
+	}
+	if got.RGBAAt(0, 0).R != 255 {
+		t.Errorf("Orient top pixel = %v, want red", got.RGBAAt(0, 0))
+	}
+}
+
+func TestBlurhash(t *testing.T) {
+	if got, want := imaging.Blurhash(solid(40, 30, color.RGBA{0, 0, 0, 255})), "L00000fQfQfQfQfQfQfQfQfQfQfQ"; got != want {
+		t.Errorf("Blurhash(black) = %q, want %q", got, want)
+	}
+	// 3x4 components, with a white average color
+	if got := imaging.Blurhash(solid(30, 40, color.RGBA{255, 255, 255, 255})); len(got) != 28 || got[0] != 'T' || got[2:6] != "TSUA" {
+		t.Errorf("Blurhash(white portrait) = %q", got)
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, solid(8, 4, color.RGBA{10, 20, 30, 255}), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// exif with orientation 6 and a gps ifd pointer, inserted right after SOI
+	tiff := []byte("II\x2a\x00\x08\x00\x00\x00\x02\x00" +
+		"\x12\x01\x03\x00\x01\x00\x00\x00\x06\x00\x00\x00" +
+		"\x25\x88\x04\x00\x01\x00\x00\x00\x26\x00\x00\x00" +
+		"\x00\x00\x00\x00")
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xff, 0xe1}, binary.BigEndian.AppendUint16(nil, uint16(len(payload)+2))...)
+	segment = append(segment, payload...)
+	comment := []byte("\xff\xfe\x00\x0esecret place")
+
+	data := append([]byte{0xff, 0xd8}, segment...)
+	data = append(data, comment...)
+	data = append(data, buf.Bytes()[2:]...)
+
+	if got := imaging.JPEGOrientation(data); got != 6 {
+		t.Fatalf("JPEGOrientation = %d, want 6", got)
+	}
+
+	stripped, err := imaging.StripMetadata(data, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(stripped, []byte("secret place")) || bytes.Contains(stripped, []byte("\x25\x88")) {
+		t.Error("metadata left in the stripped jpeg")
+	}
+	if got := imaging.JPEGOrientation(stripped); got != 6 {
+		t.Errorf("orientation of the stripped jpeg = %d, want 6", got)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped jpeg doesn't decode: %v", err)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solid(2, 2, color.RGBA{1, 2, 3, 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	// a tEXt chunk after IHDR, the crc isn't checked by the stripper
+	data := buf.Bytes()
+	ihdrEnd := 8 + 12 + 13
+	text := append(binary.BigEndian.AppendUint32(nil, 10), "tEXtAuthor\x00me!"...)
+	text = append(text, 0, 0, 0, 0)
+	data = append(append(append([]byte(nil), data[:ihdrEnd]...), text...), data[ihdrEnd:]...)
+
+	stripped, err := imaging.StripMetadata(data, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(stripped, []byte("tEXt")) {
+		t.Error("tEXt chunk left in the stripped png")
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped png doesn't decode: %v", err)
+	}
+}
+
+// ffmpegWebP returns an encoder running the ffmpeg on the path, the test is skipped without one.
+func ffmpegWebP(t *testing.T, quality int) *imaging.WebPEncoder {
+	t.Helper()
+
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+
+	return imaging.NewWebPEncoder(ffmpeg, quality)
+}
+
+func TestEncodeWebP(t *testing.T) {
+	encoder := ffmpegWebP(t, 80)
+
+	img := solid(300, 200, color.RGBA{10, 200, 30, 255})
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			img.SetRGBA(x, y, color.RGBA{200, 40, 40, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(context.Background(), &buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if len(data) < 16 || string(data[:4]) != "RIFF" || string(data[8:16]) != "WEBPVP8 " {
+		t.Fatalf("not a lossy webp: % x", data[:min(len(data), 16)])
+	}
+
+	decoded, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("webp doesn't decode: %v", err)
+	}
+	if got := decoded.Bounds(); got.Dx() != 300 || got.Dy() != 200 {
+		t.Fatalf("webp size = %v, want 300x200", got)
+	}
+
+	near := func(got color.Color, want color.RGBA) bool {
+		r, g, b, _ := got.RGBA()
+		return abs(int(r>>8)-int(want.R)) <= 16 && abs(int(g>>8)-int(want.G)) <= 16 && abs(int(b>>8)-int(want.B)) <= 16
+	}
+	if got := decoded.At(150, 40); !near(got, color.RGBA{200, 40, 40, 255}) {
+		t.Errorf("top pixel = %v, want close to red", got)
+	}
+	if got := decoded.At(150, 160); !near(got, color.RGBA{10, 200, 30, 255}) {
+		t.Errorf("bottom pixel = %v, want close to green", got)
+	}
+}
+
+func TestEncodeWebPAlpha(t *testing.T) {
+	encoder := ffmpegWebP(t, 80)
+
+	img := solid(64, 64, color.RGBA{0, 0, 0, 0})
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 32; x++ {
+			img.SetRGBA(x, y, color.RGBA{0, 0, 200, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.Encode(context.Background(), &buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("webp doesn't decode: %v", err)
+	}
+
+	if _, _, _, a := decoded.At(10, 32).RGBA(); a>>8 < 240 {
+		t.Errorf("alpha of the opaque half = %d", a>>8)
+	}
+	if _, _, _, a := decoded.At(54, 32).RGBA(); a>>8 > 15 {
+		t.Errorf("alpha of the transparent half = %d", a>>8)
+	}
+}
+
+func TestDecodeWebP(t *testing.T) {
+	// a 3x2 lossless webp, red over blue
+	data, err := hex.DecodeString("5249464684000000574542505650384c780000002f024000008d6444040090082000000000000000000000" +
+		"000000000000000000000000000000000000000000000010402401000000000000001800000000000000000000000000000003000000" +
+		"000000000040220000000000000000000000000000000000000000000000080000000000000020e2000500")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, format, err := imaging.Decode(data, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if format != "webp" || img.Rect.Dx() != 3 || img.Rect.Dy() != 2 {
+		t.Fatalf("Decode = %s %v, want a 3x2 webp", format, img.Rect)
+	}
+	if got, want := img.RGBAAt(1, 0), (color.RGBA{200, 10, 10, 255}); got != want {
+		t.Errorf("top pixel = %v, want %v", got, want)
+	}
+	if got, want := img.RGBAAt(1, 1), (color.RGBA{10, 10, 200, 255}); got != want {
+		t.Errorf("bottom pixel = %v, want %v", got, want)
+	}
+
+	if _, _, err = imaging.Decode(data, 5); !errors.Is(err, imaging.ErrTooLarge) {
+		t.Errorf("Decode over the pixel limit = %v, want ErrTooLarge", err)
+	}
+	if imaging.WebPAnimated(data) {
+		t.Error("still webp taken for an animated one")
+	}
+
+	animated := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x12\x00\x00\x00\x02\x00\x00\x01\x00\x00")
+	if !imaging.WebPAnimated(animated) {
+		t.Error("animated webp not recognised")
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
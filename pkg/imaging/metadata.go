@@ -0,0 +1,240 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrMalformed is returned for a file whose structure can't be walked to strip its metadata.
+var ErrMalformed = errors.New("imaging: malformed file")
+
+// pngMetadataChunks are the ancillary png chunks that can carry text, exif or timestamps.
+var pngMetadataChunks = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// StripMetadata removes exif, xmp and comments from a jpeg, png or webp, other content types are returned as
+// they are. The orientation of a jpeg is kept, in an exif of its own, so the original still shows upright.
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+
+	return data, nil
+}
+
+// JPEGOrientation returns the exif orientation of a jpeg, 1 when it has none.
+func JPEGOrientation(data []byte) int {
+	orientation := 1
+
+	_, _ = walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			orientation = exifOrientation(segment[6:])
+			return false
+		}
+
+		return true
+	})
+
+	return orientation
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+
+	// the orientation exif follows the jfif app0, which has to come first
+	orientation := JPEGOrientation(data)
+	oriented := orientation == 1
+
+	scan, err := walkJPEG(data, func(marker byte, segment []byte) bool {
+		// the icc profile and adobe's app14 change how the pixels are decoded and are kept
+		keep := marker <= 0xe0 || marker == 0xee || (marker == 0xe2 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")))
+		if !keep {
+			return true
+		}
+
+		if !oriented && marker != 0xe0 {
+			out = append(out, orientationExif(orientation)...)
+			oriented = true
+		}
+
+		out = append(out, 0xff, marker)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+		out = append(out, segment...)
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !oriented {
+		out = append(out, orientationExif(orientation)...)
+	}
+
+	// the scans and everything after them are copied as they are
+	return append(out, data[scan:]...), nil
+}
+
+// walkJPEG calls fn with the marker and payload of every segment before the first scan, until fn returns false.
+// It returns the offset of the first scan.
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 0, ErrMalformed
+	}
+
+	for i := 2; ; {
+		if i+4 > len(data) || data[i] != 0xff {
+			return 0, ErrMalformed
+		}
+
+		marker := data[i+1]
+		if marker == 0xff {
+			// fill byte
+			i++
+			continue
+		}
+		if marker == 0xda {
+			return i, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 0, ErrMalformed
+		}
+
+		if !fn(marker, data[i+4:i+2+length]) {
+			return i, nil
+		}
+
+		i += 2 + length
+	}
+}
+
+// exifOrientation reads the orientation tag of ifd0 of a tiff structure, 1 when it's missing or invalid.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// orientationExif is an app1 segment with an exif of the orientation tag only.
+func orientationExif(orientation int) []byte {
+	segment := []byte{0xff, 0xe1, 0x00, 0x22}
+	segment = append(segment, "Exif\x00\x00"...)
+	segment = append(segment, "MM\x00\x2a\x00\x00\x00\x08"...)
+	segment = append(segment, 0x00, 0x01)
+	segment = append(segment, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, byte(orientation), 0x00, 0x00)
+	segment = append(segment, 0x00, 0x00, 0x00, 0x00)
+
+	return segment
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, ErrMalformed
+		}
+
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+
+		i = end
+	}
+
+	return out, nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) || end < i {
+			return nil, ErrMalformed
+		}
+
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				// clear the exif and xmp flags
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+
+	return out, nil
+}
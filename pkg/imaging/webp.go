@@ -0,0 +1,76 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// webpMaxDimension is the largest width or height a WebP image can have.
+const webpMaxDimension = 1 << 14
+
+// WebPEncoder encodes lossy WebP with the libwebp encoder of the ffmpeg binary.
+type WebPEncoder struct {
+	ffmpeg  string
+	quality int
+}
+
+// NewWebPEncoder -.
+func NewWebPEncoder(ffmpeg string, quality int) *WebPEncoder {
+	return &WebPEncoder{ffmpeg: ffmpeg, quality: quality}
+}
+
+// Encode writes img as a lossy WebP of the quality (0-100) of the encoder. Raw pixels are piped to ffmpeg, an
+// alpha channel is only kept when img has transparent pixels.
+func (e *WebPEncoder) Encode(ctx context.Context, w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width > webpMaxDimension || height > webpMaxDimension {
+		return errors.New("imaging: webp image dimensions out of range")
+	}
+
+	// ffmpeg reads rgba as straight alpha, rows with no padding
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+
+	pixFmt := "yuv420p"
+	if !nrgba.Opaque() {
+		pixFmt = "yuva420p"
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, e.ffmpeg, "-nostdin", "-v", "error",
+		"-f", "rawvideo", "-pix_fmt", "rgba", "-s", fmt.Sprintf("%dx%d", width, height), "-i", "pipe:0",
+		"-frames:v", "1", "-c:v", "libwebp", "-lossless", "0", "-quality", strconv.Itoa(e.quality),
+		"-pix_fmt", pixFmt, "-f", "webp", "pipe:1")
+	cmd.Stdin = bytes.NewReader(nrgba.Pix)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+
+		return fmt.Errorf("imaging: %s: %w: %s", filepath.Base(e.ffmpeg), err, msg)
+	}
+
+	_, err := w.Write(stdout.Bytes())
+
+	return err
+}
+
+// WebPAnimated reports whether data is an animated WebP, going by the animation flag of its VP8X header.
+func WebPAnimated(data []byte) bool {
+	return len(data) > 20 && string(data[:4]) == "RIFF" && string(data[8:16]) == "WEBPVP8X" && data[20]&0x02 != 0
+}
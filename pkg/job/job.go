@@ -0,0 +1,57 @@
+// Package job runs periodic background work of a replica.
+package job
+
+import (
+	"context"
+	"time"
+)
+
+// Job runs fn every interval until Stop is called.
+// Every replica runs its own jobs, so fn has to be safe to run concurrently on several replicas.
+type Job struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Every starts fn right away and then every interval, errors are passed to onError.
+// A non-positive interval disables the job.
+func Every(interval time.Duration, fn func(ctx context.Context) error, onError func(error)) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &Job{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	if interval <= 0 {
+		close(j.done)
+		return j
+	}
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+// Stop cancels the running fn and waits for it to return.
+func (j *Job) Stop() {
+	j.cancel()
+	<-j.done
+}
@@ -0,0 +1,124 @@
+package lru_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/lru"
+)
+
+func TestGetSet(t *testing.T) {
+	c := lru.New[int](2, time.Minute)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get on an empty cache found a value")
+	}
+
+	c.Set("a", 1)
+	c.Set("a", 2)
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Errorf("Get(a) = %d, %v, want 2, true", v, ok)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	c := lru.New[int](2, time.Minute)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b, the least recently used, was kept")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a was evicted although it was used last")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c was evicted right after it was set")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestExpires(t *testing.T) {
+	c := lru.New[int](2, 10*time.Millisecond)
+
+	c.Set("a", 1)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expired entry was returned")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d, want the expired entry removed", c.Len())
+	}
+}
+
+func TestDeleteAndPurge(t *testing.T) {
+	c := lru.New[int](3, time.Minute)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Delete("a")
+	c.Delete("missing")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("deleted entry was returned")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("b was deleted with a")
+	}
+
+	c.Purge()
+	if _, ok := c.Get("b"); ok || c.Len() != 0 {
+		t.Errorf("Purge() left %d entries", c.Len())
+	}
+
+	// the cache is usable after a purge
+	c.Set("c", 3)
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) after Purge = %d, %v, want 3, true", v, ok)
+	}
+}
+
+func TestSizeAtLeastOne(t *testing.T) {
+	c := lru.New[int](0, time.Minute)
+
+	c.Set("a", 1)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("cache of size 0 can't hold an entry")
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	c := lru.New[int](10, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := strconv.Itoa((i + j) % 20)
+				c.Set(key, j)
+				c.Get(key)
+				if j%100 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() > 10 {
+		t.Errorf("Len() = %d, over the size of the cache", c.Len())
+	}
+}
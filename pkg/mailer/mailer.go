@@ -0,0 +1,23 @@
+// Package mailer renders and delivers emails.
+package mailer
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoRecipient is returned for a message without recipients.
+var ErrNoRecipient = errors.New("mailer: message has no recipient")
+
+// Message is a rendered email, Text and HTML are sent as alternatives of each other.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers a message, implementations have to be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,195 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/mailer"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := mailer.Message{
+		To:      []string{"user@example.com"},
+		Subject: "Привет",
+		Text:    "code 123456",
+		HTML:    "<p>code <b>123456</b></p>",
+	}
+
+	data, err := msg.Bytes(mail.Address{Name: "Mini twitter", Address: "noreply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Fatalf("subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want it in the sender's domain", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q (%v), want multipart/alternative", mediaType, err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	want := []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	}
+
+	for _, w := range want {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if contentType != w.contentType {
+			t.Errorf("part content type = %q, want %q", contentType, w.contentType)
+		}
+
+		// the multipart reader decodes quoted-printable parts
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != w.body {
+			t.Errorf("%s body = %q, want %q", w.contentType, body, w.body)
+		}
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two parts, got %v", err)
+	}
+}
+
+func TestMessageBytesRejectsHeaderInjection(t *testing.T) {
+	msg := mailer.Message{
+		To:   []string{"user@example.com\r\nBcc: victim@example.com"},
+		Text: "hi",
+	}
+
+	_, err := msg.Bytes(mail.Address{Address: "noreply@example.com"})
+	if err == nil {
+		t.Fatal("expected an invalid recipient error")
+	}
+}
+
+func newTestTemplates(t *testing.T) *mailer.Templates {
+	t.Helper()
+
+	fsys := fstest.MapFS{
+		"layout.html":     {Data: []byte(`<html lang="x">{{template "content" .}}</html>`)},
+		"en/welcome.txt":  {Data: []byte("{{define \"subject\"}}Welcome {{.Name}}{{end}}\nHello {{.Name}}\n")},
+		"en/welcome.html": {Data: []byte(`{{define "content"}}<p>Hello {{.Name}}</p>{{end}}`)},
+		"uz/welcome.txt":  {Data: []byte("{{define \"subject\"}}Xush kelibsiz {{.Name}}{{end}}\nSalom {{.Name}}\n")},
+		"en/notice.txt":   {Data: []byte("{{define \"subject\"}}Notice{{end}}\nSomething happened\n")},
+	}
+
+	templates, err := mailer.NewTemplates(fsys, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return templates
+}
+
+func TestTemplatesMatch(t *testing.T) {
+	templates := newTestTemplates(t)
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"uz", "uz"},
+		{"uz-UZ,en;q=0.5", "uz"},
+		{"fr,en;q=0.5,uz;q=0.8", "uz"},
+		{"de, *", "en"},
+		{"uz;q=0", "en"},
+	}
+
+	for _, tt := range tests {
+		if got := templates.Match(tt.acceptLanguage); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	templates := newTestTemplates(t)
+
+	msg, err := templates.Render("welcome", "uz", map[string]interface{}{"Name": "<Ali>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Subject != "Xush kelibsiz <Ali>" || msg.Text != "Salom <Ali>\n" {
+		t.Errorf("unexpected text rendering: %+v", msg)
+	}
+	// uz has no html version, it isn't mixed with the english one
+	if msg.HTML != "" {
+		t.Errorf("html = %q, want none", msg.HTML)
+	}
+
+	msg, err = templates.Render("welcome", "en", map[string]interface{}{"Name": "<Ali>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.HTML != `<html lang="x"><p>Hello &lt;Ali&gt;</p></html>` {
+		t.Errorf("html = %q, want the escaped name inside the layout", msg.HTML)
+	}
+
+	// a message missing in a locale falls back to the default one
+	msg, err = templates.Render("notice", "uz", nil)
+	if err != nil || msg.Subject != "Notice" {
+		t.Errorf("fallback render = %+v (%v)", msg, err)
+	}
+
+	_, err = templates.Render("welcome", "en", map[string]interface{}{})
+	if err == nil {
+		t.Error("expected an error for missing data")
+	}
+
+	_, err = templates.Render("unknown", "en", nil)
+	if err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := mailer.NewMemory()
+
+	err := m.Send(context.Background(), mailer.Message{To: []string{"a@example.com"}, Subject: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("down")
+	m.Fail(failure)
+
+	err = m.Send(context.Background(), mailer.Message{To: []string{"a@example.com"}, Subject: "two"})
+	if !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	if sent := m.Sent(); len(sent) != 1 || sent[0].Subject != "one" {
+		t.Errorf("sent = %+v, want only the first message", sent)
+	}
+}
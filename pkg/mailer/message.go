@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Bytes formats the message as MIME, a message with both bodies is multipart/alternative.
+// Recipients are validated, so a header can't be injected through an address.
+func (m Message) Bytes(from mail.Address) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, ErrNoRecipient
+	}
+
+	to := make([]string, 0, len(m.To))
+	for _, address := range m.To {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: invalid recipient %q: %w", address, err)
+		}
+		to = append(to, parsed.String())
+	}
+
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", from.String())
+	header.Set("To", strings.Join(to, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(from.Address))
+	header.Set("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header.Set("Content-Type", `text/plain; charset="UTF-8"`)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+
+		err := writeQuotedPrintable(&buf, m.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct{ contentType, content string }{
+		{`text/plain; charset="UTF-8"`, m.Text},
+		{`text/html; charset="UTF-8"`, m.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeQuotedPrintable(w, part.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := parts.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	header.Set("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	writeHeader(&buf, header)
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// writeHeader writes the header fields in a stable order followed by the blank line.
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+
+	_, err := qp.Write([]byte(content))
+	if err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+// messageID makes a unique Message-ID in the domain of the sender.
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
@@ -0,0 +1,129 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File writes every message as an .eml file into a directory, meant for development.
+type File struct {
+	dir  string
+	from mail.Address
+}
+
+var _ Mailer = (*File)(nil)
+
+// NewFile -.
+func NewFile(dir string, from mail.Address) (*File, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: %w", err)
+	}
+
+	return &File{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send -.
+func (f *File) Send(_ context.Context, msg Message) error {
+	data, err := msg.Bytes(f.from)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(f.dir, time.Now().Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+
+	return nil
+}
+
+// Logger is the part of a logger the Log mailer needs.
+type Logger interface {
+	Info(message string, args ...interface{})
+}
+
+// Log writes the plain text of every message to a logger, meant for development.
+type Log struct {
+	logger Logger
+}
+
+var _ Mailer = (*Log)(nil)
+
+// NewLog -.
+func NewLog(logger Logger) *Log {
+	return &Log{logger: logger}
+}
+
+// Send -.
+func (l *Log) Send(_ context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipient
+	}
+
+	l.logger.Info(fmt.Sprintf("mailer - to: %s, subject: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Text))
+
+	return nil
+}
+
+// Memory keeps the messages it is given, meant for tests.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+var _ Mailer = (*Memory)(nil)
+
+// NewMemory -.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send -.
+func (m *Memory) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+
+	if len(msg.To) == 0 {
+		return ErrNoRecipient
+	}
+
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// Fail makes the following sends return err until it is called with nil.
+func (m *Memory) Fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+// Sent returns the messages sent so far.
+func (m *Memory) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
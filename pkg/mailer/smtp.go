@@ -0,0 +1,230 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Security modes of an SMTP connection.
+const (
+	// SecurityStartTLS upgrades a plain connection and fails when the server doesn't offer STARTTLS.
+	SecurityStartTLS = "starttls"
+	// SecurityTLS connects over implicit TLS, usually port 465.
+	SecurityTLS = "tls"
+	// SecurityNone sends in clear text, only meant for a local relay.
+	SecurityNone = "none"
+)
+
+// Authentication mechanisms of an SMTP server.
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCramMD5 = "cram-md5"
+	AuthNone    = "none"
+)
+
+// SMTPConfig -.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Security string
+	Auth     string
+	Timeout  time.Duration
+}
+
+// SMTP delivers messages to an SMTP server, a connection is opened per message.
+type SMTP struct {
+	config SMTPConfig
+	from   mail.Address
+}
+
+var _ Mailer = (*SMTP)(nil)
+
+// NewSMTP -.
+func NewSMTP(config SMTPConfig, from mail.Address) (*SMTP, error) {
+	if config.Host == "" || config.Port == "" {
+		return nil, errors.New("mailer: smtp host and port are required")
+	}
+
+	if config.Security == "" {
+		config.Security = SecurityStartTLS
+	}
+	switch config.Security {
+	case SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return nil, fmt.Errorf("mailer: unknown smtp security %q", config.Security)
+	}
+
+	if config.Auth == "" {
+		config.Auth = AuthPlain
+	}
+	switch config.Auth {
+	case AuthPlain, AuthLogin, AuthCramMD5, AuthNone:
+	default:
+		return nil, fmt.Errorf("mailer: unknown smtp auth %q", config.Auth)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &SMTP{
+		config: config,
+		from:   from,
+	}, nil
+}
+
+// Send -.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes(s.from)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("mailer: dial smtp: %w", err)
+	}
+	defer conn.Close()
+
+	// the whole conversation has to fit into the deadline of ctx
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		return fmt.Errorf("mailer: smtp greeting: %w", err)
+	}
+	defer client.Close()
+
+	if s.config.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("mailer: smtp server doesn't support STARTTLS")
+		}
+
+		err = client.StartTLS(&tls.Config{ServerName: s.config.Host, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return fmt.Errorf("mailer: starttls: %w", err)
+		}
+	}
+
+	if auth := s.auth(); auth != nil {
+		err = client.Auth(auth)
+		if err != nil {
+			return fmt.Errorf("mailer: smtp auth: %w", err)
+		}
+	}
+
+	err = client.Mail(s.from.Address)
+	if err != nil {
+		return fmt.Errorf("mailer: smtp MAIL FROM: %w", err)
+	}
+
+	for _, to := range msg.To {
+		address, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+
+		err = client.Rcpt(address.Address)
+		if err != nil {
+			return fmt.Errorf("mailer: smtp RCPT TO %s: %w", address.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("mailer: smtp DATA: %w", err)
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("mailer: smtp write: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("mailer: smtp DATA: %w", err)
+	}
+
+	// the message is accepted at this point, a failing QUIT doesn't matter
+	_ = client.Quit()
+
+	return nil
+}
+
+func (s *SMTP) dial(ctx context.Context) (net.Conn, error) {
+	address := net.JoinHostPort(s.config.Host, s.config.Port)
+
+	if s.config.Security == SecurityTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.config.Host, MinVersion: tls.VersionTLS12}}
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+func (s *SMTP) auth() smtp.Auth {
+	if s.config.Username == "" {
+		return nil
+	}
+
+	switch s.config.Auth {
+	case AuthPlain:
+		return smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	case AuthLogin:
+		return &loginAuth{username: s.config.Username, password: s.config.Password, host: s.config.Host}
+	case AuthCramMD5:
+		return smtp.CRAMMD5Auth(s.config.Username, s.config.Password)
+	}
+
+	return nil
+}
+
+// loginAuth implements the LOGIN mechanism, net/smtp has only PLAIN and CRAM-MD5.
+// Like PLAIN it sends the password as is, so it is refused on an unencrypted connection.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" && server.Name != "::1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+
+	return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+}
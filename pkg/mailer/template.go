@@ -0,0 +1,193 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	ttemplate "text/template"
+)
+
+// layoutFile is the optional html layout shared by all locales, it includes the "content" template of a message.
+const layoutFile = "layout.html"
+
+// Templates renders messages from a tree of <locale>/<name>.txt and <locale>/<name>.html files.
+// The txt file is the plain text body and defines the "subject" template, the html file is optional
+// and defines "content" for the layout. A message missing in a locale is rendered in the fallback locale.
+type Templates struct {
+	fallback string
+	locales  map[string]bool
+	text     map[string]*ttemplate.Template
+	html     map[string]*htemplate.Template
+}
+
+// NewTemplates parses all templates of fsys, fallback has to be one of its locales.
+func NewTemplates(fsys fs.FS, fallback string) (*Templates, error) {
+	t := &Templates{
+		fallback: fallback,
+		locales:  map[string]bool{},
+		text:     map[string]*ttemplate.Template{},
+		html:     map[string]*htemplate.Template{},
+	}
+
+	_, err := fs.Stat(fsys, layoutFile)
+	hasLayout := err == nil
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("mailer: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		locale := entry.Name()
+		t.locales[locale] = true
+
+		files, err := fs.ReadDir(fsys, locale)
+		if err != nil {
+			return nil, fmt.Errorf("mailer: %w", err)
+		}
+
+		for _, file := range files {
+			var (
+				name = path.Join(locale, file.Name())
+				key  = path.Join(locale, strings.TrimSuffix(file.Name(), path.Ext(file.Name())))
+			)
+
+			switch path.Ext(file.Name()) {
+			case ".txt":
+				tmpl, err := ttemplate.ParseFS(fsys, name)
+				if err != nil {
+					return nil, fmt.Errorf("mailer: %w", err)
+				}
+				if tmpl.Lookup("subject") == nil {
+					return nil, fmt.Errorf("mailer: %s doesn't define a subject", name)
+				}
+				t.text[key] = tmpl.Option("missingkey=error")
+			case ".html":
+				patterns := []string{name}
+				if hasLayout {
+					patterns = []string{layoutFile, name}
+				}
+
+				tmpl, err := htemplate.ParseFS(fsys, patterns...)
+				if err != nil {
+					return nil, fmt.Errorf("mailer: %w", err)
+				}
+				t.html[key] = tmpl.Option("missingkey=error")
+			}
+		}
+	}
+
+	if !t.locales[fallback] {
+		return nil, fmt.Errorf("mailer: fallback locale %q has no templates", fallback)
+	}
+
+	for key := range t.html {
+		if _, ok := t.text[key]; !ok {
+			return nil, fmt.Errorf("mailer: %s.html has no plain text counterpart", key)
+		}
+	}
+
+	return t, nil
+}
+
+// Render renders the message name in the locale matching acceptLanguage, the recipients are left to the caller.
+func (t *Templates) Render(name, acceptLanguage string, data interface{}) (Message, error) {
+	var (
+		msg Message
+		key = path.Join(t.Match(acceptLanguage), name)
+	)
+
+	if _, ok := t.text[key]; !ok {
+		key = path.Join(t.fallback, name)
+	}
+
+	text, ok := t.text[key]
+	if !ok {
+		return msg, fmt.Errorf("mailer: unknown template %q", name)
+	}
+
+	var buf bytes.Buffer
+
+	err := text.ExecuteTemplate(&buf, "subject", data)
+	if err != nil {
+		return msg, fmt.Errorf("mailer: %w", err)
+	}
+	msg.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	err = text.Execute(&buf, data)
+	if err != nil {
+		return msg, fmt.Errorf("mailer: %w", err)
+	}
+	msg.Text = strings.TrimSpace(buf.String()) + "\n"
+
+	if html, ok := t.html[key]; ok {
+		buf.Reset()
+		err = html.Execute(&buf, data)
+		if err != nil {
+			return msg, fmt.Errorf("mailer: %w", err)
+		}
+		msg.HTML = buf.String()
+	}
+
+	if msg.Subject == "" {
+		return msg, errors.New("mailer: " + key + " rendered an empty subject")
+	}
+
+	return msg, nil
+}
+
+// Match picks the locale best matching an Accept-Language header, e.g. "uz-UZ,ru;q=0.8".
+// A region is dropped when only the language is available, the fallback is used when nothing matches.
+func (t *Templates) Match(acceptLanguage string) string {
+	type tag struct {
+		value string
+		q     float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		value, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || value == "*" || q <= 0 {
+			continue
+		}
+
+		tags = append(tags, tag{value: value, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, tag := range tags {
+		if t.locales[tag.value] {
+			return tag.value
+		}
+
+		language, _, _ := strings.Cut(tag.value, "-")
+		if t.locales[language] {
+			return language
+		}
+	}
+
+	return t.fallback
+}
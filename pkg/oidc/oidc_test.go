@@ -0,0 +1,176 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/oidc"
+)
+
+// mockProvider is a minimal OIDC provider: discovery, jwks and a token endpoint
+// that hands out an id_token for the code issued by authorize.
+type mockProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockProvider{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "code" || base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":            m.server.URL,
+			"aud":            "client",
+			"sub":            "subject-1",
+			"email":          "john@example.com",
+			"email_verified": true,
+			"nonce":          m.nonce,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		signed, _ := token.SignedString(key)
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "id_token": signed})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// authorize plays the user consenting at the provider, remembering the PKCE challenge and nonce.
+func (m *mockProvider) authorize(t *testing.T, authURL string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 challenge, got %q", u.Query().Get("code_challenge_method"))
+	}
+
+	m.challenge = u.Query().Get("code_challenge")
+	m.nonce = u.Query().Get("nonce")
+}
+
+func TestProviderFlow(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		verifier string
+		nonce    string
+		wantErr  bool
+	}{
+		{name: "ok"},
+		{name: "wrong verifier", verifier: "other", wantErr: true},
+		{name: "wrong nonce", nonce: "other", wantErr: true},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "someone-else"}, wantErr: true},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, wantErr: true},
+		{name: "string email_verified", claims: jwt.MapClaims{"email_verified": "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.claims = tt.claims
+
+			p := oidc.NewProvider(oidc.Config{
+				Name:        "mock",
+				Issuer:      m.server.URL,
+				ClientID:    "client",
+				RedirectURL: "http://localhost/callback",
+			})
+
+			verifier, _ := oidc.RandomString(32)
+			nonce, _ := oidc.RandomString(16)
+
+			authURL, err := p.AuthCodeURL(context.Background(), "state", nonce, verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.authorize(t, authURL)
+
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			claims, err := p.Exchange(context.Background(), "code", verifier, nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.Subject != "subject-1" || claims.Email != "john@example.com" || !claims.EmailVerified {
+				t.Fatalf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter counts events per key in a sliding time window backed by a redis sorted set.
+type Limiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// New -.
+func New(client *redis.Client, prefix string) *Limiter {
+	return &Limiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Hit records an event for key and returns the number of events within window, including this one.
+func (l *Limiter) Hit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	key = l.prefix + key
+
+	var card *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: uuid.NewString()})
+		card = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return card.Val(), nil
+}
+
+// Count returns the number of events for key within window without recording a new one.
+func (l *Limiter) Count(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	key = l.prefix + key
+
+	var card *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+		card = pipe.ZCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return card.Val(), nil
+}
+
+// Reset forgets all events recorded for key.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.client.Del(ctx, l.prefix+key).Err()
+}
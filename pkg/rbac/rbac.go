@@ -0,0 +1,134 @@
+// Package rbac wraps a casbin enforcer whose policy can be reloaded while requests are served.
+package rbac
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin"
+	"github.com/casbin/casbin/persist"
+)
+
+// Enforcer checks requests against the last successfully loaded policy.
+// A reload builds a new casbin enforcer and swaps it in, so a failed load keeps the old policy
+// instead of leaving an empty one behind.
+type Enforcer struct {
+	modelPath string
+	adapter   persist.Adapter
+	watcher   persist.Watcher
+
+	mu      sync.Mutex
+	current atomic.Pointer[casbin.Enforcer]
+	stop    chan struct{}
+}
+
+// New -.
+func New(modelPath string, adapter persist.Adapter) (*Enforcer, error) {
+	e := &Enforcer{
+		modelPath: modelPath,
+		adapter:   adapter,
+		stop:      make(chan struct{}),
+	}
+
+	err := e.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Reload loads the policy from the adapter.
+func (e *Enforcer) Reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enforcer, err := casbin.NewEnforcerSafe(e.modelPath, false)
+	if err != nil {
+		return fmt.Errorf("rbac - Reload: %w", err)
+	}
+
+	// casbin ignores load errors of the adapter it is created with, so the policy is loaded separately
+	enforcer.SetAdapter(e.adapter)
+
+	err = enforcer.LoadPolicy()
+	if err != nil {
+		return fmt.Errorf("rbac - Reload - LoadPolicy: %w", err)
+	}
+
+	e.current.Store(enforcer)
+
+	return nil
+}
+
+// Enforce reports whether sub may perform act on obj.
+func (e *Enforcer) Enforce(sub, obj, act string) (bool, error) {
+	return e.current.Load().EnforceSafe(sub, obj, act)
+}
+
+// Policies returns the permission rules (p).
+func (e *Enforcer) Policies() [][]string {
+	return e.current.Load().GetPolicy()
+}
+
+// Roles returns the role assignments (g).
+func (e *Enforcer) Roles() [][]string {
+	return e.current.Load().GetGroupingPolicy()
+}
+
+// Watch reloads the policy whenever the watcher reports a change made by another replica,
+// and every interval as a fallback for notifications lost while the watcher was disconnected.
+func (e *Enforcer) Watch(watcher persist.Watcher, interval time.Duration, onError func(error)) error {
+	e.watcher = watcher
+
+	err := watcher.SetUpdateCallback(func(string) {
+		if err := e.Reload(); err != nil {
+			onError(err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := e.Reload(); err != nil {
+					onError(err)
+				}
+			case <-e.stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Notify tells the other replicas to reload after the policy was changed in storage.
+func (e *Enforcer) Notify() error {
+	if e.watcher == nil {
+		return nil
+	}
+
+	return e.watcher.Update()
+}
+
+// Close stops the periodic reload and the watcher.
+func (e *Enforcer) Close() {
+	close(e.stop)
+
+	if e.watcher != nil {
+		e.watcher.Close()
+	}
+}
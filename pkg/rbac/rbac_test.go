@@ -0,0 +1,142 @@
+package rbac_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/model"
+	fileadapter "github.com/casbin/casbin/persist/file-adapter"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/rbac"
+)
+
+const testModel = "../../config/rbac.conf"
+
+func writePolicy(t *testing.T, lines string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(path, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestEnforce(t *testing.T) {
+	path := writePolicy(t, `p, unauthorized, /v1/auth/*, GET|POST
+p, user, /v1/tweet/*, GET|POST
+p, admin, /v1/admin/*, GET
+g, user, unauthorized
+g, admin, user
+g, superadmin, admin
+`)
+
+	enforcer, err := rbac.New(testModel, fileadapter.NewAdapter(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sub, obj, act string
+		want          bool
+	}{
+		{"unauthorized", "/v1/auth/login", "POST", true},
+		{"unauthorized", "/v1/tweet/list", "GET", false},
+		{"user", "/v1/auth/login", "POST", true},
+		{"user", "/v1/tweet/list", "GET", true},
+		{"user", "/v1/tweet/list", "DELETE", false},
+		{"user", "/v1/admin/audit", "GET", false},
+		{"admin", "/v1/admin/audit", "GET", true},
+		{"superadmin", "/v1/admin/audit", "GET", true},
+		{"superadmin", "/v1/tweet/list", "POST", true},
+		// superadmins get what the policy grants them, nothing more
+		{"superadmin", "/v1/admin/audit", "DELETE", false},
+		{"superadmin", "/v1/unknown", "GET", false},
+		{"someone", "/v1/auth/login", "POST", false},
+	}
+
+	for _, tt := range tests {
+		got, err := enforcer.Enforce(tt.sub, tt.obj, tt.act)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Errorf("Enforce(%s, %s, %s) = %v, want %v", tt.sub, tt.obj, tt.act, got, tt.want)
+		}
+	}
+}
+
+func TestPoliciesAndRoles(t *testing.T) {
+	path := writePolicy(t, `p, user, /v1/tweet/*, GET
+g, admin, user
+`)
+
+	enforcer, err := rbac.New(testModel, fileadapter.NewAdapter(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policies := enforcer.Policies(); len(policies) != 1 || policies[0][1] != "/v1/tweet/*" {
+		t.Errorf("Policies() = %v", policies)
+	}
+	if roles := enforcer.Roles(); len(roles) != 1 || roles[0][0] != "admin" || roles[0][1] != "user" {
+		t.Errorf("Roles() = %v", roles)
+	}
+}
+
+// failingAdapter loads its policy file until it is told to fail.
+type failingAdapter struct {
+	*fileadapter.Adapter
+	fail bool
+}
+
+func (a *failingAdapter) LoadPolicy(model model.Model) error {
+	if a.fail {
+		// a failed load may have touched the model already
+		model.AddPolicy("p", "p", []string{"user", "/v1/admin/*", "GET"})
+		return errors.New("connection refused")
+	}
+
+	return a.Adapter.LoadPolicy(model)
+}
+
+func TestReloadKeepsPolicyOnFailure(t *testing.T) {
+	path := writePolicy(t, "p, user, /v1/tweet/*, GET\n")
+	adapter := &failingAdapter{Adapter: fileadapter.NewAdapter(path)}
+
+	enforcer, err := rbac.New(testModel, adapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adapter.fail = true
+	if err = enforcer.Reload(); err == nil {
+		t.Fatal("Reload() succeeded with a failing adapter")
+	}
+
+	if ok, _ := enforcer.Enforce("user", "/v1/tweet/list", "GET"); !ok {
+		t.Error("policy loaded before the failed reload is gone")
+	}
+	if ok, _ := enforcer.Enforce("user", "/v1/admin/audit", "GET"); ok {
+		t.Error("rule of the failed reload is applied")
+	}
+
+	adapter.fail = false
+	if err = os.WriteFile(path, []byte("p, user, /v1/admin/*, GET\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = enforcer.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := enforcer.Enforce("user", "/v1/admin/audit", "GET"); !ok {
+		t.Error("reloaded policy is not applied")
+	}
+	if ok, _ := enforcer.Enforce("user", "/v1/tweet/list", "GET"); ok {
+		t.Error("rule removed from the policy is still applied")
+	}
+}
@@ -0,0 +1,86 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const _publishTimeout = 5 * time.Second
+
+// Watcher is a casbin watcher over redis pub/sub, every replica subscribes to the same channel.
+type Watcher struct {
+	client  *redis.Client
+	channel string
+	id      string
+	pubsub  *redis.PubSub
+
+	mu       sync.Mutex
+	callback func(string)
+}
+
+// NewWatcher subscribes to channel and starts listening for updates of other replicas.
+func NewWatcher(ctx context.Context, client *redis.Client, channel string) (*Watcher, error) {
+	pubsub := client.Subscribe(ctx, channel)
+
+	// wait for the subscription to be confirmed so no update is missed after startup
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		client:  client,
+		channel: channel,
+		id:      uuid.NewString(),
+		pubsub:  pubsub,
+	}
+
+	go w.listen()
+
+	return w, nil
+}
+
+// SetUpdateCallback -.
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.callback = callback
+
+	return nil
+}
+
+// Update tells the other replicas to reload their policy.
+func (w *Watcher) Update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), _publishTimeout)
+	defer cancel()
+
+	return w.client.Publish(ctx, w.channel, w.id).Err()
+}
+
+// Close -.
+func (w *Watcher) Close() {
+	w.pubsub.Close()
+}
+
+func (w *Watcher) listen() {
+	for msg := range w.pubsub.Channel() {
+		// own updates are already applied
+		if msg.Payload == w.id {
+			continue
+		}
+
+		w.mu.Lock()
+		callback := w.callback
+		w.mu.Unlock()
+
+		if callback != nil {
+			callback(msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// get-vanilla of the AWS signature v4 test suite.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signV4(req, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service", now, emptyPayload)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+}
+
+func TestURIEncode(t *testing.T) {
+	if got := uriEncode("media/a b+c~.jpg", false); got != "media/a%20b%2Bc~.jpg" {
+		t.Errorf("uriEncode = %s", got)
+	}
+	if got := uriEncode("a/b", true); got != "a%2Fb" {
+		t.Errorf("uriEncode = %s", got)
+	}
+}
@@ -0,0 +1,112 @@
+// Package storage keeps objects, e.g. uploaded media, in a local directory or an S3 compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned for an object that doesn't exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage keeps objects under slash separated keys, implementations have to be safe for concurrent use.
+type Storage interface {
+	// Put stores size bytes of body under key, replacing the object stored under it.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key, the caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange opens length bytes of the object stored under key from offset on, the caller closes it.
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object stored under key, a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeleteDir removes every object whose key starts with dir followed by a slash.
+	DeleteDir(ctx context.Context, dir string) error
+}
+
+// validKey rejects keys that could escape the root of a storage, e.g. ../secret.
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+
+	return nil
+}
+
+// Reader reads an object of a known size with ranged gets, so it can be seeked, e.g. by http.ServeContent.
+// A ranged get is made on the first read after a seek.
+type Reader struct {
+	ctx     context.Context
+	storage Storage
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+var _ io.ReadSeekCloser = (*Reader)(nil)
+
+// NewReader -.
+func NewReader(ctx context.Context, storage Storage, key string, size int64) *Reader {
+	return &Reader{ctx: ctx, storage: storage, key: key, size: size}
+}
+
+// Read -.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, err := r.storage.GetRange(r.ctx, r.key, r.offset, r.size-r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if errors.Is(err, io.EOF) && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// Seek -.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("storage: seek to %d", offset)
+	}
+
+	if offset != r.offset {
+		r.Close()
+		r.offset = offset
+	}
+
+	return offset, nil
+}
+
+// Close closes the ranged get being read, the reader can still be seeked and read again.
+func (r *Reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+
+	return err
+}
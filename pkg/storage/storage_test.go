@@ -0,0 +1,234 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/storage"
+)
+
+// fakeS3 is a stand-in of an S3 compatible service with path style buckets, like MinIO.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code><Message>unsigned</Message></Error>")
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "<Error><Code>NoSuchBucket</Code></Error>")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		if key == "" && r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r.URL.Query().Get("prefix"))
+			return
+		}
+
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list answers a ListObjectsV2 a key at a time, so continuation tokens are used.
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	keys := []string{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	io.WriteString(w, "<ListBucketResult>")
+	if len(keys) > 0 {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", keys[0])
+	}
+	if len(keys) > 1 {
+		io.WriteString(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>next</NextContinuationToken>")
+	}
+	io.WriteString(w, "</ListBucketResult>")
+}
+
+func testStorage(t *testing.T, s storage.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	body := "hello media"
+	err := s.Put(ctx, "media/one.txt", strings.NewReader(body), int64(len(body)), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := s.Get(ctx, "media/one.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(object)
+	object.Close()
+	if err != nil || string(data) != body {
+		t.Fatalf("Get = %q (%v), want %q", data, err, body)
+	}
+
+	object, err = s.GetRange(ctx, "media/one.txt", 6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(object)
+	object.Close()
+	if err != nil || string(data) != "med" {
+		t.Fatalf("GetRange = %q (%v), want %q", data, err, "med")
+	}
+
+	// a reader can be seeked back and forth, each read after a seek is a ranged get
+	reader := storage.NewReader(ctx, s, "media/one.txt", int64(len(body)))
+	if _, err = reader.Seek(-5, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(reader)
+	if err != nil || string(data) != "media" {
+		t.Fatalf("read from the end = %q (%v), want %q", data, err, "media")
+	}
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(io.LimitReader(reader, 5))
+	reader.Close()
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("read from the start = %q (%v), want %q", data, err, "hello")
+	}
+
+	err = s.Delete(ctx, "media/one.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// deleting twice is fine
+	err = s.Delete(ctx, "media/one.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Get(ctx, "media/one.txt")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get of a deleted object = %v, want ErrNotFound", err)
+	}
+
+	// a dir is deleted with everything under it and nothing next to it
+	for _, key := range []string{"hls/m1/master.m3u8", "hls/m1/720p/000.ts", "hls/m10/master.m3u8"} {
+		err = s.Put(ctx, key, strings.NewReader("x"), 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = s.DeleteDir(ctx, "hls/m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"hls/m1/master.m3u8": false, "hls/m1/720p/000.ts": false, "hls/m10/master.m3u8": true} {
+		object, err := s.Get(ctx, key)
+		if err == nil {
+			object.Close()
+		}
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v after DeleteDir, want %v", key, exists, want)
+		}
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "media/../../secret", `media\one`} {
+		err = s.Put(ctx, key, strings.NewReader("x"), 1, "")
+		if err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	s, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStorage(t, s)
+
+	// a body that doesn't match its size is not stored
+	err = s.Put(context.Background(), "media/short", strings.NewReader("abc"), 4, "")
+	if err == nil {
+		t.Error("expected an error for a short body")
+	}
+	if _, err = s.Get(context.Background(), "media/short"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("short body was stored: %v", err)
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{bucket: "media", objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s, err := storage.NewS3(storage.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "media",
+		AccessKey: "minio",
+		SecretKey: "minio-secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStorage(t, s)
+
+	err = s.Put(context.Background(), "media/two.jpg", strings.NewReader("jpeg"), 4, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.types["media/two.jpg"] != "image/jpeg" {
+		t.Errorf("content type = %q, want image/jpeg", fake.types["media/two.jpg"])
+	}
+
+	denied, err := storage.NewS3(storage.S3Config{Endpoint: server.URL, Bucket: "media", AccessKey: "other", PathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = denied.Put(context.Background(), "media/three", strings.NewReader("x"), 1, "")
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("err = %v, want AccessDenied", err)
+	}
+}
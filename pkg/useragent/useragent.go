@@ -0,0 +1,85 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Info is a human readable description of a User-Agent header.
+type Info struct {
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version"`
+	OS             string `json:"os"`
+	Device         string `json:"device"` // desktop, mobile, tablet, bot
+}
+
+type matcher struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Order matters: Edge and Opera also advertise Chrome, Chrome also advertises Safari.
+var browsers = []matcher{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Yandex", regexp.MustCompile(`YaBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	{"curl", regexp.MustCompile(`curl/([\d.]+)`)},
+	{"Postman", regexp.MustCompile(`PostmanRuntime/([\d.]+)`)},
+	{"Dart", regexp.MustCompile(`Dart/([\d.]+)`)},
+	{"okhttp", regexp.MustCompile(`okhttp/([\d.]+)`)},
+}
+
+var systems = []matcher{
+	{"iPadOS", regexp.MustCompile(`iPad.*OS ([\d_]+)`)},
+	{"iOS", regexp.MustCompile(`(?:iPhone|iPod).*OS ([\d_]+)`)},
+	{"Android", regexp.MustCompile(`Android ([\d.]+)`)},
+	{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+	{"macOS", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+	{"ChromeOS", regexp.MustCompile(`CrOS \S+ ([\d.]+)`)},
+	{"Linux", regexp.MustCompile(`Linux()`)},
+}
+
+// Parse extracts browser, operating system and device type from a User-Agent header.
+// Unknown values are reported as "Unknown".
+func Parse(ua string) Info {
+	info := Info{
+		Browser: "Unknown",
+		OS:      "Unknown",
+		Device:  "desktop",
+	}
+
+	for _, m := range browsers {
+		if match := m.re.FindStringSubmatch(ua); match != nil {
+			info.Browser = m.name
+			info.BrowserVersion = match[1]
+			break
+		}
+	}
+
+	for _, m := range systems {
+		if match := m.re.FindStringSubmatch(ua); match != nil {
+			info.OS = m.name
+			if version := strings.ReplaceAll(match[1], "_", "."); version != "" {
+				info.OS += " " + version
+			}
+			break
+		}
+	}
+
+	lower := strings.ToLower(ua)
+	switch {
+	case strings.Contains(lower, "bot") || strings.Contains(lower, "spider") || strings.Contains(lower, "crawl"):
+		info.Device = "bot"
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") ||
+		(strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		info.Device = "tablet"
+	case strings.Contains(lower, "mobi") || strings.Contains(lower, "iphone") || strings.Contains(lower, "dart/"):
+		info.Device = "mobile"
+	}
+
+	return info
+}
@@ -0,0 +1,84 @@
+package useragent_test
+
+import (
+	"testing"
+
+	"github.com/golanguzb70/udevslabs-twitter/pkg/useragent"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want useragent.Info
+	}{
+		{
+			name: "chrome on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			want: useragent.Info{Browser: "Chrome", BrowserVersion: "120.0.6099.109", OS: "Windows 10.0", Device: "desktop"},
+		},
+		{
+			name: "edge advertises chrome",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.77",
+			want: useragent.Info{Browser: "Edge", BrowserVersion: "120.0.2210.77", OS: "Windows 10.0", Device: "desktop"},
+		},
+		{
+			name: "safari on macos",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			want: useragent.Info{Browser: "Safari", BrowserVersion: "17.1", OS: "macOS 10.15.7", Device: "desktop"},
+		},
+		{
+			name: "safari on iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1.2 Mobile/15E148 Safari/604.1",
+			want: useragent.Info{Browser: "Safari", BrowserVersion: "17.1.2", OS: "iOS 17.1.2", Device: "mobile"},
+		},
+		{
+			name: "safari on ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			want: useragent.Info{Browser: "Safari", BrowserVersion: "16.6", OS: "iPadOS 16.6", Device: "tablet"},
+		},
+		{
+			name: "chrome on android phone",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.43 Mobile Safari/537.36",
+			want: useragent.Info{Browser: "Chrome", BrowserVersion: "120.0.6099.43", OS: "Android 14", Device: "mobile"},
+		},
+		{
+			name: "chrome on android tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+			want: useragent.Info{Browser: "Chrome", BrowserVersion: "119.0.0.0", OS: "Android 13", Device: "tablet"},
+		},
+		{
+			name: "firefox on linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: useragent.Info{Browser: "Firefox", BrowserVersion: "121.0", OS: "Linux", Device: "desktop"},
+		},
+		{
+			name: "bot",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: useragent.Info{Browser: "Unknown", OS: "Unknown", Device: "bot"},
+		},
+		{
+			name: "curl",
+			ua:   "curl/8.4.0",
+			want: useragent.Info{Browser: "curl", BrowserVersion: "8.4.0", OS: "Unknown", Device: "desktop"},
+		},
+		{
+			name: "dart client",
+			ua:   "Dart/3.2 (dart:io)",
+			want: useragent.Info{Browser: "Dart", BrowserVersion: "3.2", OS: "Unknown", Device: "mobile"},
+		},
+		{
+			name: "empty",
+			ua:   "",
+			want: useragent.Info{Browser: "Unknown", OS: "Unknown", Device: "desktop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := useragent.Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Package video probes videos and transcodes them into HLS renditions with the ffprobe and ffmpeg binaries.
+package video
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoVideo is returned by Probe for a file without a video stream.
+var ErrNoVideo = errors.New("video: no video stream")
+
+// Info is what Probe learns of a video. Width and Height are the displayed ones, after rotation.
+type Info struct {
+	Duration   time.Duration
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string // empty for a video without sound
+}
+
+// Rendition is a step of the HLS ladder, Height is the shorter side of the picture and bitrates are in kbit/s.
+type Rendition struct {
+	Name         string
+	Height       int
+	VideoBitrate int
+	AudioBitrate int
+}
+
+// Ladder are the renditions a video is transcoded into, from the largest down.
+var Ladder = []Rendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 128},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Height: 480, VideoBitrate: 1200, AudioBitrate: 96},
+	{Name: "240p", Height: 240, VideoBitrate: 400, AudioBitrate: 64},
+}
+
+// FFmpeg runs the ffprobe and ffmpeg binaries.
+type FFmpeg struct {
+	ffmpeg  string
+	ffprobe string
+}
+
+// New -.
+func New(ffmpeg, ffprobe string) *FFmpeg {
+	return &FFmpeg{ffmpeg: ffmpeg, ffprobe: ffprobe}
+}
+
+// Probe reads the duration, size and codecs of the video at path.
+func (f *FFmpeg) Probe(ctx context.Context, path string) (Info, error) {
+	out, err := f.run(ctx, f.ffprobe, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return parseProbe(out)
+}
+
+type probeOutput struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType string            `json:"codec_type"`
+		CodecName string            `json:"codec_name"`
+		Width     int               `json:"width"`
+		Height    int               `json:"height"`
+		Tags      map[string]string `json:"tags"`
+		SideData  []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+func parseProbe(data []byte) (Info, error) {
+	var probe probeOutput
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return Info{}, fmt.Errorf("video: ffprobe output: %w", err)
+	}
+
+	info := Info{}
+
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err == nil && seconds > 0 {
+		info.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	for _, stream := range probe.Streams {
+		switch {
+		case stream.CodecType == "video" && info.VideoCodec == "":
+			info.VideoCodec = stream.CodecName
+			info.Width, info.Height = stream.Width, stream.Height
+
+			// phones record upright video sideways with a rotation, which ffmpeg applies when transcoding
+			rotation, _ := strconv.ParseFloat(stream.Tags["rotate"], 64)
+			for _, side := range stream.SideData {
+				if side.Rotation != 0 {
+					rotation = side.Rotation
+				}
+			}
+			if int(math.Abs(rotation))%180 == 90 {
+				info.Width, info.Height = info.Height, info.Width
+			}
+		case stream.CodecType == "audio" && info.AudioCodec == "":
+			info.AudioCodec = stream.CodecName
+		}
+	}
+
+	if info.VideoCodec == "" || info.Width <= 0 || info.Height <= 0 {
+		return Info{}, ErrNoVideo
+	}
+
+	return info, nil
+}
+
+// Poster writes the frame at the given time of the video at path as a png.
+func (f *FFmpeg) Poster(ctx context.Context, path string, at time.Duration, out string) error {
+	_, err := f.run(ctx, f.ffmpeg, "-nostdin", "-y", "-v", "error",
+		"-ss", seconds(at), "-i", path,
+		"-frames:v", "1", "-f", "image2", "-c:v", "png", out)
+
+	return err
+}
+
+// LadderFor returns the renditions of the ladder a video of the given size is transcoded into: the ones not
+// larger than the video, or the smallest one for a video smaller than all of them.
+func LadderFor(width, height int) []Rendition {
+	short := min(width, height)
+
+	renditions := []Rendition{}
+	for _, rendition := range Ladder {
+		if rendition.Height <= short {
+			renditions = append(renditions, rendition)
+		}
+	}
+
+	if len(renditions) == 0 {
+		renditions = append(renditions, Ladder[len(Ladder)-1])
+	}
+
+	return renditions
+}
+
+// Size is the picture size of a video of width x height in the rendition, the shorter side being the height
+// of the rendition, or of the video when that's smaller, and both sides even.
+func (r Rendition) Size(width, height int) (int, int) {
+	short := min(r.Height, width, height)
+
+	if width >= height {
+		return even(width * short / height), even(short)
+	}
+
+	return even(short), even(height * short / width)
+}
+
+// HLS transcodes the video at path into the renditions, each into dir/<name>/index.m3u8 and its segments,
+// and writes the master playlist to dir/master.m3u8. Key frames are forced at every segment boundary so
+// players can switch renditions between segments.
+func (f *FFmpeg) HLS(ctx context.Context, path string, info Info, renditions []Rendition, segment time.Duration, dir string) error {
+	for _, rendition := range renditions {
+		renditionDir := filepath.Join(dir, rendition.Name)
+		if err := os.MkdirAll(renditionDir, 0o755); err != nil {
+			return fmt.Errorf("video: %w", err)
+		}
+
+		width, height := rendition.Size(info.Width, info.Height)
+
+		args := []string{"-nostdin", "-y", "-v", "error", "-i", path,
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", fmt.Sprintf("scale=%d:%d", width, height),
+			"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+			"-b:v", fmt.Sprintf("%dk", rendition.VideoBitrate),
+			"-maxrate", fmt.Sprintf("%dk", rendition.VideoBitrate*107/100),
+			"-bufsize", fmt.Sprintf("%dk", rendition.VideoBitrate*3/2),
+			"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", seconds(segment)),
+			"-c:a", "aac", "-ac", "2", "-b:a", fmt.Sprintf("%dk", rendition.AudioBitrate),
+			"-f", "hls", "-hls_time", seconds(segment), "-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(renditionDir, "%03d.ts"),
+			filepath.Join(renditionDir, "index.m3u8"),
+		}
+
+		if _, err := f.run(ctx, f.ffmpeg, args...); err != nil {
+			return err
+		}
+	}
+
+	master := MasterPlaylist(info, renditions)
+	if err := os.WriteFile(filepath.Join(dir, "master.m3u8"), []byte(master), 0o644); err != nil {
+		return fmt.Errorf("video: %w", err)
+	}
+
+	return nil
+}
+
+// MasterPlaylist lists the renditions of a video, their playlists are at <name>/index.m3u8 next to it.
+func MasterPlaylist(info Info, renditions []Rendition) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for _, rendition := range renditions {
+		width, height := rendition.Size(info.Width, info.Height)
+
+		bandwidth := rendition.VideoBitrate * 1000
+		if info.AudioCodec != "" {
+			bandwidth += rendition.AudioBitrate * 1000
+		}
+
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/index.m3u8\n",
+			bandwidth, width, height, rendition.Name)
+	}
+
+	return b.String()
+}
+
+func (f *FFmpeg) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+
+		return nil, fmt.Errorf("video: %s: %w: %s", filepath.Base(name), err, msg)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func even(n int) int {
+	return max(2, n+n%2)
+}
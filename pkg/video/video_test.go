@@ -0,0 +1,93 @@
+package video
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProbe(t *testing.T) {
+	out := `{
+		"streams": [
+			{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080,
+			 "side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]},
+			{"codec_type": "audio", "codec_name": "aac"}
+		],
+		"format": {"duration": "12.480000"}
+	}`
+
+	info, err := parseProbe([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Info{Duration: 12480 * time.Millisecond, Width: 1080, Height: 1920, VideoCodec: "h264", AudioCodec: "aac"}
+	if info != want {
+		t.Errorf("parseProbe = %+v, want %+v", info, want)
+	}
+}
+
+func TestParseProbeNoVideo(t *testing.T) {
+	out := `{"streams": [{"codec_type": "audio", "codec_name": "mp3"}], "format": {"duration": "3.0"}}`
+
+	if _, err := parseProbe([]byte(out)); err != ErrNoVideo {
+		t.Errorf("parseProbe = %v, want ErrNoVideo", err)
+	}
+}
+
+func TestLadderFor(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          string
+	}{
+		{1920, 1080, "1080p 720p 480p 240p"},
+		{720, 1280, "720p 480p 240p"},
+		{640, 360, "240p"},
+		{160, 120, "240p"},
+	}
+
+	for _, tt := range tests {
+		names := []string{}
+		for _, rendition := range LadderFor(tt.width, tt.height) {
+			names = append(names, rendition.Name)
+		}
+
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("LadderFor(%d, %d) = %s, want %s", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestRenditionSize(t *testing.T) {
+	tests := []struct {
+		rendition     Rendition
+		width, height int
+		wantW, wantH  int
+	}{
+		{Ladder[1], 1920, 1080, 1280, 720},
+		{Ladder[2], 1080, 1920, 480, 854},
+		{Ladder[3], 160, 120, 160, 120},
+		{Ladder[3], 333, 250, 320, 240},
+	}
+
+	for _, tt := range tests {
+		w, h := tt.rendition.Size(tt.width, tt.height)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("%s.Size(%d, %d) = %dx%d, want %dx%d", tt.rendition.Name, tt.width, tt.height, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestMasterPlaylist(t *testing.T) {
+	info := Info{Width: 1280, Height: 720, VideoCodec: "h264"}
+
+	got := MasterPlaylist(info, LadderFor(info.Width, info.Height))
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2800000,RESOLUTION=1280x720\n720p/index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1200000,RESOLUTION=854x480\n480p/index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=400000,RESOLUTION=426x240\n240p/index.m3u8\n"
+
+	if got != want {
+		t.Errorf("MasterPlaylist =\n%s\nwant\n%s", got, want)
+	}
+}